@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"todo-backend/internal/backup"
+	"todo-backend/internal/config"
+	"todo-backend/internal/database"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+
+	"github.com/spf13/cobra"
+)
+
+func migrateCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Create or upgrade the database schema",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := openDatabase(cfg, true); err != nil {
+				return err
+			}
+			defer database.Close()
+			fmt.Fprintf(cmd.OutOrStdout(), "Migrated %s\n", cfg.DatabasePath)
+			return nil
+		},
+	}
+}
+
+func backupCommand(cfg *config.Config) *cobra.Command {
+	var force, list bool
+
+	cmd := &cobra.Command{
+		Use:   "backup [file]",
+		Short: "Write a consistent copy of the database, safe while the server is running",
+		Long: "Without a file the backup is written to TODO_BACKUP_DIR and old backups beyond TODO_BACKUP_KEEP\n" +
+			"are removed. Every backup gets a .sha256 file that restore checks.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list {
+				// 列出备份只读取备份目录, 不需要打开数据库
+				return listBackups(cmd, backup.NewManager(nil, cfg.BackupDir, cfg.BackupKeep))
+			}
+
+			if err := openDatabase(cfg, false); err != nil {
+				return err
+			}
+			defer database.Close()
+
+			manager := backup.NewManager(database.DB, cfg.BackupDir, cfg.BackupKeep)
+
+			if len(args) == 0 {
+				info, err := manager.Create()
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Backed up %s to %s (%s)\n", cfg.DatabasePath, info.Path, fileSize(info.Path))
+				return nil
+			}
+
+			dest := args[0]
+			if force {
+				if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+			if err := database.Backup(database.DB, dest); err != nil {
+				return err
+			}
+			if _, err := backup.WriteChecksum(dest); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Backed up %s to %s (%s)\n", cfg.DatabasePath, dest, fileSize(dest))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "overwrite an existing file")
+	cmd.Flags().BoolVar(&list, "list", false, "list the backups in the backup directory")
+	return cmd
+}
+
+func listBackups(cmd *cobra.Command, manager *backup.Manager) error {
+	backups, err := manager.List()
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "No backups in %s\n", manager.Dir())
+		return nil
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CREATED\tSIZE\tFILE")
+	for _, b := range backups {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", b.CreatedAt.Local().Format("2006-01-02 15:04:05"), fileSize(b.Path), b.Path)
+	}
+	return w.Flush()
+}
+
+func restoreCommand(cfg *config.Config) *cobra.Command {
+	var at string
+	var skipChecksum bool
+
+	cmd := &cobra.Command{
+		Use:   "restore [file]",
+		Short: "Replace the database with a backup, refused while the server is running",
+		Long: "Restore a backup file, or with --at the newest backup in TODO_BACKUP_DIR created at or before\n" +
+			"the given time. The checksum, integrity and schema version are checked before the database is replaced.\n" +
+			"The running server holds a lock on the database file; stop it first.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 1) == (at != "") {
+				return fmt.Errorf("specify either a backup file or --at")
+			}
+
+			src := ""
+			if len(args) == 1 {
+				src = args[0]
+			} else {
+				t, err := parseTime(at)
+				if err != nil {
+					return err
+				}
+				found, err := backup.NewManager(database.DB, cfg.BackupDir, cfg.BackupKeep).Find(t)
+				if err != nil {
+					return err
+				}
+				src = found.Path
+			}
+
+			if err := backup.Restore(src, cfg.DatabasePath, !skipChecksum); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Restored %s from %s\n", cfg.DatabasePath, src)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&at, "at", "", `restore the newest backup at or before this time (RFC 3339 or "2006-01-02 15:04" local time)`)
+	cmd.Flags().BoolVar(&skipChecksum, "skip-checksum", false, "restore a backup without a .sha256 file")
+	return cmd
+}
+
+// parseTime 接受 RFC 3339 或本地时间
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+func vacuumCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "vacuum",
+		Short: "Rebuild the database file to reclaim free space",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := openDatabase(cfg, false); err != nil {
+				return err
+			}
+			defer database.Close()
+
+			before := fileSize(cfg.DatabasePath)
+			if err := database.Vacuum(database.DB); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Vacuumed %s: %s -> %s\n", cfg.DatabasePath, before, fileSize(cfg.DatabasePath))
+			return nil
+		},
+	}
+}
+
+func seedCommand(cfg *config.Config) *cobra.Command {
+	var count int
+
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Insert sample todos for development",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if count < 1 {
+				return fmt.Errorf("--count must be at least 1")
+			}
+			if err := openDatabase(cfg, true); err != nil {
+				return err
+			}
+			defer database.Close()
+
+			now := time.Now()
+			err := repository.NewTodoRepository(database.DB).Transaction(cmd.Context(), func(repo *repository.TodoRepository) error {
+				for i := 1; i <= count; i++ {
+					todo := &model.Todo{
+						Title:     fmt.Sprintf("Sample todo %d", i),
+						Content:   fmt.Sprintf("Generated by seed at %s", now.Format(time.RFC3339)),
+						Completed: i%3 == 0,
+					}
+					// 一部分 Todo 设置截止时间, 其中一些已经过期
+					if i%4 == 0 {
+						due := now.AddDate(0, 0, i%8-4)
+						todo.DueAt = &due
+					}
+					if err := repo.Create(cmd.Context(), todo); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Inserted %d todos\n", count)
+			return nil
+		},
+	}
+	cmd.Flags().IntVarP(&count, "count", "n", 10, "number of todos to insert")
+	return cmd
+}
+
+func statsCommand(cfg *config.Config) *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show database statistics",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := openDatabase(cfg, false); err != nil {
+				return err
+			}
+			defer database.Close()
+
+			stats, err := repository.CollectStats(cmd.Context(), database.DB)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(stats)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintf(w, "Database:\t%s (%s)\n", cfg.DatabasePath, fileSize(cfg.DatabasePath))
+			fmt.Fprintf(w, "Todos:\t%d\n", stats.Todos)
+			fmt.Fprintf(w, "  completed:\t%d\n", stats.Completed)
+			fmt.Fprintf(w, "  pending:\t%d\n", stats.Pending)
+			fmt.Fprintf(w, "  overdue:\t%d\n", stats.Overdue)
+			fmt.Fprintf(w, "  deleted:\t%d\n", stats.Deleted)
+			fmt.Fprintf(w, "  text:\t%d bytes\n", stats.StorageBytes)
+			fmt.Fprintf(w, "Webhooks:\t%d\n", stats.Webhooks)
+			fmt.Fprintf(w, "  pending deliveries:\t%d\n", stats.PendingDeliveries)
+			fmt.Fprintf(w, "Idempotency keys:\t%d\n", stats.IdempotencyKeys)
+			fmt.Fprintf(w, "Users:\t%d\n", stats.Users)
+			fmt.Fprintf(w, "Last change seq:\t%d\n", stats.LastChangeSeq)
+			return w.Flush()
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print statistics as JSON")
+	return cmd
+}
+
+func fileSize(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "unknown size"
+	}
+	if info.Size() < 1024 {
+		return fmt.Sprintf("%d B", info.Size())
+	}
+	size := float64(info.Size()) / 1024
+	for _, unit := range []string{"KiB", "MiB"} {
+		if size < 1024 {
+			return fmt.Sprintf("%.1f %s", size, unit)
+		}
+		size /= 1024
+	}
+	return fmt.Sprintf("%.1f GiB", size)
+}
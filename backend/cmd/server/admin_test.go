@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-backend/internal/auth"
+	"todo-backend/internal/backup"
+	"todo-backend/internal/database"
+	"todo-backend/internal/repository"
+)
+
+// runAdmin 在 dbPath 上执行一条子命令, stdin 作为密码输入
+func runAdmin(t *testing.T, dbPath, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	cmd := newRootCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetIn(strings.NewReader(stdin))
+	cmd.SetArgs(append([]string{"--db", dbPath}, args...))
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func mustRunAdmin(t *testing.T, dbPath string, args ...string) string {
+	t.Helper()
+
+	out, err := runAdmin(t, dbPath, "", args...)
+	if err != nil {
+		t.Fatalf("server %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+func adminStats(t *testing.T, dbPath string) repository.Stats {
+	t.Helper()
+
+	var stats repository.Stats
+	if err := json.Unmarshal([]byte(mustRunAdmin(t, dbPath, "stats", "--json")), &stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	return stats
+}
+
+func TestMigrateSeedAndStats(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "admin.db")
+
+	if _, err := runAdmin(t, dbPath, "", "stats"); err == nil {
+		t.Error("Expected stats on a missing database to fail")
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Error("Expected stats not to create the database file")
+	}
+
+	mustRunAdmin(t, dbPath, "migrate")
+	if out := mustRunAdmin(t, dbPath, "seed", "--count", "12"); !strings.Contains(out, "Inserted 12 todos") {
+		t.Errorf("Unexpected seed output: %q", out)
+	}
+
+	stats := adminStats(t, dbPath)
+	if stats.Todos != 12 || stats.Completed != 4 || stats.Pending != 8 || stats.LastChangeSeq == 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	out := mustRunAdmin(t, dbPath, "stats")
+	if !strings.Contains(out, "Todos:") || !strings.Contains(out, "12") {
+		t.Errorf("Unexpected stats output:\n%s", out)
+	}
+
+	if _, err := runAdmin(t, dbPath, "", "seed", "--count", "0"); err == nil {
+		t.Error("Expected seed --count 0 to fail")
+	}
+}
+
+func TestUserCommands(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "admin.db")
+
+	if out, err := runAdmin(t, dbPath, "first-password\n", "user", "create", "alice"); err != nil {
+		t.Fatalf("user create failed: %v\n%s", err, out)
+	}
+	if _, err := runAdmin(t, dbPath, "other-password\n", "user", "create", "alice"); err != repository.ErrUserExists {
+		t.Errorf("Expected ErrUserExists, got %v", err)
+	}
+	if _, err := runAdmin(t, dbPath, "short\n", "user", "create", "bob"); err != auth.ErrPasswordTooShort {
+		t.Errorf("Expected ErrPasswordTooShort, got %v", err)
+	}
+
+	if out, err := runAdmin(t, dbPath, "second-password", "user", "reset-password", "alice"); err != nil {
+		t.Fatalf("reset-password failed: %v\n%s", err, out)
+	}
+	if _, err := runAdmin(t, dbPath, "second-password\n", "user", "reset-password", "nobody"); err == nil {
+		t.Error("Expected reset-password for an unknown user to fail")
+	}
+
+	if err := database.Open(dbPath, database.DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	user, err := repository.NewUserRepository(database.DB).GetByUsername(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !auth.CheckPassword(user.PasswordHash, "second-password") || auth.CheckPassword(user.PasswordHash, "first-password") {
+		t.Error("Expected the password to be reset")
+	}
+}
+
+func TestBackupVacuumAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "admin.db")
+	backupPath := filepath.Join(dir, "backup.db")
+
+	mustRunAdmin(t, dbPath, "seed", "--count", "5")
+	if out := mustRunAdmin(t, dbPath, "backup", backupPath); !strings.Contains(out, "Backed up") {
+		t.Errorf("Unexpected backup output: %q", out)
+	}
+	if _, err := runAdmin(t, dbPath, "", "backup", backupPath); err == nil {
+		t.Error("Expected backup to refuse overwriting an existing file")
+	}
+
+	mustRunAdmin(t, dbPath, "seed", "--count", "3")
+	mustRunAdmin(t, dbPath, "backup", "--force", filepath.Join(dir, "second.db"))
+	if out := mustRunAdmin(t, dbPath, "vacuum"); !strings.Contains(out, "->") {
+		t.Errorf("Unexpected vacuum output: %q", out)
+	}
+	if stats := adminStats(t, dbPath); stats.Todos != 8 {
+		t.Fatalf("Expected 8 todos before restore, got %d", stats.Todos)
+	}
+
+	mustRunAdmin(t, dbPath, "restore", backupPath)
+	if stats := adminStats(t, dbPath); stats.Todos != 5 {
+		t.Errorf("Expected the backup to be restored, got %d todos", stats.Todos)
+	}
+
+	garbage := filepath.Join(dir, "garbage.db")
+	os.WriteFile(garbage, []byte("not a database"), 0o644)
+	if _, err := runAdmin(t, dbPath, "", "restore", garbage); err == nil {
+		t.Error("Expected restoring an invalid file to fail")
+	}
+	if stats := adminStats(t, dbPath); stats.Todos != 5 {
+		t.Errorf("Expected a failed restore to keep the database, got %d todos", stats.Todos)
+	}
+}
+
+func TestRestoreRefusedWhileServerRunning(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "admin.db")
+	backupPath := filepath.Join(dir, "backup.db")
+
+	mustRunAdmin(t, dbPath, "seed", "--count", "2")
+	mustRunAdmin(t, dbPath, "backup", backupPath)
+	mustRunAdmin(t, dbPath, "seed", "--count", "1")
+
+	// 与 serve 命令相同, 运行中的服务持有数据库锁
+	unlock, err := database.Lock(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Lock(dbPath); !errors.Is(err, database.ErrLocked) {
+		t.Errorf("Expected a second server to be refused, got %v", err)
+	}
+	if _, err := runAdmin(t, dbPath, "", "restore", backupPath); !errors.Is(err, database.ErrLocked) {
+		t.Fatalf("Expected restore to be refused while the server is running, got %v", err)
+	}
+	if stats := adminStats(t, dbPath); stats.Todos != 3 {
+		t.Errorf("Expected the database to be untouched, got %d todos", stats.Todos)
+	}
+
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	mustRunAdmin(t, dbPath, "restore", backupPath)
+	if stats := adminStats(t, dbPath); stats.Todos != 2 {
+		t.Errorf("Expected the backup to be restored after the server stopped, got %d todos", stats.Todos)
+	}
+}
+
+func TestBackupDirectoryAndPointInTimeRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "admin.db")
+	backupDir := filepath.Join(dir, "backups")
+	t.Setenv("TODO_BACKUP_DIR", backupDir)
+	t.Setenv("TODO_BACKUP_KEEP", "2")
+
+	if out := mustRunAdmin(t, dbPath, "backup", "--list"); !strings.Contains(out, "No backups") {
+		t.Errorf("Unexpected list output: %q", out)
+	}
+
+	// 每次备份前增加 Todo, 三个备份分别包含 1、2、3 个 Todo
+	var times []time.Time
+	for i := 0; i < 3; i++ {
+		mustRunAdmin(t, dbPath, "seed", "--count", "1")
+		mustRunAdmin(t, dbPath, "backup")
+		times = append(times, time.Now())
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(backupDir, "todo-*.db"))
+	sums, _ := filepath.Glob(filepath.Join(backupDir, "todo-*.db.sha256"))
+	if len(backups) != 2 || len(sums) != 2 {
+		t.Fatalf("Expected 2 backups after rotation, got %v %v", backups, sums)
+	}
+	if out := mustRunAdmin(t, dbPath, "backup", "--list"); strings.Count(out, backupDir) != 2 {
+		t.Errorf("Unexpected list output:\n%s", out)
+	}
+
+	mustRunAdmin(t, dbPath, "restore", "--at", times[1].Format(time.RFC3339Nano))
+	if stats := adminStats(t, dbPath); stats.Todos != 2 {
+		t.Errorf("Expected the second backup to be restored, got %d todos", stats.Todos)
+	}
+
+	// 第一个备份已被轮换删除
+	if _, err := runAdmin(t, dbPath, "", "restore", "--at", times[0].Format(time.RFC3339Nano)); err == nil {
+		t.Error("Expected no backup before the rotated one")
+	}
+	if _, err := runAdmin(t, dbPath, "", "restore"); err == nil {
+		t.Error("Expected restore without a file or --at to fail")
+	}
+}
+
+func TestRestoreVerifiesChecksumAndSchemaVersion(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "admin.db")
+	backupPath := filepath.Join(dir, "backup.db")
+
+	mustRunAdmin(t, dbPath, "seed", "--count", "2")
+	mustRunAdmin(t, dbPath, "backup", backupPath)
+
+	// 修改备份后校验和不再匹配
+	f, _ := os.OpenFile(backupPath, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write(make([]byte, 4096))
+	f.Close()
+	if _, err := runAdmin(t, dbPath, "", "restore", backupPath); !errors.Is(err, backup.ErrChecksumMismatch) {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+
+	os.Remove(backupPath + backup.ChecksumSuffix)
+	if _, err := runAdmin(t, dbPath, "", "restore", backupPath); !errors.Is(err, backup.ErrNoChecksum) {
+		t.Errorf("Expected a missing checksum error, got %v", err)
+	}
+
+	// 来自更新版本程序的备份不能恢复
+	newer := filepath.Join(dir, "newer.db")
+	mustRunAdmin(t, newer, "migrate")
+	if err := database.Open(newer, database.DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	database.DB.Exec(fmt.Sprintf("PRAGMA user_version = %d", database.SchemaVersion+1))
+	database.Close()
+	_, err := runAdmin(t, dbPath, "", "restore", "--skip-checksum", newer)
+	if err == nil || !strings.Contains(err.Error(), "schema version") {
+		t.Errorf("Expected a schema version error, got %v", err)
+	}
+	if stats := adminStats(t, dbPath); stats.Todos != 2 {
+		t.Errorf("Expected the database to be unchanged, got %d todos", stats.Todos)
+	}
+}
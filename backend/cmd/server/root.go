@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"todo-backend/internal/config"
+	"todo-backend/internal/database"
+	"todo-backend/internal/server"
+
+	"github.com/spf13/cobra"
+)
+
+func newRootCommand() *cobra.Command {
+	cfg := config.Load()
+
+	root := &cobra.Command{
+		Use:          "server",
+		Short:        "Todo API server and database administration",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		// 不带子命令时和 serve 相同, 保持原来的启动方式
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return serve(cfg)
+		},
+	}
+	root.PersistentFlags().StringVar(&cfg.DatabasePath, "db", cfg.DatabasePath, "SQLite database file (overrides TODO_DB_PATH)")
+
+	root.AddCommand(
+		&cobra.Command{
+			Use:   "serve",
+			Short: "Start the HTTP and gRPC servers",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return serve(cfg)
+			},
+		},
+		migrateCommand(cfg),
+		userCommand(cfg),
+		backupCommand(cfg),
+		restoreCommand(cfg),
+		vacuumCommand(cfg),
+		seedCommand(cfg),
+		statsCommand(cfg),
+	)
+	return root
+}
+
+// openDatabase 打开配置的数据库. 除 migrate 外的子命令不会创建新文件, 避免路径写错时静默生成空库
+func openDatabase(cfg *config.Config, create bool) error {
+	if !create {
+		if _, err := os.Stat(cfg.DatabasePath); err != nil {
+			return fmt.Errorf("database %s does not exist, run migrate first", cfg.DatabasePath)
+		}
+	}
+	if err := database.Open(cfg.DatabasePath, server.DatabaseOptions(cfg)); err != nil {
+		return err
+	}
+	return database.Migrate(database.DB)
+}
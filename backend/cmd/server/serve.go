@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	"todo-backend/internal/backup"
+	"todo-backend/internal/config"
+	"todo-backend/internal/database"
+	"todo-backend/internal/events"
+	"todo-backend/internal/middleware"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/server"
+	"todo-backend/internal/webhook"
+)
+
+func serve(cfg *config.Config) error {
+	// 运行期间持有数据库锁, restore 命令不能替换正在使用的数据库
+	unlock, err := database.Lock(cfg.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// 初始化数据库
+	if err := database.Open(cfg.DatabasePath, server.DatabaseOptions(cfg)); err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := database.Migrate(database.DB); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	// 初始化事件中心
+	hub := events.NewHub(cfg.EventLogSize)
+
+	// 启动 webhook 投递
+	webhookOptions := webhook.DefaultOptions()
+	webhookOptions.MaxAttempts = cfg.WebhookMaxAttempts
+	webhookOptions.Timeout = cfg.WebhookTimeout
+	webhookOptions.PollInterval = cfg.WebhookPollInterval
+	webhookOptions.BaseBackoff = cfg.WebhookBackoff
+	webhookOptions.AllowPrivateNetworks = cfg.WebhookAllowPrivate
+	dispatcher := webhook.NewDispatcher(repository.NewWebhookRepository(database.DB), hub, webhookOptions)
+	dispatcher.Start(context.Background())
+
+	// 定期删除过期的幂等键
+	if cfg.IdempotencyCleanupInterval > 0 {
+		middleware.CleanupIdempotencyKeys(context.Background(), repository.NewIdempotencyRepository(database.DB), cfg.IdempotencyCleanupInterval)
+	}
+
+	// 定时备份数据库
+	backupManager := backup.NewManager(database.DB, cfg.BackupDir, cfg.BackupKeep)
+	if cfg.BackupInterval > 0 {
+		backupManager.Start(context.Background(), cfg.BackupInterval)
+	}
+
+	deps := server.Deps{
+		Config:     cfg,
+		DB:         database.DB,
+		Hub:        hub,
+		Dispatcher: dispatcher,
+		Backups:    backupManager,
+	}
+	r, err := server.NewRouter(deps)
+	if err != nil {
+		return err
+	}
+
+	// gRPC 服务使用单独的端口
+	if cfg.GRPCAddr != "off" {
+		lis, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", cfg.GRPCAddr, err)
+		}
+		grpcServer := server.NewGRPCServer(deps)
+		go func() {
+			log.Printf("gRPC server listening on %s", lis.Addr())
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("gRPC server stopped: %v", err)
+			}
+		}()
+	}
+
+	// 启动服务器
+	log.Println("Server starting on http://localhost:8080")
+	return r.Run(":8080")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"todo-backend/internal/auth"
+	"todo-backend/internal/config"
+	"todo-backend/internal/database"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"gorm.io/gorm"
+)
+
+func userCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage user accounts",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "create <username>",
+		Short: "Create a user, the password is read from the terminal or stdin",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hash, err := readPasswordHash(cmd)
+			if err != nil {
+				return err
+			}
+			if err := openDatabase(cfg, true); err != nil {
+				return err
+			}
+			defer database.Close()
+
+			user := &model.User{Username: args[0], PasswordHash: hash}
+			if err := repository.NewUserRepository(database.DB).Create(cmd.Context(), user); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Created user %s (#%d)\n", user.Username, user.ID)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "reset-password <username>",
+		Short: "Set a new password for a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := openDatabase(cfg, false); err != nil {
+				return err
+			}
+			defer database.Close()
+
+			repo := repository.NewUserRepository(database.DB)
+			user, err := repo.GetByUsername(cmd.Context(), args[0])
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("user %s not found", args[0])
+			}
+			if err != nil {
+				return err
+			}
+
+			hash, err := readPasswordHash(cmd)
+			if err != nil {
+				return err
+			}
+			if err := repo.UpdatePassword(cmd.Context(), user.ID, hash); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Updated password for %s\n", user.Username)
+			return nil
+		},
+	})
+	return cmd
+}
+
+// readPasswordHash 在终端中不回显地读取两次密码, 否则读取标准输入的第一行, 便于脚本调用
+func readPasswordHash(cmd *cobra.Command) (string, error) {
+	var password string
+	if f, ok := cmd.InOrStdin().(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		first, err := promptPassword(cmd, f, "Password: ")
+		if err != nil {
+			return "", err
+		}
+		second, err := promptPassword(cmd, f, "Repeat password: ")
+		if err != nil {
+			return "", err
+		}
+		if first != second {
+			return "", errors.New("passwords do not match")
+		}
+		password = first
+	} else {
+		line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	return auth.HashPassword(password)
+}
+
+func promptPassword(cmd *cobra.Command, f *os.File, prompt string) (string, error) {
+	fmt.Fprint(cmd.ErrOrStderr(), prompt)
+	password, err := term.ReadPassword(int(f.Fd()))
+	fmt.Fprintln(cmd.ErrOrStderr())
+	return string(password), err
+}
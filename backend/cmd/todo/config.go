@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultServer = "http://localhost:8080"
+
+// cliConfig 保存在配置文件中, 命令行参数和环境变量 TODO_SERVER、TODO_TOKEN 优先
+type cliConfig struct {
+	Server string `json:"server,omitempty"`
+	Token  string `json:"token,omitempty"`
+}
+
+// defaultConfigPath 返回 $XDG_CONFIG_HOME/todo/config.json (各平台的用户配置目录)
+func defaultConfigPath() string {
+	if path := os.Getenv("TODO_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "todo.json"
+	}
+	return filepath.Join(dir, "todo", "config.json")
+}
+
+// loadConfig 读取配置文件, 文件不存在时返回空配置
+func loadConfig(path string) (*cliConfig, error) {
+	cfg := &cliConfig{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// save 写入配置文件, 文件中可能有 token, 因此只允许当前用户读写
+func (c *cliConfig) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+func (c *cliConfig) set(key, value string) error {
+	switch key {
+	case "server":
+		if !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+			return fmt.Errorf("server must start with http:// or https://")
+		}
+		c.Server = strings.TrimRight(value, "/")
+	case "token":
+		c.Token = value
+	default:
+		return fmt.Errorf("unknown config key %q (expected server or token)", key)
+	}
+	return nil
+}
+
+// maskToken 只显示 token 的最后 4 个字符
+func maskToken(token string) string {
+	if len(token) <= 4 {
+		return strings.Repeat("*", len(token))
+	}
+	return strings.Repeat("*", len(token)-4) + token[len(token)-4:]
+}
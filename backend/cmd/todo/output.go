@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"todo-backend/internal/model"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeTodos(w io.Writer, format string, todos []model.Todo) error {
+	if format == outputJSON {
+		if todos == nil {
+			todos = []model.Todo{}
+		}
+		return writeJSON(w, todos)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDONE\tTITLE\tDUE\tUPDATED")
+	for _, todo := range todos {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", todo.ID, checkbox(todo.Completed), todo.Title, formatTime(todo.DueAt), formatTime(&todo.UpdatedAt))
+	}
+	return tw.Flush()
+}
+
+func writeTodo(w io.Writer, format string, todo *model.Todo) error {
+	if format == outputJSON {
+		return writeJSON(w, todo)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%d\n", todo.ID)
+	fmt.Fprintf(tw, "Title:\t%s\n", todo.Title)
+	fmt.Fprintf(tw, "Done:\t%s\n", checkbox(todo.Completed))
+	if todo.Content != "" {
+		fmt.Fprintf(tw, "Content:\t%s\n", todo.Content)
+	}
+	if todo.DueAt != nil {
+		fmt.Fprintf(tw, "Due:\t%s\n", formatTime(todo.DueAt))
+	}
+	fmt.Fprintf(tw, "Version:\t%d\n", todo.Version)
+	fmt.Fprintf(tw, "Updated:\t%s\n", formatTime(&todo.UpdatedAt))
+	return tw.Flush()
+}
+
+func checkbox(done bool) string {
+	if done {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"todo-backend/internal/client"
+	"todo-backend/internal/model"
+
+	"github.com/spf13/cobra"
+)
+
+// app 保存全局参数, 各子命令通过它访问配置和 API 客户端
+type app struct {
+	configPath string
+	server     string
+	token      string
+	output     string
+
+	cfg *cliConfig
+}
+
+func newRootCommand() *cobra.Command {
+	a := &app{}
+
+	root := &cobra.Command{
+		Use:          "todo",
+		Short:        "Command-line client for the todo API",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if a.output != outputTable && a.output != outputJSON {
+				return fmt.Errorf("invalid output %q (expected table or json)", a.output)
+			}
+			cfg, err := loadConfig(a.configPath)
+			if err != nil {
+				return err
+			}
+			a.cfg = cfg
+			return nil
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&a.configPath, "config", defaultConfigPath(), "config file")
+	flags.StringVar(&a.server, "server", "", "server URL (overrides TODO_SERVER and the config file)")
+	flags.StringVar(&a.token, "token", "", "API token (overrides TODO_TOKEN and the config file)")
+	flags.StringVarP(&a.output, "output", "o", outputTable, "output format: table or json")
+	root.RegisterFlagCompletionFunc("output", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return []string{outputTable, outputJSON}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	root.AddCommand(
+		a.addCommand(),
+		a.listCommand(),
+		a.doneCommand(),
+		a.editCommand(),
+		a.removeCommand(),
+		a.configCommand(),
+	)
+	return root
+}
+
+// client 按 命令行参数 > 环境变量 > 配置文件 的顺序确定服务地址和 token
+func (a *app) client() *client.Client {
+	// 补全时不会执行 PersistentPreRunE, 需要在这里读取配置
+	if a.cfg == nil {
+		a.cfg, _ = loadConfig(a.configPath)
+		if a.cfg == nil {
+			a.cfg = &cliConfig{}
+		}
+	}
+
+	server := firstNonEmpty(a.server, os.Getenv("TODO_SERVER"), a.cfg.Server, defaultServer)
+	token := firstNonEmpty(a.token, os.Getenv("TODO_TOKEN"), a.cfg.Token)
+	return client.New(server, token)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func parseIDs(args []string) ([]uint, error) {
+	ids := make([]uint, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseUint(strings.TrimPrefix(arg, "#"), 10, 32)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("invalid todo id %q", arg)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// completeIDs 补全 Todo ID, 并把标题作为说明显示
+func (a *app) completeIDs(pending bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		todos, err := a.client().ListTodos(cmd.Context())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		var completions []string
+		for _, todo := range todos {
+			if pending && todo.Completed {
+				continue
+			}
+			completions = append(completions, fmt.Sprintf("%d\t%s", todo.ID, todo.Title))
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func (a *app) addCommand() *cobra.Command {
+	var content string
+
+	cmd := &cobra.Command{
+		Use:   "add <title>",
+		Short: "Create a todo",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			todo, err := a.client().CreateTodo(cmd.Context(), &model.CreateTodoRequest{Title: args[0], Content: content})
+			if err != nil {
+				return err
+			}
+			if a.output == outputJSON {
+				return writeJSON(cmd.OutOrStdout(), todo)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Added #%d %s\n", todo.ID, todo.Title)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&content, "content", "c", "", "todo content")
+	return cmd
+}
+
+func (a *app) listCommand() *cobra.Command {
+	var done, pending bool
+	var query string
+
+	cmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List todos",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			todos, err := a.client().ListTodos(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			filtered := todos[:0]
+			for _, todo := range todos {
+				if (done && !todo.Completed) || (pending && todo.Completed) {
+					continue
+				}
+				if query != "" && !containsFold(todo.Title, query) && !containsFold(todo.Content, query) {
+					continue
+				}
+				filtered = append(filtered, todo)
+			}
+			return writeTodos(cmd.OutOrStdout(), a.output, filtered)
+		},
+	}
+	cmd.Flags().BoolVar(&done, "done", false, "only show completed todos")
+	cmd.Flags().BoolVar(&pending, "pending", false, "only show pending todos")
+	cmd.Flags().StringVarP(&query, "query", "q", "", "only show todos whose title or content contains the text")
+	cmd.MarkFlagsMutuallyExclusive("done", "pending")
+	return cmd
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func (a *app) doneCommand() *cobra.Command {
+	var undo bool
+
+	cmd := &cobra.Command{
+		Use:   "done <id>...",
+		Short: "Mark todos as completed",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := parseIDs(args)
+			if err != nil {
+				return err
+			}
+
+			var updated []model.Todo
+			for _, id := range ids {
+				todo, err := a.update(cmd.Context(), id, func(req *model.UpdateTodoRequest) {
+					completed := !undo
+					req.Completed = &completed
+				})
+				if err != nil {
+					return fmt.Errorf("todo #%d: %w", id, err)
+				}
+				updated = append(updated, *todo)
+				if a.output == outputTable {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s #%d %s\n", checkbox(todo.Completed), todo.ID, todo.Title)
+				}
+			}
+			if a.output == outputJSON {
+				return writeJSON(cmd.OutOrStdout(), updated)
+			}
+			return nil
+		},
+		ValidArgsFunction: a.completeIDs(true),
+	}
+	cmd.Flags().BoolVar(&undo, "undo", false, "mark todos as pending again")
+	return cmd
+}
+
+func (a *app) editCommand() *cobra.Command {
+	var title, content string
+
+	cmd := &cobra.Command{
+		Use:   "edit <id>",
+		Short: "Change the title or content of a todo",
+		Long: "Change the title or content of a todo. The API keeps the current value for empty fields,\n" +
+			"so an empty --title or --content leaves that field unchanged.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("title") && !cmd.Flags().Changed("content") {
+				return fmt.Errorf("nothing to change, use --title or --content")
+			}
+			ids, err := parseIDs(args)
+			if err != nil {
+				return err
+			}
+
+			todo, err := a.update(cmd.Context(), ids[0], func(req *model.UpdateTodoRequest) {
+				if cmd.Flags().Changed("title") {
+					req.Title = title
+				}
+				if cmd.Flags().Changed("content") {
+					req.Content = content
+				}
+			})
+			if err != nil {
+				return err
+			}
+			return writeTodo(cmd.OutOrStdout(), a.output, todo)
+		},
+		ValidArgsFunction: a.completeIDs(false),
+	}
+	cmd.Flags().StringVarP(&title, "title", "t", "", "new title")
+	cmd.Flags().StringVarP(&content, "content", "c", "", "new content")
+	return cmd
+}
+
+// update 只发送 apply 设置的字段, 并用读取到的 ETag 作为 If-Match, 避免覆盖其他客户端在此期间的修改
+func (a *app) update(ctx context.Context, id uint, apply func(req *model.UpdateTodoRequest)) (*model.Todo, error) {
+	c := a.client()
+	_, etag, err := c.GetTodo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &model.UpdateTodoRequest{}
+	apply(req)
+	return c.UpdateTodo(ctx, id, req, etag)
+}
+
+func (a *app) removeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm <id>...",
+		Aliases: []string{"remove"},
+		Short:   "Delete todos",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := parseIDs(args)
+			if err != nil {
+				return err
+			}
+
+			c := a.client()
+			for _, id := range ids {
+				if err := c.DeleteTodo(cmd.Context(), id, ""); err != nil {
+					return fmt.Errorf("todo #%d: %w", id, err)
+				}
+				if a.output == outputTable {
+					fmt.Fprintf(cmd.OutOrStdout(), "Removed #%d\n", id)
+				}
+			}
+			if a.output == outputJSON {
+				return writeJSON(cmd.OutOrStdout(), map[string][]uint{"removed": ids})
+			}
+			return nil
+		},
+		ValidArgsFunction: a.completeIDs(false),
+	}
+}
+
+func (a *app) configCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show or change the config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if a.output == outputJSON {
+				return writeJSON(cmd.OutOrStdout(), cliConfig{Server: a.client().BaseURL, Token: maskToken(a.client().Token)})
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "config: %s\nserver: %s\ntoken:  %s\n", a.configPath, a.client().BaseURL, maskToken(a.client().Token))
+			return nil
+		},
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set <server|token> <value>",
+		Short: "Save a value to the config file",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return []string{"server", "token"}, cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := a.cfg.set(args[0], args[1]); err != nil {
+				return err
+			}
+			if err := a.cfg.save(a.configPath); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Saved %s to %s\n", args[0], a.configPath)
+			return nil
+		},
+	})
+	return cmd
+}
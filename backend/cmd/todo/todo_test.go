@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"todo-backend/internal/model"
+	"todo-backend/internal/testutil"
+)
+
+// run 对测试自己的服务器执行一条命令并返回标准输出, 配置文件放在测试的临时目录中
+func run(t *testing.T, server *testutil.Server, args ...string) (string, error) {
+	t.Helper()
+
+	cmd := newRootCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"--server", server.URL, "--config", filepath.Join(t.TempDir(), "config.json")}, args...))
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func mustRun(t *testing.T, server *testutil.Server, args ...string) string {
+	t.Helper()
+
+	out, err := run(t, server, args...)
+	if err != nil {
+		t.Fatalf("todo %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+func addTodo(t *testing.T, server *testutil.Server, title string) model.Todo {
+	t.Helper()
+
+	var todo model.Todo
+	if err := json.Unmarshal([]byte(mustRun(t, server, "add", title, "-o", "json")), &todo); err != nil {
+		t.Fatalf("Failed to decode todo: %v", err)
+	}
+	return todo
+}
+
+func listTodos(t *testing.T, server *testutil.Server, args ...string) []model.Todo {
+	t.Helper()
+
+	var todos []model.Todo
+	if err := json.Unmarshal([]byte(mustRun(t, server, append([]string{"ls", "-o", "json"}, args...)...)), &todos); err != nil {
+		t.Fatalf("Failed to decode todos: %v", err)
+	}
+	return todos
+}
+
+func TestAddAndList(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	out := mustRun(t, server, "add", "Write the CLI", "--content", "with cobra")
+	if !strings.HasPrefix(out, "Added #") || !strings.Contains(out, "Write the CLI") {
+		t.Errorf("Unexpected add output: %q", out)
+	}
+
+	out = mustRun(t, server, "ls", "-q", "cobra")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[1], "[ ]") || !strings.Contains(lines[1], "Write the CLI") {
+		t.Errorf("Unexpected table output:\n%s", out)
+	}
+
+	todos := listTodos(t, server, "-q", "cobra")
+	if len(todos) != 1 || todos[0].Content != "with cobra" {
+		t.Errorf("Unexpected JSON output: %+v", todos)
+	}
+}
+
+func TestDoneAndFilters(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	first := addTodo(t, server, "Filter first")
+	second := addTodo(t, server, "Filter second")
+
+	out := mustRun(t, server, "done", "#"+itoa(first.ID))
+	if !strings.Contains(out, "[x]") {
+		t.Errorf("Unexpected done output: %q", out)
+	}
+
+	done := listTodos(t, server, "--done", "-q", "Filter")
+	pending := listTodos(t, server, "--pending", "-q", "Filter")
+	if len(done) != 1 || done[0].ID != first.ID || len(pending) != 1 || pending[0].ID != second.ID {
+		t.Errorf("Unexpected filters: done %+v, pending %+v", done, pending)
+	}
+
+	if _, err := run(t, server, "ls", "--done", "--pending"); err == nil {
+		t.Error("Expected --done and --pending to be mutually exclusive")
+	}
+
+	mustRun(t, server, "done", "--undo", itoa(first.ID))
+	if pending := listTodos(t, server, "--pending", "-q", "Filter"); len(pending) != 2 {
+		t.Errorf("Expected both todos to be pending, got %+v", pending)
+	}
+}
+
+func TestEditKeepsOtherFields(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	todo := addTodo(t, server, "Edit me")
+	mustRun(t, server, "done", itoa(todo.ID))
+
+	var edited model.Todo
+	json.Unmarshal([]byte(mustRun(t, server, "edit", itoa(todo.ID), "--content", "details", "-o", "json")), &edited)
+	if edited.Title != "Edit me" || edited.Content != "details" || !edited.Completed {
+		t.Errorf("Expected edit to keep title and status, got %+v", edited)
+	}
+
+	out := mustRun(t, server, "edit", itoa(todo.ID), "-t", "Edited")
+	if !strings.Contains(out, "Title:") || !strings.Contains(out, "Edited") {
+		t.Errorf("Unexpected edit output:\n%s", out)
+	}
+
+	if _, err := run(t, server, "edit", itoa(todo.ID)); err == nil {
+		t.Error("Expected edit without flags to fail")
+	}
+}
+
+func TestRemoveAndErrors(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	todo := addTodo(t, server, "Remove me")
+	if out := mustRun(t, server, "rm", itoa(todo.ID)); !strings.Contains(out, "Removed #"+itoa(todo.ID)) {
+		t.Errorf("Unexpected rm output: %q", out)
+	}
+
+	out, err := run(t, server, "done", itoa(todo.ID))
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(out, "todo not found") {
+		t.Errorf("Expected a not found error, got %v\n%s", err, out)
+	}
+
+	if _, err := run(t, server, "add", ""); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected a validation error, got %v", err)
+	}
+	if _, err := run(t, server, "rm", "abc"); err == nil {
+		t.Error("Expected an invalid id error")
+	}
+	if _, err := run(t, server, "ls", "-o", "yaml"); err == nil {
+		t.Error("Expected an invalid output error")
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	path := filepath.Join(t.TempDir(), "todo", "config.json")
+
+	cmd := newRootCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--config", path, "config", "set", "server", server.URL + "/"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	cmd = newRootCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--config", path, "config", "set", "token", "secret-token"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected config file with mode 0600, got %v %v", info, err)
+	}
+
+	// 不指定 --server 时使用配置文件中的地址
+	var out bytes.Buffer
+	cmd = newRootCommand()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--config", path, "config"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("config failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "server: "+server.URL+"\n") || !strings.Contains(out.String(), "********oken") {
+		t.Errorf("Unexpected config output:\n%s", out.String())
+	}
+
+	cmd = newRootCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--config", path, "config", "set", "colour", "blue"})
+	if err := cmd.Execute(); err == nil {
+		t.Error("Expected an unknown key error")
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	todo := addTodo(t, server, "Complete me")
+
+	out := mustRun(t, server, "completion", "bash")
+	if !strings.Contains(out, "__start_todo") {
+		t.Errorf("Expected a bash completion script")
+	}
+
+	out = mustRun(t, server, "__complete", "rm", "")
+	if !strings.Contains(out, itoa(todo.ID)+"\tComplete me") {
+		t.Errorf("Expected todo ids in completions, got:\n%s", out)
+	}
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,274 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-backend/internal/database"
+
+	"gorm.io/gorm"
+)
+
+const (
+	prefix = "todo-"
+	suffix = ".db"
+
+	// ChecksumSuffix 是校验文件的后缀, 内容与 sha256sum 的输出格式相同, 可以用 sha256sum -c 检查
+	ChecksumSuffix = ".sha256"
+
+	// 文件名中的时间使用 UTC, 精确到毫秒以免手动和定时备份重名
+	timeLayout = "20060102T150405.000Z"
+)
+
+var (
+	ErrNoBackup         = errors.New("no backup found")
+	ErrNoChecksum       = errors.New("checksum file not found")
+	ErrChecksumMismatch = errors.New("checksum does not match")
+)
+
+// Backup 描述备份目录中的一个备份文件
+type Backup struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"-"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Manager 把数据库备份到一个目录, 并只保留最新的 keep 个
+type Manager struct {
+	db   *gorm.DB
+	dir  string
+	keep int
+
+	// 同一时间只执行一个备份
+	mu sync.Mutex
+}
+
+// NewManager 创建 db 的备份管理器, keep 小于 1 时不删除旧备份
+func NewManager(db *gorm.DB, dir string, keep int) *Manager {
+	return &Manager{db: db, dir: dir, keep: keep}
+}
+
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// Create 用 VACUUM INTO 写入新备份, 检查完整性并生成校验文件后再删除多余的旧备份.
+// 备份先写入临时文件, 列表中不会出现不完整的备份
+func (m *Manager) Create() (*Backup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	name := prefix + now.Format(timeLayout) + suffix
+	path := filepath.Join(m.dir, name)
+	tmp := path + ".partial"
+	defer os.Remove(tmp)
+
+	if err := database.Backup(m.db, tmp); err != nil {
+		return nil, err
+	}
+	if err := database.CheckIntegrity(tmp); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+
+	sum, err := WriteChecksum(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.rotate(); err != nil {
+		return nil, err
+	}
+	return &Backup{Name: name, Path: path, Size: info.Size(), SHA256: sum, CreatedAt: now}, nil
+}
+
+// List 返回备份目录中的备份, 最新的在前
+func (m *Manager) List() ([]Backup, error) {
+	entries, err := os.ReadDir(m.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Backup{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []Backup{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		createdAt, err := time.Parse(timeLayout, strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix))
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(m.dir, name)
+		sum, _ := readChecksum(path)
+		backups = append(backups, Backup{Name: name, Path: path, Size: info.Size(), SHA256: sum, CreatedAt: createdAt})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// Find 返回在 at 或之前创建的最新备份, 用于恢复到某个时间点
+func (m *Manager) Find(at time.Time) (*Backup, error) {
+	backups, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range backups {
+		if !backups[i].CreatedAt.After(at) {
+			return &backups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w at or before %s", ErrNoBackup, at.Format(time.RFC3339))
+}
+
+// Start 每隔 interval 执行一次备份, 直到 ctx 结束
+func (m *Manager) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				info, err := m.Create()
+				if err != nil {
+					log.Printf("backup: %v", err)
+					continue
+				}
+				log.Printf("backup: wrote %s (%d bytes)", info.Path, info.Size)
+			}
+		}
+	}()
+}
+
+func (m *Manager) rotate() error {
+	if m.keep < 1 {
+		return nil
+	}
+	backups, err := m.List()
+	if err != nil {
+		return err
+	}
+	for _, old := range backups[min(m.keep, len(backups)):] {
+		if err := os.Remove(old.Path); err != nil {
+			return err
+		}
+		if err := os.Remove(old.Path + ChecksumSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteChecksum 计算 path 的 SHA-256 并写入 path.sha256
+func WriteChecksum(path string) (string, error) {
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return "", err
+	}
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))
+	if err := os.WriteFile(path+ChecksumSuffix, []byte(line), 0o644); err != nil {
+		return "", err
+	}
+	return sum, nil
+}
+
+// Verify 检查 path 与校验文件中的 SHA-256 是否一致
+func Verify(path string) error {
+	expected, err := readChecksum(path)
+	if err != nil {
+		return err
+	}
+	actual, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("%s: %w", path, ErrChecksumMismatch)
+	}
+	return nil
+}
+
+// Restore 校验备份后替换 dest, 调用前必须关闭 dest 上的所有连接.
+// verify 为 false 时跳过 SHA-256 检查, 用于恢复没有校验文件的备份
+func Restore(src, dest string, verify bool) error {
+	if verify {
+		if err := Verify(src); err != nil {
+			return err
+		}
+	}
+	return database.Restore(src, dest)
+}
+
+func readChecksum(path string) (string, error) {
+	f, err := os.Open(path + ChecksumSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%s: %w", path, ErrNoChecksum)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("%s: invalid checksum file", path)
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
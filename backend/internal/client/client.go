@@ -0,0 +1,128 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"todo-backend/internal/model"
+)
+
+// Client 是 REST API 的客户端, 解析统一的 {code, data, message} 响应格式
+type Client struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+// APIError 是服务端返回的非 2xx 响应
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server returned %d %s", e.Status, http.StatusText(e.Status))
+	}
+	return fmt.Sprintf("server returned %d: %s", e.Status, e.Message)
+}
+
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		HTTP:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *Client) ListTodos(ctx context.Context) ([]model.Todo, error) {
+	var todos []model.Todo
+	_, err := c.do(ctx, http.MethodGet, "/api/todos", nil, nil, &todos)
+	return todos, err
+}
+
+// GetTodo 返回 Todo 和它的 ETag, ETag 可用于之后修改时的 If-Match
+func (c *Client) GetTodo(ctx context.Context, id uint) (*model.Todo, string, error) {
+	var todo model.Todo
+	header, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/todos/%d", id), nil, nil, &todo)
+	if err != nil {
+		return nil, "", err
+	}
+	return &todo, header.Get("ETag"), nil
+}
+
+func (c *Client) CreateTodo(ctx context.Context, req *model.CreateTodoRequest) (*model.Todo, error) {
+	var todo model.Todo
+	_, err := c.do(ctx, http.MethodPost, "/api/todos", req, nil, &todo)
+	return &todo, err
+}
+
+// UpdateTodo 使用 PUT 更新 Todo, ifMatch 不为空时版本不匹配返回 412
+func (c *Client) UpdateTodo(ctx context.Context, id uint, req *model.UpdateTodoRequest, ifMatch string) (*model.Todo, error) {
+	var todo model.Todo
+	_, err := c.do(ctx, http.MethodPut, fmt.Sprintf("/api/todos/%d", id), req, ifMatchHeader(ifMatch), &todo)
+	return &todo, err
+}
+
+func (c *Client) DeleteTodo(ctx context.Context, id uint, ifMatch string) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/todos/%d", id), nil, ifMatchHeader(ifMatch), nil)
+	return err
+}
+
+func ifMatchHeader(ifMatch string) http.Header {
+	if ifMatch == "" {
+		return nil
+	}
+	return http.Header{"If-Match": {ifMatch}}
+}
+
+// do 发送请求并把响应中的 data 解码到 out
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, header http.Header, out interface{}) (http.Header, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response := model.Response{Data: out}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil && err != io.EOF {
+		if resp.StatusCode >= 300 {
+			return nil, &APIError{Status: resp.StatusCode}
+		}
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, &APIError{Status: resp.StatusCode, Message: response.Message}
+	}
+	return resp.Header, nil
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	DatabasePath string
+
+	// SQLite 连接设置, 见 database.Options
+	DBJournalMode string
+	DBSynchronous string
+	DBBusyTimeout time.Duration
+	DBForeignKeys bool
+	DBReadConns   int
+
+	// 定时备份: 间隔为 0 时不启动, 只保留最新的 BackupKeep 个
+	BackupDir      string
+	BackupInterval time.Duration
+	BackupKeep     int
+
+	// 管理接口使用 Bearer token 认证, 为空时不启用
+	AdminToken string
+
+	// 允许跨域访问的来源, 逗号分隔, 支持 * 和 https://*.example.com 形式的通配子域名
+	CORSOrigins          []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+
+	// 请求频率限制, 格式为 <次数>/<时间>, 设置为 off 时不限制; 路由限制的格式为 "POST /api/todos=60/m"
+	RateLimit       string
+	RateLimitRoutes []string
+	// 信任其 X-Forwarded-For 头的代理地址, 为空时直接使用连接的来源 IP
+	TrustedProxies []string
+
+	// 每个调用方的 Todo 数量和文本总字节数的上限, 0 表示不限制
+	QuotaMaxTodos        int64
+	QuotaMaxStorageBytes int64
+
+	TitleMaxLength   int
+	ContentMaxLength int
+	MaxBodyBytes     int64
+	IdempotencyTTL   time.Duration
+	// IdempotencyCleanupInterval 是删除过期幂等键的间隔
+	IdempotencyCleanupInterval time.Duration
+	EventLogSize               int
+
+	// 一个请求中所有数据库查询的总时间上限, 0 表示不限制
+	QueryTimeout time.Duration
+
+	WebhookMaxAttempts  int
+	WebhookTimeout      time.Duration
+	WebhookPollInterval time.Duration
+	WebhookBackoff      time.Duration
+	WebhookAllowPrivate bool
+
+	ImportMaxBytes int64
+	ImportMaxRows  int
+
+	CalendarToken  string
+	CalendarDomain string
+
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
+
+	// gRPC 服务监听地址, 设置为 off 时不启动
+	GRPCAddr string
+
+	// 设置后把前端请求代理到 Vite 开发服务器, 不使用编译进程序的前端
+	FrontendDevURL string
+
+	// CalDAV 使用 HTTP Basic 认证, 用户名和密码都为空时不启用
+	CalDAVUsername string
+	CalDAVPassword string
+}
+
+func Load() *Config {
+	return &Config{
+		DatabasePath: getEnv("TODO_DB_PATH", "todo.db"),
+
+		DBJournalMode: getEnv("TODO_DB_JOURNAL_MODE", "WAL"),
+		DBSynchronous: getEnv("TODO_DB_SYNCHRONOUS", "NORMAL"),
+		DBBusyTimeout: getEnvDuration("TODO_DB_BUSY_TIMEOUT", 5*time.Second),
+		DBForeignKeys: getEnvBool("TODO_DB_FOREIGN_KEYS", true),
+		DBReadConns:   getEnvInt("TODO_DB_READ_CONNS", 4),
+
+		BackupDir:      getEnv("TODO_BACKUP_DIR", "backups"),
+		BackupInterval: getEnvDuration("TODO_BACKUP_INTERVAL", 24*time.Hour),
+		BackupKeep:     getEnvInt("TODO_BACKUP_KEEP", 7),
+
+		AdminToken: getEnv("TODO_ADMIN_TOKEN", ""),
+
+		CORSOrigins:          getEnvList("TODO_CORS_ORIGINS", []string{"*"}),
+		CORSAllowCredentials: getEnvBool("TODO_CORS_ALLOW_CREDENTIALS", false),
+		CORSMaxAge:           getEnvDuration("TODO_CORS_MAX_AGE", 10*time.Minute),
+
+		RateLimit:       getEnv("TODO_RATE_LIMIT", "20/s"),
+		RateLimitRoutes: getEnvList("TODO_RATE_LIMIT_ROUTES", []string{"POST /api/todos=60/m"}),
+		TrustedProxies:  getEnvList("TODO_TRUSTED_PROXIES", nil),
+
+		QuotaMaxTodos:        int64(getEnvInt("TODO_QUOTA_MAX_TODOS", 0)),
+		QuotaMaxStorageBytes: int64(getEnvInt("TODO_QUOTA_MAX_STORAGE_BYTES", 0)),
+
+		TitleMaxLength:             getEnvInt("TODO_TITLE_MAX_LENGTH", 200),
+		ContentMaxLength:           getEnvInt("TODO_CONTENT_MAX_LENGTH", 10000),
+		MaxBodyBytes:               int64(getEnvInt("TODO_MAX_BODY_BYTES", 1<<20)),
+		IdempotencyTTL:             getEnvDuration("TODO_IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyCleanupInterval: getEnvDuration("TODO_IDEMPOTENCY_CLEANUP_INTERVAL", 10*time.Minute),
+		EventLogSize:               getEnvInt("TODO_EVENT_LOG_SIZE", 1000),
+
+		QueryTimeout: getEnvDuration("TODO_QUERY_TIMEOUT", 5*time.Second),
+
+		WebhookMaxAttempts:  getEnvInt("TODO_WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookTimeout:      getEnvDuration("TODO_WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookPollInterval: getEnvDuration("TODO_WEBHOOK_POLL_INTERVAL", 5*time.Second),
+		WebhookBackoff:      getEnvDuration("TODO_WEBHOOK_BACKOFF", 10*time.Second),
+		WebhookAllowPrivate: getEnvBool("TODO_WEBHOOK_ALLOW_PRIVATE", false),
+
+		ImportMaxBytes: int64(getEnvInt("TODO_IMPORT_MAX_BYTES", 10<<20)),
+		ImportMaxRows:  getEnvInt("TODO_IMPORT_MAX_ROWS", 5000),
+
+		CalendarToken:  getEnv("TODO_CALENDAR_TOKEN", ""),
+		CalendarDomain: getEnv("TODO_CALENDAR_DOMAIN", "todo-backend"),
+
+		GraphQLMaxDepth:      getEnvInt("TODO_GRAPHQL_MAX_DEPTH", 8),
+		GraphQLMaxComplexity: getEnvInt("TODO_GRAPHQL_MAX_COMPLEXITY", 1000),
+
+		GRPCAddr: getEnv("TODO_GRPC_ADDR", ":9090"),
+
+		FrontendDevURL: getEnv("TODO_FRONTEND_DEV_URL", ""),
+
+		CalDAVUsername: getEnv("TODO_CALDAV_USERNAME", ""),
+		CalDAVPassword: getEnv("TODO_CALDAV_PASSWORD", ""),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvList 读取逗号分隔的列表, 忽略空项
+func getEnvList(key string, fallback []string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return fallback
+	}
+	return list
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
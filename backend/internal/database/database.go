@@ -1,6 +1,14 @@
 package database
 
 import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
 	"todo-backend/internal/model"
 
 	"gorm.io/driver/sqlite"
@@ -9,32 +17,141 @@ import (
 
 var DB *gorm.DB
 
-func InitDatabase() error {
-	var err error
-	DB, err = gorm.Open(sqlite.Open("todo.db"), &gorm.Config{})
-	if err != nil {
-		return err
+// DefaultPath 是未配置 TODO_DB_PATH 时使用的数据库文件
+const DefaultPath = "todo.db"
+
+// SchemaVersion 保存在 PRAGMA user_version 中, 表结构有不兼容的变化时加 1.
+// 恢复备份时拒绝版本比当前程序更新的文件
+const SchemaVersion = 1
+
+// Options 是打开数据库时设置的 PRAGMA 和连接数
+type Options struct {
+	// JournalMode 和 Synchronous 为空时使用 SQLite 的默认值
+	JournalMode string
+	Synchronous string
+	// BusyTimeout 是等待其他连接释放锁的时间, 0 表示立即返回 SQLITE_BUSY
+	BusyTimeout time.Duration
+	ForeignKeys bool
+	// ReadConns 是只读连接池的大小. 写操作和事务始终使用唯一的写连接,
+	// 为 0 时读写共用这一个连接
+	ReadConns int
+}
+
+// DefaultOptions 返回服务默认使用的设置: WAL 模式下读写互不阻塞, 写操作在进程内排队而不是争抢文件锁
+func DefaultOptions() Options {
+	return Options{
+		JournalMode: "WAL",
+		Synchronous: "NORMAL",
+		BusyTimeout: 5 * time.Second,
+		ForeignKeys: true,
+		ReadConns:   4,
 	}
+}
 
-	err = DB.AutoMigrate(&model.Todo{})
+// Open 打开数据库作为全局的 DB, 不执行迁移
+func Open(path string, opts Options) error {
+	db, err := Connect(path, opts)
 	if err != nil {
 		return err
 	}
-
+	DB = db
 	return nil
 }
 
-func InitTestDatabase(dbName string) error {
-	var err error
-	DB, err = gorm.Open(sqlite.Open(dbName), &gorm.Config{})
+// Connect 打开 dsn 指向的数据库并返回连接, 不修改全局的 DB. dsn 中已有的参数优先于 opts
+func Connect(dsn string, opts Options) (*gorm.DB, error) {
+	params := url.Values{}
+	params.Set("_busy_timeout", strconv.FormatInt(opts.BusyTimeout.Milliseconds(), 10))
+	params.Set("_foreign_keys", strconv.FormatBool(opts.ForeignKeys))
+	// 写连接使用 BEGIN IMMEDIATE, 事务开始时就拿到写锁, 避免读锁升级失败
+	params.Set("_txlock", "immediate")
+	if opts.Synchronous != "" {
+		params.Set("_synchronous", opts.Synchronous)
+	}
+	if opts.JournalMode != "" {
+		params.Set("_journal_mode", opts.JournalMode)
+	}
+
+	writer, err := sql.Open(sqlite.DriverName, withParams(dsn, params))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	writer.SetMaxOpenConns(1)
+	// 先建立写连接, 让 journal_mode 在只读连接打开之前生效
+	if err := writer.Ping(); err != nil {
+		writer.Close()
+		return nil, err
+	}
+
+	var pool gorm.ConnPool = writer
+	if opts.ReadConns > 0 {
+		params.Del("_txlock")
+		params.Del("_journal_mode")
+		params.Set("_query_only", "true")
+		reader, err := sql.Open(sqlite.DriverName, withParams(dsn, params))
+		if err != nil {
+			writer.Close()
+			return nil, err
+		}
+		reader.SetMaxOpenConns(opts.ReadConns)
+		pool = &connPool{writer: writer, reader: reader}
 	}
 
-	err = DB.AutoMigrate(&model.Todo{})
+	db, err := gorm.Open(sqlite.Dialector{Conn: pool}, &gorm.Config{})
 	if err != nil {
-		return err
+		closePool(pool)
+		return nil, err
 	}
+	return db, nil
+}
+
+// withParams 把 params 追加到 dsn 之后, go-sqlite3 对重复的参数只使用第一个
+func withParams(dsn string, params url.Values) string {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + params.Encode()
+}
+
+// Close 关闭当前连接, 恢复备份等需要替换数据库文件的操作之前调用
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	db := DB
+	DB = nil
+	return CloseDB(db)
+}
+
+// CloseDB 关闭 db 的写连接和只读连接池
+func CloseDB(db *gorm.DB) error {
+	return closePool(db.ConnPool)
+}
 
+func closePool(pool gorm.ConnPool) error {
+	if closer, ok := pool.(io.Closer); ok {
+		return closer.Close()
+	}
 	return nil
 }
+
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&model.Todo{},
+		&model.IdempotencyKey{},
+		&model.Webhook{},
+		&model.WebhookDelivery{},
+		&model.User{},
+	)
+	if err != nil {
+		return err
+	}
+	// 早期版本保存了 Webhook 接收端的响应体, 可能包含内网服务的内容
+	if db.Migrator().HasColumn(&model.WebhookDelivery{}, "response_body") {
+		if err := db.Exec("ALTER TABLE webhook_deliveries DROP COLUMN response_body").Error; err != nil {
+			return err
+		}
+	}
+	return db.Exec(fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion)).Error
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LockSuffix 是数据库锁文件的后缀, 锁文件与数据库位于同一目录
+const LockSuffix = ".lock"
+
+// ErrLocked 表示数据库正被运行中的服务使用
+var ErrLocked = errors.New("database is in use by a running server")
+
+// Lock 获取 path 的独占锁, 直到调用返回的 unlock. serve 命令在整个运行期间持有这个锁,
+// restore 拿不到锁时拒绝替换数据库. 进程退出时操作系统自动释放锁, 不会留下失效的锁
+func Lock(path string) (unlock func() error, err error) {
+	f, err := os.OpenFile(path+LockSuffix, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return nil, err
+	}
+
+	// 记录持有锁的进程, 便于排查
+	f.Truncate(0)
+	fmt.Fprintf(f, "%d\n", os.Getpid())
+
+	return func() error {
+		unlockFile(f)
+		return f.Close()
+	}, nil
+}
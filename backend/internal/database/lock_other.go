@@ -0,0 +1,12 @@
+//go:build !unix
+
+package database
+
+import "os"
+
+// 其他平台不支持 flock, 不检查数据库是否正在使用
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) {}
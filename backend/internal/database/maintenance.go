@@ -0,0 +1,143 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Backup 用 VACUUM INTO 把 db 写入 dest, 可以在服务运行时执行, 得到的是一致的快照
+func Backup(db *gorm.DB, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("%s already exists", dest)
+	}
+
+	// VACUUM INTO 使用源数据库的 VFS, 内存数据库 (测试使用的 memdb) 需要用 URI 指定写入磁盘
+	var file string
+	if err := db.Raw("SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&file).Error; err != nil {
+		return err
+	}
+	target := dest
+	if file == "" {
+		uri, err := diskURI(dest)
+		if err != nil {
+			return err
+		}
+		target = uri
+	}
+	return db.Exec("VACUUM INTO ?", target).Error
+}
+
+func diskURI(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	vfs := "unix"
+	if runtime.GOOS == "windows" {
+		vfs = "win32"
+	}
+	uri := url.URL{Scheme: "file", Path: filepath.ToSlash(abs), RawQuery: "vfs=" + vfs}
+	if !strings.HasPrefix(uri.Path, "/") {
+		uri.Path = "/" + uri.Path
+	}
+	return uri.String(), nil
+}
+
+// Vacuum 重建数据库文件以回收软删除和历史记录释放的空间
+func Vacuum(db *gorm.DB) error {
+	return db.Exec("VACUUM").Error
+}
+
+// CheckIntegrity 打开 path 并执行 PRAGMA integrity_check, 同时确认文件包含 todos 表,
+// 且表结构版本不比当前程序新. 版本为 0 的文件来自引入版本号之前, 迁移时会自动升级
+func CheckIntegrity(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	db, err := gorm.Open(sqlite.Open("file:"+path+"?mode=ro"), &gorm.Config{})
+	if err != nil {
+		return fmt.Errorf("%s is not a SQLite database: %w", path, err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	var result string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return fmt.Errorf("%s is not a SQLite database: %w", path, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check failed: %s", result)
+	}
+	if !db.Migrator().HasTable("todos") {
+		return errors.New("backup does not contain a todos table")
+	}
+
+	var version int
+	if err := db.Raw("PRAGMA user_version").Scan(&version).Error; err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("backup has schema version %d, this server supports up to %d", version, SchemaVersion)
+	}
+	return nil
+}
+
+// Restore 用备份文件替换 dest. 调用前必须关闭 dest 上的所有连接, 服务正在运行 (持有 Lock) 时返回 ErrLocked.
+// 文件先复制到同一目录再重命名, 中途失败不会留下不完整的数据库
+func Restore(src, dest string) error {
+	if err := CheckIntegrity(src); err != nil {
+		return err
+	}
+
+	// 恢复期间持有锁, 服务不能同时启动
+	unlock, err := Lock(dest)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// 旧的 WAL 和共享内存文件属于被替换的数据库, 必须一起删除
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(dest + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), dest)
+}
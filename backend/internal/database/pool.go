@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// connPool 实现 gorm.ConnPool, 写操作和事务使用唯一的写连接, 其余的 SELECT 使用只读连接池.
+// WAL 模式下读不阻塞写, 写连接只有一个, 进程内的写操作排队执行而不会互相得到 SQLITE_BUSY
+type connPool struct {
+	writer *sql.DB
+	reader *sql.DB
+}
+
+func (p *connPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.writer.PrepareContext(ctx, query)
+}
+
+func (p *connPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.writer.ExecContext(ctx, query, args...)
+}
+
+func (p *connPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.route(query).QueryContext(ctx, query, args...)
+}
+
+func (p *connPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.route(query).QueryRowContext(ctx, query, args...)
+}
+
+func (p *connPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return p.writer.BeginTx(ctx, opts)
+}
+
+// GetDBConn 供 gorm.DB.DB() 使用, 返回写连接
+func (p *connPool) GetDBConn() (*sql.DB, error) {
+	return p.writer, nil
+}
+
+func (p *connPool) Close() error {
+	return errors.Join(p.reader.Close(), p.writer.Close())
+}
+
+// route 只把 SELECT 交给只读连接, INSERT ... RETURNING 等也通过 QueryContext 执行, 需要使用写连接
+func (p *connPool) route(query string) *sql.DB {
+	query = strings.TrimSpace(query)
+	if len(query) >= 6 && strings.EqualFold(query[:6], "SELECT") {
+		return p.reader
+	}
+	return p.writer
+}
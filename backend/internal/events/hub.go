@@ -0,0 +1,136 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	TodoCreated = "todo.created"
+	TodoUpdated = "todo.updated"
+	TodoDeleted = "todo.deleted"
+)
+
+type Event struct {
+	ID   uint64      `json:"id"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+	Time time.Time   `json:"time"`
+}
+
+// Hub 是进程内的发布订阅中心, 保留最近 logSize 条事件用于断线续传
+type Hub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	log         []Event
+	logSize     int
+	subscribers map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	C <-chan Event
+
+	ch     chan Event
+	hub    *Hub
+	closed bool
+}
+
+func NewHub(logSize int) *Hub {
+	if logSize <= 0 {
+		logSize = 1
+	}
+	return &Hub{
+		logSize:     logSize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+func (h *Hub) Publish(eventType string, data interface{}) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event := Event{
+		ID:   h.lastID,
+		Type: eventType,
+		Data: data,
+		Time: time.Now(),
+	}
+
+	h.log = append(h.log, event)
+	if len(h.log) > h.logSize {
+		h.log = h.log[len(h.log)-h.logSize:]
+	}
+
+	for sub := range h.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			// 消费过慢的订阅者直接断开, 由客户端凭 Last-Event-ID 重连补齐
+			h.unsubscribe(sub)
+		}
+	}
+
+	return event
+}
+
+func (h *Hub) Subscribe(buffer int) *Subscription {
+	sub, _, _ := h.SubscribeSince(0, false, buffer)
+	return sub
+}
+
+// SubscribeSince 订阅并返回 lastID 之后错过的事件. 当 lastID 已不在日志范围内时 ok 为 false,
+// 客户端需要重新拉取全量数据
+func (h *Hub) SubscribeSince(lastID uint64, resume bool, buffer int) (sub *Subscription, missed []Event, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Event, buffer)
+	sub = &Subscription{C: ch, ch: ch, hub: h}
+	h.subscribers[sub] = struct{}{}
+
+	if !resume {
+		return sub, nil, true
+	}
+
+	missed, ok = h.since(lastID)
+	return sub, missed, ok
+}
+
+func (h *Hub) since(lastID uint64) ([]Event, bool) {
+	if lastID > h.lastID {
+		return nil, false
+	}
+	if lastID == h.lastID {
+		return nil, true
+	}
+	if len(h.log) == 0 || lastID+1 < h.log[0].ID {
+		return nil, false
+	}
+
+	start := int(lastID + 1 - h.log[0].ID)
+	missed := make([]Event, len(h.log)-start)
+	copy(missed, h.log[start:])
+	return missed, true
+}
+
+func (h *Hub) LastID() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastID
+}
+
+func (h *Hub) unsubscribe(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(h.subscribers, sub)
+	close(sub.ch)
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.unsubscribe(s)
+}
@@ -0,0 +1,207 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-backend/internal/model"
+)
+
+const (
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+	FormatTodoTxt  = "todotxt"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// CSVColumns 是 CSV 导出的列顺序, 调整时需要同时考虑导入
+var CSVColumns = []string{"id", "title", "content", "completed", "created_at", "updated_at", "completed_at", "due_at"}
+
+// Encoder 逐条写出 Todo, 调用顺序为 Begin、Write...、End
+type Encoder interface {
+	Begin() error
+	Write(todo *model.Todo) error
+	End() error
+}
+
+func NewEncoder(format string, w io.Writer) (Encoder, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonEncoder{w: w}, nil
+	case FormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w)}, nil
+	case FormatMarkdown:
+		return &markdownEncoder{w: w}, nil
+	case FormatTodoTxt:
+		return &todoTxtEncoder{w: w}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatJSON:
+		return "application/json; charset=utf-8"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+func FileExtension(format string) string {
+	switch format {
+	case FormatMarkdown:
+		return "md"
+	case FormatTodoTxt:
+		return "txt"
+	}
+	return format
+}
+
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonEncoder) Begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonEncoder) Write(todo *model.Todo) error {
+	data, err := json.Marshal(todo)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "\n"
+	}
+	e.count++
+
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) End() error {
+	_, err := io.WriteString(e.w, "\n]\n")
+	return err
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) Begin() error {
+	return e.w.Write(CSVColumns)
+}
+
+func (e *csvEncoder) Write(todo *model.Todo) error {
+	return e.w.Write([]string{
+		strconv.FormatUint(uint64(todo.ID), 10),
+		todo.Title,
+		todo.Content,
+		strconv.FormatBool(todo.Completed),
+		formatTime(&todo.CreatedAt),
+		formatTime(&todo.UpdatedAt),
+		formatTime(todo.CompletedAt),
+		formatTime(todo.DueAt),
+	})
+}
+
+func (e *csvEncoder) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type markdownEncoder struct {
+	w io.Writer
+}
+
+func (e *markdownEncoder) Begin() error {
+	_, err := io.WriteString(e.w, "# Todos\n\n")
+	return err
+}
+
+// Write 输出 GitHub 风格的任务列表, 内容作为缩进的续行
+func (e *markdownEncoder) Write(todo *model.Todo) error {
+	mark := " "
+	if todo.Completed {
+		mark = "x"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "- [%s] %s\n", mark, todo.Title)
+	if todo.Content != "" {
+		for _, line := range strings.Split(todo.Content, "\n") {
+			if line == "" {
+				b.WriteString("\n")
+				continue
+			}
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func (e *markdownEncoder) End() error {
+	return nil
+}
+
+type todoTxtEncoder struct {
+	w io.Writer
+}
+
+func (e *todoTxtEncoder) Begin() error {
+	return nil
+}
+
+// Write 按 todo.txt 格式输出: 完成的任务以 "x 完成日期 创建日期" 开头,
+// 未完成的任务以创建日期开头, 截止日期使用通用的 due: 扩展. 内容不是 todo.txt 的一部分, 不会导出
+func (e *todoTxtEncoder) Write(todo *model.Todo) error {
+	created := todo.CreatedAt.Format(todoTxtDate)
+
+	line := created + " " + todo.Title
+	if todo.DueAt != nil {
+		line += " due:" + todo.DueAt.Format(todoTxtDate)
+	}
+	if todo.Completed {
+		completed := todo.UpdatedAt
+		if todo.CompletedAt != nil {
+			completed = *todo.CompletedAt
+		}
+		line = "x " + completed.Format(todoTxtDate) + " " + line
+	}
+
+	_, err := io.WriteString(e.w, line+"\n")
+	return err
+}
+
+func (e *todoTxtEncoder) End() error {
+	return nil
+}
+
+const todoTxtDate = "2006-01-02"
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"todo-backend/internal/backup"
+	"todo-backend/internal/middleware"
+	"todo-backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BackupHandler struct {
+	manager *backup.Manager
+	token   string
+}
+
+func NewBackupHandler(manager *backup.Manager, token string) *BackupHandler {
+	return &BackupHandler{
+		manager: manager,
+		token:   token,
+	}
+}
+
+// Authorize 检查管理接口的 Bearer token; 未配置 token 时管理接口不可用
+func (h *BackupHandler) Authorize(c *gin.Context) {
+	if h.token == "" {
+		c.AbortWithStatusJSON(http.StatusNotFound, model.Response{
+			Code:    404,
+			Data:    nil,
+			Message: "not found",
+		})
+		return
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		c.Header("WWW-Authenticate", `Bearer realm="todo-admin"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, model.Response{
+			Code:    401,
+			Data:    nil,
+			Message: "invalid admin token",
+		})
+		return
+	}
+	c.Set(middleware.TokenNameKey, "admin")
+	c.Next()
+}
+
+func (h *BackupHandler) GetBackups(c *gin.Context) {
+	backups, err := h.manager.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.Response{
+			Code:    500,
+			Data:    nil,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Code:    0,
+		Data:    backups,
+		Message: "success",
+	})
+}
+
+// CreateBackup 立即执行一次在线备份, 备份期间服务可以继续读写
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	info, err := h.manager.Create()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.Response{
+			Code:    500,
+			Data:    nil,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, model.Response{
+		Code:    0,
+		Data:    info,
+		Message: "success",
+	})
+}
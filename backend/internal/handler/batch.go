@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"todo-backend/internal/events"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errBatchItemFailed = errors.New("batch operation failed")
+
+func (h *TodoHandler) BatchTodos(c *gin.Context) {
+	var req model.BatchRequest
+	if err := h.validator.BindJSON(c, &req); err != nil {
+		respondBindError(c, h.validator, err)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = "atomic"
+	}
+
+	locale := validation.Locale(c.GetHeader("Accept-Language"))
+	resp := model.BatchResponse{
+		Mode:    req.Mode,
+		Results: make([]model.BatchResult, 0, len(req.Operations)),
+	}
+
+	ctx := c.Request.Context()
+	var err error
+	if req.Mode == "partial" {
+		// 每个操作使用独立的 SAVEPOINT, 失败只回滚该操作
+		err = h.repo.Transaction(ctx, func(repo *repository.TodoRepository) error {
+			for i, op := range req.Operations {
+				var result model.BatchResult
+				err := repo.Transaction(ctx, func(item *repository.TodoRepository) error {
+					result = h.execBatchOperation(ctx, item, i, op, locale)
+					if result.Error != "" {
+						return errBatchItemFailed
+					}
+					return nil
+				})
+				if err != nil && !errors.Is(err, errBatchItemFailed) {
+					// SAVEPOINT 本身失败时操作没有执行或已回滚, 不能报告为成功
+					result = failedResult(model.BatchResult{Index: i, Op: op.Op, ID: op.ID}, writeStatus(err), err.Error())
+				}
+				resp.Results = append(resp.Results, result)
+			}
+			return nil
+		})
+	} else {
+		err = h.repo.Transaction(ctx, func(repo *repository.TodoRepository) error {
+			for i, op := range req.Operations {
+				result := h.execBatchOperation(ctx, repo, i, op, locale)
+				resp.Results = append(resp.Results, result)
+				if result.Error != "" {
+					return errBatchItemFailed
+				}
+			}
+			return nil
+		})
+	}
+
+	if err != nil && !errors.Is(err, errBatchItemFailed) {
+		respondServerError(c, err)
+		return
+	}
+
+	if errors.Is(err, errBatchItemFailed) {
+		failed := resp.Results[len(resp.Results)-1]
+		for i := range resp.Results[:len(resp.Results)-1] {
+			resp.Results[i] = rolledBack(resp.Results[i], "rolled back")
+		}
+		for i := len(resp.Results); i < len(req.Operations); i++ {
+			op := req.Operations[i]
+			resp.Results = append(resp.Results, rolledBack(model.BatchResult{Index: i, Op: op.Op, ID: op.ID}, "not executed"))
+		}
+		resp.Failed = len(resp.Results)
+
+		c.JSON(failed.Status, model.Response{
+			Code:    failed.Status,
+			Data:    resp,
+			Message: failed.Error,
+		})
+		return
+	}
+
+	for _, result := range resp.Results {
+		if result.Error != "" {
+			resp.Failed++
+			continue
+		}
+		resp.Succeeded++
+		h.publishBatchResult(result)
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Code:    0,
+		Data:    resp,
+		Message: "success",
+	})
+}
+
+func (h *TodoHandler) execBatchOperation(ctx context.Context, repo *repository.TodoRepository, index int, op model.BatchOperation, locale string) model.BatchResult {
+	result := model.BatchResult{Index: index, Op: op.Op, ID: op.ID}
+
+	switch op.Op {
+	case "create":
+		var req model.CreateTodoRequest
+		if err := h.decodeBatchData(op.Data, &req); err != nil {
+			return failedResult(result, validation.StatusCode(err), h.validator.Message(err, locale))
+		}
+
+		todo := &model.Todo{
+			Title:   req.Title,
+			Content: req.Content,
+		}
+		if err := repo.Create(ctx, todo); err != nil {
+			return failedResult(result, writeStatus(err), err.Error())
+		}
+
+		result.ID = todo.ID
+		result.Status = http.StatusCreated
+		result.Todo = todo
+
+	case "update":
+		var req model.UpdateTodoRequest
+		if err := h.decodeBatchData(op.Data, &req); err != nil {
+			return failedResult(result, validation.StatusCode(err), h.validator.Message(err, locale))
+		}
+
+		if _, err := repo.GetByID(ctx, op.ID); err != nil {
+			return lookupFailedResult(repo, result, err)
+		}
+
+		if err := repo.Update(ctx, op.ID, &req); err != nil {
+			return failedResult(result, writeStatus(err), err.Error())
+		}
+
+		todo, err := repo.GetByID(ctx, op.ID)
+		if err != nil {
+			return failedResult(result, serverErrorStatus(err), err.Error())
+		}
+		result.Status = http.StatusOK
+		result.Todo = todo
+
+	case "delete":
+		if _, err := repo.GetByID(ctx, op.ID); err != nil {
+			return lookupFailedResult(repo, result, err)
+		}
+
+		if err := repo.Delete(ctx, op.ID); err != nil {
+			return failedResult(result, serverErrorStatus(err), err.Error())
+		}
+		result.Status = http.StatusOK
+	}
+
+	return result
+}
+
+func (h *TodoHandler) publishBatchResult(result model.BatchResult) {
+	switch result.Op {
+	case "create":
+		h.hub.Publish(events.TodoCreated, result.Todo)
+	case "update":
+		h.hub.Publish(events.TodoUpdated, result.Todo)
+	case "delete":
+		h.hub.Publish(events.TodoDeleted, deletedTodo(result.ID))
+	}
+}
+
+func (h *TodoHandler) decodeBatchData(data json.RawMessage, obj interface{}) error {
+	if err := validation.Unmarshal(data, obj); err != nil {
+		return err
+	}
+	return h.validator.Validate(obj)
+}
+
+func failedResult(result model.BatchResult, status int, message string) model.BatchResult {
+	result.Status = status
+	result.Todo = nil
+	result.Error = message
+	return result
+}
+
+func lookupFailedResult(repo *repository.TodoRepository, result model.BatchResult, err error) model.BatchResult {
+	if repo.IsNotFound(err) {
+		return failedResult(result, http.StatusNotFound, "todo not found")
+	}
+	return failedResult(result, serverErrorStatus(err), err.Error())
+}
+
+func rolledBack(result model.BatchResult, message string) model.BatchResult {
+	if result.Op == "create" {
+		result.ID = 0
+	}
+	return failedResult(result, http.StatusFailedDependency, message)
+}
+
+func (h *TodoHandler) DeleteTodos(c *gin.Context) {
+	completed, err := strconv.ParseBool(c.Query("completed"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.Response{
+			Code:    400,
+			Data:    nil,
+			Message: "completed query parameter must be true or false",
+		})
+		return
+	}
+
+	ids, err := h.repo.DeleteByCompleted(c.Request.Context(), completed)
+	if err != nil {
+		respondServerError(c, err)
+		return
+	}
+
+	for _, id := range ids {
+		h.hub.Publish(events.TodoDeleted, deletedTodo(id))
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Code:    0,
+		Data:    gin.H{"deleted": len(ids)},
+		Message: "success",
+	})
+}
+
+func (h *TodoHandler) CompleteAllTodos(c *gin.Context) {
+	todos, err := h.repo.CompleteAll(c.Request.Context())
+	if err != nil {
+		respondServerError(c, err)
+		return
+	}
+
+	for i := range todos {
+		h.hub.Publish(events.TodoUpdated, &todos[i])
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Code:    0,
+		Data:    gin.H{"updated": len(todos)},
+		Message: "success",
+	})
+}
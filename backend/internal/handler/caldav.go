@@ -0,0 +1,720 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"todo-backend/internal/events"
+	"todo-backend/internal/ical"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	davNS       = "DAV:"
+	calDAVNS    = "urn:ietf:params:xml:ns:caldav"
+	calServerNS = "http://calendarserver.org/ns/"
+
+	caldavRoot       = "/caldav/"
+	caldavCollection = "/caldav/todos/"
+	caldavSyncPrefix = "http://todo-backend/ns/sync/"
+	caldavMediaType  = "text/calendar; charset=utf-8; component=vtodo"
+)
+
+// CalDAVMethods 是 CalDAV 路由需要注册的 HTTP 方法
+var CalDAVMethods = []string{"OPTIONS", "PROPFIND", "REPORT", "GET", "HEAD", "PUT", "DELETE"}
+
+var errCalDAVNotFound = errors.New("resource not found")
+
+type CalDAVHandler struct {
+	repo      *repository.TodoRepository
+	hub       *events.Hub
+	validator *validation.Validator
+	domain    string
+}
+
+func NewCalDAVHandler(db *gorm.DB, hub *events.Hub, quota repository.Quota, v *validation.Validator, domain string) *CalDAVHandler {
+	return &CalDAVHandler{
+		repo:      repository.NewTodoRepository(db).WithQuota(quota),
+		hub:       hub,
+		validator: v,
+		domain:    domain,
+	}
+}
+
+// WellKnown 把客户端的服务发现请求重定向到 CalDAV 根目录
+func (h *CalDAVHandler) WellKnown(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, caldavRoot)
+}
+
+// ServeDAV 处理 /caldav 下的所有请求. 资源结构:
+//
+//	/caldav/              当前用户的 principal 和日历主目录
+//	/caldav/todos/        包含全部 Todo 的日历集合
+//	/caldav/todos/<name>.ics 单个 VTODO, name 是客户端创建时使用的资源名, 其余 Todo 使用 UID
+func (h *CalDAVHandler) ServeDAV(c *gin.Context) {
+	path := c.Param("path")
+
+	if c.Request.Method == "OPTIONS" {
+		c.Header("DAV", "1, 3, calendar-access")
+		c.Header("Allow", strings.Join(CalDAVMethods, ", "))
+		c.Status(http.StatusOK)
+		return
+	}
+
+	switch {
+	case path == "/" || path == "":
+		h.serveRoot(c)
+	case path == "/todos" || path == "/todos/":
+		h.serveCollection(c)
+	case strings.HasPrefix(path, "/todos/") && strings.HasSuffix(path, ".ics") && !strings.Contains(path[len("/todos/"):], "/"):
+		h.serveResource(c, strings.TrimSuffix(path[len("/todos/"):], ".ics"))
+	default:
+		c.String(http.StatusNotFound, "not found")
+	}
+}
+
+func (h *CalDAVHandler) serveRoot(c *gin.Context) {
+	if c.Request.Method != "PROPFIND" {
+		c.String(http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	names, ok := h.readPropfind(c)
+	if !ok {
+		return
+	}
+
+	ms := davMultistatus{}
+	ms.add(caldavRoot, h.rootProps(), names)
+	if davDepth(c) > 0 {
+		props, err := h.collectionProps(c.Request.Context())
+		if err != nil {
+			c.String(serverErrorStatus(err), err.Error())
+			return
+		}
+		ms.add(caldavCollection, props, names)
+	}
+	writeMultistatus(c, ms)
+}
+
+func (h *CalDAVHandler) serveCollection(c *gin.Context) {
+	switch c.Request.Method {
+	case "PROPFIND":
+		names, ok := h.readPropfind(c)
+		if !ok {
+			return
+		}
+
+		props, err := h.collectionProps(c.Request.Context())
+		if err != nil {
+			c.String(serverErrorStatus(err), err.Error())
+			return
+		}
+
+		ms := davMultistatus{}
+		ms.add(caldavCollection, props, names)
+		if davDepth(c) > 0 {
+			err := h.repo.Each(c.Request.Context(), repository.TodoFilter{}, func(todo *model.Todo) error {
+				ms.add(h.href(todo), h.resourceProps(todo, names), names)
+				return nil
+			})
+			if err != nil {
+				c.String(serverErrorStatus(err), err.Error())
+				return
+			}
+		}
+		writeMultistatus(c, ms)
+
+	case "REPORT":
+		h.report(c)
+
+	case "GET", "HEAD":
+		c.String(http.StatusMethodNotAllowed, "use the calendar feed to download all todos")
+
+	default:
+		c.String(http.StatusForbidden, "the todo collection cannot be modified")
+	}
+}
+
+func (h *CalDAVHandler) serveResource(c *gin.Context, name string) {
+	switch c.Request.Method {
+	case "GET", "HEAD":
+		todo, err := h.lookup(c.Request.Context(), name)
+		if err != nil {
+			respondDAVError(c, err)
+			return
+		}
+
+		etag := todoETag(todo)
+		c.Header("ETag", etag)
+		if matchETag(c.GetHeader("If-None-Match"), etag, true) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		var buf bytes.Buffer
+		ical.WriteTodoObject(&buf, todo, h.domain)
+		c.Data(http.StatusOK, caldavMediaType, buf.Bytes())
+
+	case "PROPFIND":
+		names, ok := h.readPropfind(c)
+		if !ok {
+			return
+		}
+		todo, err := h.lookup(c.Request.Context(), name)
+		if err != nil {
+			respondDAVError(c, err)
+			return
+		}
+
+		ms := davMultistatus{}
+		ms.add(h.href(todo), h.resourceProps(todo, names), names)
+		writeMultistatus(c, ms)
+
+	case "PUT":
+		h.put(c, name)
+
+	case "DELETE":
+		todo, err := h.lookup(c.Request.Context(), name)
+		if err != nil {
+			respondDAVError(c, err)
+			return
+		}
+		if ifMatch := c.GetHeader("If-Match"); ifMatch != "" && !matchETag(ifMatch, todoETag(todo), false) {
+			c.String(http.StatusPreconditionFailed, "todo has been modified")
+			return
+		}
+
+		if err := h.repo.DeleteIfVersion(c.Request.Context(), todo.ID, todo.Version); err != nil {
+			respondDAVError(c, err)
+			return
+		}
+		h.hub.Publish(events.TodoDeleted, deletedTodo(todo.ID))
+		c.Status(http.StatusNoContent)
+
+	default:
+		c.String(http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// put 创建或整体替换一个 VTODO, 支持 If-Match 和 If-None-Match: *.
+// 新资源保存在请求 URL 的资源名下; 已有资源的 UID 不能修改
+func (h *CalDAVHandler) put(c *gin.Context, name string) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.validator.Rules().MaxBodyBytes)
+
+	vtodo, err := ical.DecodeTodo(c.Request.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.String(http.StatusRequestEntityTooLarge, "calendar object is too large")
+			return
+		}
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := model.CreateTodoRequest{Title: vtodo.Summary, Content: vtodo.Description}
+	if err := h.validator.Validate(&req); err != nil {
+		c.String(http.StatusBadRequest, h.validator.Message(err, validation.Locale(c.GetHeader("Accept-Language"))))
+		return
+	}
+
+	existing, err := h.lookup(c.Request.Context(), name)
+	if err != nil && !errors.Is(err, errCalDAVNotFound) {
+		respondDAVError(c, err)
+		return
+	}
+
+	ifMatch := c.GetHeader("If-Match")
+	if existing != nil && c.GetHeader("If-None-Match") == "*" {
+		c.String(http.StatusPreconditionFailed, "resource already exists")
+		return
+	}
+	if ifMatch != "" && (existing == nil || !matchETag(ifMatch, todoETag(existing), false)) {
+		c.String(http.StatusPreconditionFailed, "todo has been modified")
+		return
+	}
+
+	if existing != nil && ical.TodoUID(existing, h.domain) != vtodo.UID {
+		c.String(http.StatusConflict, "UID does not match the existing resource")
+		return
+	}
+
+	if existing == nil {
+		if other, err := h.lookupUID(c.Request.Context(), vtodo.UID); err == nil && other != nil {
+			c.String(http.StatusConflict, "UID is already used by another resource")
+			return
+		}
+
+		todo := &model.Todo{
+			Title:        req.Title,
+			Content:      req.Content,
+			Completed:    vtodo.Completed,
+			CompletedAt:  vtodo.CompletedAt,
+			DueAt:        vtodo.Due,
+			UID:          &vtodo.UID,
+			ResourceName: &name,
+		}
+		err := h.repo.Transaction(c.Request.Context(), func(repo *repository.TodoRepository) error {
+			if err := repo.ReleaseResource(c.Request.Context(), name, vtodo.UID); err != nil {
+				return err
+			}
+			return repo.Create(c.Request.Context(), todo)
+		})
+		if err != nil {
+			respondDAVError(c, err)
+			return
+		}
+
+		h.hub.Publish(events.TodoCreated, todo)
+		c.Header("ETag", todoETag(todo))
+		c.Header("Location", h.href(todo))
+		c.Status(http.StatusCreated)
+		return
+	}
+
+	values := map[string]interface{}{
+		"title":     req.Title,
+		"content":   req.Content,
+		"completed": vtodo.Completed,
+		"due_at":    vtodo.Due,
+	}
+	if vtodo.Completed && vtodo.CompletedAt != nil {
+		values["completed_at"] = vtodo.CompletedAt
+	}
+	var todo *model.Todo
+	err = h.repo.Transaction(c.Request.Context(), func(repo *repository.TodoRepository) error {
+		if err := repo.UpdateFields(c.Request.Context(), existing.ID, values, existing.Version); err != nil {
+			return err
+		}
+		todo, err = repo.GetByID(c.Request.Context(), existing.ID)
+		return err
+	})
+	if err != nil {
+		respondDAVError(c, err)
+		return
+	}
+
+	h.hub.Publish(events.TodoUpdated, todo)
+	c.Header("ETag", todoETag(todo))
+	c.Status(http.StatusNoContent)
+}
+
+// report 支持 calendar-query、calendar-multiget 和 sync-collection
+func (h *CalDAVHandler) report(c *gin.Context) {
+	var body davReport
+	if err := xml.NewDecoder(c.Request.Body).Decode(&body); err != nil {
+		c.String(http.StatusBadRequest, "invalid REPORT body")
+		return
+	}
+	names := body.Prop.names()
+
+	ms := davMultistatus{}
+	switch body.XMLName {
+	case xml.Name{Space: calDAVNS, Local: "calendar-query"}:
+		filter, ok := body.Filter.todoFilter()
+		if ok {
+			err := h.repo.Each(c.Request.Context(), filter, func(todo *model.Todo) error {
+				ms.add(h.href(todo), h.resourceProps(todo, names), names)
+				return nil
+			})
+			if err != nil {
+				c.String(serverErrorStatus(err), err.Error())
+				return
+			}
+		}
+
+	case xml.Name{Space: calDAVNS, Local: "calendar-multiget"}:
+		for _, href := range body.Hrefs {
+			todo, err := h.lookupHref(c.Request.Context(), href)
+			if err != nil {
+				ms.Responses = append(ms.Responses, davResponse{Href: href, Status: davStatus(http.StatusNotFound)})
+				continue
+			}
+			ms.add(h.href(todo), h.resourceProps(todo, names), names)
+		}
+
+	case xml.Name{Space: davNS, Local: "sync-collection"}:
+		token, ok := h.syncCollection(c, &ms, body.SyncToken, names)
+		if !ok {
+			return
+		}
+		ms.SyncToken = token
+
+	default:
+		c.String(http.StatusForbidden, "unsupported report")
+		return
+	}
+
+	writeMultistatus(c, ms)
+}
+
+// syncCollection 基于变更序号实现 RFC 6578, 删除的 Todo 以 404 返回
+func (h *CalDAVHandler) syncCollection(c *gin.Context, ms *davMultistatus, token string, names []xml.Name) (string, bool) {
+	var (
+		since uint64
+		full  = token == ""
+	)
+	if !full {
+		seq, err := strconv.ParseUint(strings.TrimPrefix(token, caldavSyncPrefix), 10, 64)
+		if err != nil || !strings.HasPrefix(token, caldavSyncPrefix) {
+			respondInvalidSyncToken(c)
+			return "", false
+		}
+		since = seq
+	}
+
+	var last uint64
+	err := h.repo.Transaction(c.Request.Context(), func(repo *repository.TodoRepository) error {
+		var err error
+		if last, err = repo.LastChangeSeq(c.Request.Context()); err != nil {
+			return err
+		}
+		if since > last {
+			return errInvalidSyncToken
+		}
+
+		if full {
+			return repo.Each(c.Request.Context(), repository.TodoFilter{}, func(todo *model.Todo) error {
+				ms.add(h.href(todo), h.resourceProps(todo, names), names)
+				return nil
+			})
+		}
+
+		todos, err := repo.ChangesSince(c.Request.Context(), since)
+		if err != nil {
+			return err
+		}
+		for i := range todos {
+			todo := &todos[i]
+			switch {
+			case todo.DeletedAt.Valid && todo.CreatedSeq > since:
+			case todo.DeletedAt.Valid:
+				ms.Responses = append(ms.Responses, davResponse{Href: h.href(todo), Status: davStatus(http.StatusNotFound)})
+			default:
+				ms.add(h.href(todo), h.resourceProps(todo, names), names)
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errInvalidSyncToken) {
+		respondInvalidSyncToken(c)
+		return "", false
+	}
+	if err != nil {
+		c.String(serverErrorStatus(err), err.Error())
+		return "", false
+	}
+	return caldavSyncPrefix + strconv.FormatUint(last, 10), true
+}
+
+func (h *CalDAVHandler) rootProps() map[xml.Name]string {
+	principal := davHref(caldavRoot)
+	return map[xml.Name]string{
+		{Space: davNS, Local: "resourcetype"}:               `<collection xmlns="DAV:"/>`,
+		{Space: davNS, Local: "displayname"}:                "Todo",
+		{Space: davNS, Local: "current-user-principal"}:     principal,
+		{Space: davNS, Local: "principal-URL"}:              principal,
+		{Space: calDAVNS, Local: "calendar-home-set"}:       principal,
+		{Space: davNS, Local: "current-user-privilege-set"}: davPrivileges,
+	}
+}
+
+func (h *CalDAVHandler) collectionProps(ctx context.Context) (map[xml.Name]string, error) {
+	seq, err := h.repo.LastChangeSeq(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctag := strconv.FormatUint(seq, 10)
+
+	return map[xml.Name]string{
+		{Space: davNS, Local: "resourcetype"}:                        `<collection xmlns="DAV:"/><calendar xmlns="urn:ietf:params:xml:ns:caldav"/>`,
+		{Space: davNS, Local: "displayname"}:                         "Todos",
+		{Space: davNS, Local: "current-user-principal"}:              davHref(caldavRoot),
+		{Space: davNS, Local: "current-user-privilege-set"}:          davPrivileges,
+		{Space: davNS, Local: "getetag"}:                             html.EscapeString(`"ctag-` + ctag + `"`),
+		{Space: davNS, Local: "sync-token"}:                          caldavSyncPrefix + ctag,
+		{Space: calServerNS, Local: "getctag"}:                       ctag,
+		{Space: calDAVNS, Local: "supported-calendar-component-set"}: `<comp xmlns="urn:ietf:params:xml:ns:caldav" name="VTODO"/>`,
+		{Space: davNS, Local: "supported-report-set"}: `<supported-report xmlns="DAV:"><report><calendar-query xmlns="urn:ietf:params:xml:ns:caldav"/></report></supported-report>` +
+			`<supported-report xmlns="DAV:"><report><calendar-multiget xmlns="urn:ietf:params:xml:ns:caldav"/></report></supported-report>` +
+			`<supported-report xmlns="DAV:"><report><sync-collection/></report></supported-report>`,
+	}, nil
+}
+
+// resourceProps 返回单个 VTODO 的属性, calendar-data 只在明确请求时生成
+func (h *CalDAVHandler) resourceProps(todo *model.Todo, names []xml.Name) map[xml.Name]string {
+	props := map[xml.Name]string{
+		{Space: davNS, Local: "getetag"}:         html.EscapeString(todoETag(todo)),
+		{Space: davNS, Local: "getcontenttype"}:  caldavMediaType,
+		{Space: davNS, Local: "getlastmodified"}: todo.UpdatedAt.UTC().Format(http.TimeFormat),
+		{Space: davNS, Local: "resourcetype"}:    "",
+		{Space: davNS, Local: "displayname"}:     html.EscapeString(todo.Title),
+	}
+
+	dataName := xml.Name{Space: calDAVNS, Local: "calendar-data"}
+	for _, name := range names {
+		if name == dataName {
+			var buf bytes.Buffer
+			ical.WriteTodoObject(&buf, todo, h.domain)
+			props[dataName] = html.EscapeString(buf.String())
+		}
+	}
+	return props
+}
+
+func (h *CalDAVHandler) href(todo *model.Todo) string {
+	name := ical.TodoUID(todo, h.domain)
+	if todo.ResourceName != nil {
+		name = *todo.ResourceName
+	}
+	return caldavCollection + url.PathEscape(name) + ".ics"
+}
+
+// lookup 按资源名查找; 没有保存资源名的 Todo 以 UID 作为资源名
+func (h *CalDAVHandler) lookup(ctx context.Context, name string) (*model.Todo, error) {
+	todo, err := h.repo.GetByResourceName(ctx, name)
+	if err == nil {
+		return todo, nil
+	}
+	if !h.repo.IsNotFound(err) {
+		return nil, err
+	}
+
+	todo, err = h.lookupUID(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if todo.ResourceName != nil {
+		return nil, errCalDAVNotFound
+	}
+	return todo, nil
+}
+
+// lookupUID 先按保存的 UID 查找, 再按由 ID 生成的 UID 查找
+func (h *CalDAVHandler) lookupUID(ctx context.Context, uid string) (*model.Todo, error) {
+	todo, err := h.repo.GetByUID(ctx, uid)
+	if err == nil {
+		return todo, nil
+	}
+	if !h.repo.IsNotFound(err) {
+		return nil, err
+	}
+
+	var id uint
+	if _, err := fmt.Sscanf(uid, "todo-%d@", &id); err != nil || ical.UID(id, h.domain) != uid {
+		return nil, errCalDAVNotFound
+	}
+	todo, err = h.repo.GetByID(ctx, id)
+	if err != nil {
+		if h.repo.IsNotFound(err) {
+			return nil, errCalDAVNotFound
+		}
+		return nil, err
+	}
+	if todo.UID != nil {
+		return nil, errCalDAVNotFound
+	}
+	return todo, nil
+}
+
+func (h *CalDAVHandler) lookupHref(ctx context.Context, href string) (*model.Todo, error) {
+	u, err := url.Parse(href)
+	if err != nil || !strings.HasPrefix(u.Path, caldavCollection) || !strings.HasSuffix(u.Path, ".ics") {
+		return nil, errCalDAVNotFound
+	}
+	return h.lookup(ctx, strings.TrimSuffix(strings.TrimPrefix(u.Path, caldavCollection), ".ics"))
+}
+
+// readPropfind 解析 PROPFIND 请求体, 返回请求的属性名; 空请求体或 allprop 时返回 nil
+func (h *CalDAVHandler) readPropfind(c *gin.Context) ([]xml.Name, bool) {
+	var body davPropfind
+	err := xml.NewDecoder(c.Request.Body).Decode(&body)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.String(http.StatusBadRequest, "invalid PROPFIND body")
+		return nil, false
+	}
+	if body.AllProp != nil {
+		return nil, true
+	}
+	return body.Prop.names(), true
+}
+
+func davDepth(c *gin.Context) int {
+	if c.GetHeader("Depth") == "0" {
+		return 0
+	}
+	return 1
+}
+
+func davStatus(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
+
+func davHref(href string) string {
+	return `<href xmlns="DAV:">` + html.EscapeString(href) + `</href>`
+}
+
+const davPrivileges = `<privilege xmlns="DAV:"><read/></privilege><privilege xmlns="DAV:"><write/></privilege>`
+
+func respondDAVError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errCalDAVNotFound):
+		c.String(http.StatusNotFound, "not found")
+	case errors.Is(err, repository.ErrVersionConflict):
+		c.String(http.StatusPreconditionFailed, "todo has been modified")
+	case errors.Is(err, repository.ErrQuotaExceeded):
+		// RFC 4331 规定超出配额时返回 507 和 quota-not-exceeded 前置条件
+		c.Data(http.StatusInsufficientStorage, "application/xml; charset=utf-8",
+			[]byte(xml.Header+`<error xmlns="DAV:"><quota-not-exceeded/></error>`))
+	default:
+		c.String(serverErrorStatus(err), err.Error())
+	}
+}
+
+func respondInvalidSyncToken(c *gin.Context) {
+	c.Data(http.StatusForbidden, "application/xml; charset=utf-8",
+		[]byte(xml.Header+`<error xmlns="DAV:"><valid-sync-token/></error>`))
+}
+
+func writeMultistatus(c *gin.Context, ms davMultistatus) {
+	data, err := xml.Marshal(ms)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", append([]byte(xml.Header), data...))
+}
+
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []davResponse `xml:"response"`
+	SyncToken string        `xml:"sync-token,omitempty"`
+}
+
+// add 按请求的属性名把 props 分成 200 和 404 两组; names 为 nil 时返回除 calendar-data 外的全部属性
+func (ms *davMultistatus) add(href string, props map[xml.Name]string, names []xml.Name) {
+	var found, missing []davProperty
+	if names == nil {
+		for name, value := range props {
+			found = append(found, davProperty{XMLName: name, Inner: value})
+		}
+	}
+	for _, name := range names {
+		if value, ok := props[name]; ok {
+			found = append(found, davProperty{XMLName: name, Inner: value})
+		} else {
+			missing = append(missing, davProperty{XMLName: name})
+		}
+	}
+
+	resp := davResponse{Href: href}
+	if len(found) > 0 || len(missing) == 0 {
+		resp.Propstats = append(resp.Propstats, davPropstat{Prop: davProp{Props: found}, Status: davStatus(http.StatusOK)})
+	}
+	if len(missing) > 0 {
+		resp.Propstats = append(resp.Propstats, davPropstat{Prop: davProp{Props: missing}, Status: davStatus(http.StatusNotFound)})
+	}
+	ms.Responses = append(ms.Responses, resp)
+}
+
+type davResponse struct {
+	Href      string        `xml:"href"`
+	Propstats []davPropstat `xml:"propstat,omitempty"`
+	Status    string        `xml:"status,omitempty"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"prop"`
+	Status string  `xml:"status"`
+}
+
+type davProp struct {
+	Props []davProperty
+}
+
+type davProperty struct {
+	XMLName xml.Name
+	Inner   string `xml:",innerxml"`
+}
+
+type davPropNames struct {
+	Names []davProperty `xml:",any"`
+}
+
+func (p *davPropNames) names() []xml.Name {
+	if p == nil {
+		return nil
+	}
+	names := make([]xml.Name, 0, len(p.Names))
+	for _, prop := range p.Names {
+		names = append(names, prop.XMLName)
+	}
+	return names
+}
+
+type davPropfind struct {
+	XMLName xml.Name      `xml:"DAV: propfind"`
+	AllProp *struct{}     `xml:"DAV: allprop"`
+	Prop    *davPropNames `xml:"DAV: prop"`
+}
+
+type davReport struct {
+	XMLName   xml.Name
+	Prop      *davPropNames `xml:"DAV: prop"`
+	Hrefs     []string      `xml:"DAV: href"`
+	SyncToken string        `xml:"DAV: sync-token"`
+	Filter    *calFilter    `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+type calFilter struct {
+	CompFilter *calCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type calCompFilter struct {
+	Name        string          `xml:"name,attr"`
+	CompFilters []calCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	PropFilters []struct {
+		Name         string    `xml:"name,attr"`
+		IsNotDefined *struct{} `xml:"urn:ietf:params:xml:ns:caldav is-not-defined"`
+	} `xml:"urn:ietf:params:xml:ns:caldav prop-filter"`
+}
+
+// todoFilter 把 calendar-query 的过滤条件转换为 TodoFilter. 只支持按组件类型过滤,
+// 以及客户端隐藏已完成任务时使用的 COMPLETED is-not-defined; 其余条件被忽略
+func (f *calFilter) todoFilter() (repository.TodoFilter, bool) {
+	var filter repository.TodoFilter
+	if f == nil || f.CompFilter == nil {
+		return filter, true
+	}
+	if f.CompFilter.Name != "VCALENDAR" {
+		return filter, false
+	}
+
+	for _, comp := range f.CompFilter.CompFilters {
+		if comp.Name != "VTODO" {
+			return filter, false
+		}
+		for _, prop := range comp.PropFilters {
+			if prop.Name == "COMPLETED" && prop.IsNotDefined != nil {
+				completed := false
+				filter.Completed = &completed
+			}
+		}
+	}
+	return filter, true
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+
+	"todo-backend/internal/ical"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CalendarHandler struct {
+	repo   *repository.TodoRepository
+	token  string
+	domain string
+}
+
+func NewCalendarHandler(db *gorm.DB, token, domain string) *CalendarHandler {
+	return &CalendarHandler{
+		repo:   repository.NewTodoRepository(db),
+		token:  token,
+		domain: domain,
+	}
+}
+
+// GetCalendar 以 iCalendar 格式输出 Todo 订阅源. 日历应用无法发送认证头,
+// 因此使用 URL 中的 token 认证; 未配置 token 时订阅源不可用
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	if h.token == "" || subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(h.token)) != 1 {
+		c.JSON(http.StatusNotFound, model.Response{
+			Code:    404,
+			Data:    nil,
+			Message: "calendar not found",
+		})
+		return
+	}
+
+	var filter repository.TodoFilter
+	if value := c.Query("completed"); value != "" {
+		completed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.Response{
+				Code:    400,
+				Data:    nil,
+				Message: "completed query parameter must be true or false",
+			})
+			return
+		}
+		filter.Completed = &completed
+	}
+	filter.Query = c.Query("q")
+
+	events := true
+	if value := c.Query("events"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.Response{
+				Code:    400,
+				Data:    nil,
+				Message: "events query parameter must be true or false",
+			})
+			return
+		}
+		events = parsed
+	}
+
+	var todos []model.Todo
+	err := h.repo.Each(c.Request.Context(), filter, func(todo *model.Todo) error {
+		todos = append(todos, *todo)
+		return nil
+	})
+	if err != nil {
+		respondServerError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	ical.WriteCalendar(&buf, todos, ical.Options{
+		Domain: h.domain,
+		Name:   "Todos",
+		Events: events,
+	})
+
+	sum := fnv.New64a()
+	sum.Write(buf.Bytes())
+	etag := fmt.Sprintf(`"%x"`, sum.Sum64())
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, max-age=300")
+	if matchETag(c.GetHeader("If-None-Match"), etag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="todos.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
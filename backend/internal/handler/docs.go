@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"todo-backend/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DocsHandler struct {
+	spec []byte
+}
+
+// NewDocsHandler 在启动时序列化一次文档, 之后每次请求直接返回
+func NewDocsHandler(doc *openapi.Document) *DocsHandler {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		panic(err)
+	}
+	return &DocsHandler{spec: spec}
+}
+
+func (h *DocsHandler) GetSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
+
+func (h *DocsHandler) GetDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.DocsHTML)
+}
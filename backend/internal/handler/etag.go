@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"todo-backend/internal/model"
+)
+
+func todoETag(todo *model.Todo) string {
+	return fmt.Sprintf(`"%d-%d"`, todo.ID, todo.Version)
+}
+
+// listETag 由每条记录的 ID 和版本号计算, 任意增删改都会改变结果
+func listETag(todos []model.Todo) string {
+	h := fnv.New64a()
+	for _, todo := range todos {
+		fmt.Fprintf(h, "%d-%d,", todo.ID, todo.Version)
+	}
+	return fmt.Sprintf(`W/"%x"`, h.Sum64())
+}
+
+// matchETag 判断 If-Match / If-None-Match 头是否匹配, weak 为 true 时忽略 W/ 前缀
+func matchETag(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+			etag = strings.TrimPrefix(etag, "W/")
+		} else if strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"todo-backend/internal/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	sseHeartbeatInterval = 15 * time.Second
+	sseRetryMillis       = 3000
+	sseBufferSize        = 64
+)
+
+type EventHandler struct {
+	hub *events.Hub
+}
+
+func NewEventHandler(hub *events.Hub) *EventHandler {
+	return &EventHandler{
+		hub: hub,
+	}
+}
+
+func deletedTodo(id uint) gin.H {
+	return gin.H{"id": id}
+}
+
+// StreamTodoEvents 以 Server-Sent Events 推送 Todo 变更, 支持 Last-Event-ID 续传
+func (h *EventHandler) StreamTodoEvents(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	lastID, err := strconv.ParseUint(lastEventID, 10, 64)
+	resume := err == nil
+
+	sub, missed, ok := h.hub.SubscribeSince(lastID, resume, sseBufferSize)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	w := c.Writer
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetryMillis)
+	if !ok {
+		// 请求的事件已不在日志中, 通知客户端重新拉取全量数据
+		fmt.Fprintf(w, "event: reset\ndata: {\"last_event_id\":%d}\n\n", h.hub.LastID())
+	}
+	for _, event := range missed {
+		writeSSEEvent(w, event)
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, open := <-sub.C:
+			if !open {
+				return
+			}
+			writeSSEEvent(w, event)
+			w.Flush()
+		case <-heartbeat.C:
+			io.WriteString(w, ": keepalive\n\n")
+			w.Flush()
+		}
+	}
+}
+
+func writeSSEEvent(w io.Writer, event events.Event) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"todo-backend/internal/export"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ExportHandler struct {
+	repo *repository.TodoRepository
+}
+
+func NewExportHandler(db *gorm.DB) *ExportHandler {
+	return &ExportHandler{
+		repo: repository.NewTodoRepository(db),
+	}
+}
+
+// ExportTodos 按 format 流式导出 Todo, 支持 completed 和 q 过滤
+func (h *ExportHandler) ExportTodos(c *gin.Context) {
+	format := c.DefaultQuery("format", export.FormatJSON)
+
+	var filter repository.TodoFilter
+	if value := c.Query("completed"); value != "" {
+		completed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.Response{
+				Code:    400,
+				Data:    nil,
+				Message: "completed query parameter must be true or false",
+			})
+			return
+		}
+		filter.Completed = &completed
+	}
+	filter.Query = c.Query("q")
+
+	encoder, err := export.NewEncoder(format, c.Writer)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.Response{
+			Code:    400,
+			Data:    nil,
+			Message: "format must be one of json, csv, markdown, todotxt",
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("todos-%s.%s", time.Now().Format("20060102"), export.FileExtension(format))
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// 响应已经开始发送, 出错时只能中断连接, 让客户端知道导出不完整而不是得到一个被截断的文件
+	err = encoder.Begin()
+	if err == nil {
+		err = h.repo.Each(c.Request.Context(), filter, encoder.Write)
+	}
+	if err == nil {
+		err = encoder.End()
+	}
+	if err != nil {
+		log.Printf("Failed to export todos: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"todo-backend/internal/events"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"gorm.io/gorm"
+)
+
+type GraphQLHandler struct {
+	schema        graphql.Schema
+	maxDepth      int
+	maxComplexity int
+	validator     *validation.Validator
+	upgrader      websocket.Upgrader
+}
+
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+	Extensions    map[string]interface{} `json:"extensions"`
+}
+
+// graphqlOperation 是通过解析、校验和限制检查的请求
+type graphqlOperation struct {
+	req *graphqlRequest
+	doc *ast.Document
+	op  *ast.OperationDefinition
+}
+
+func NewGraphQLHandler(db *gorm.DB, hub *events.Hub, quota repository.Quota, v *validation.Validator, maxDepth, maxComplexity int, checkOrigin func(r *http.Request) bool) *GraphQLHandler {
+	schema, err := buildGraphQLSchema(NewTodoHandler(db, hub, quota, v), hub)
+	if err != nil {
+		panic(err)
+	}
+
+	return &GraphQLHandler{
+		schema:        schema,
+		maxDepth:      maxDepth,
+		maxComplexity: maxComplexity,
+		validator:     v,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			Subprotocols:    []string{graphqlWSProtocol},
+			CheckOrigin:     checkOrigin,
+		},
+	}
+}
+
+// Serve 处理 GraphQL over HTTP 请求. GET 请求只能执行查询, 带 Upgrade 头的 GET 请求切换为 WebSocket,
+// 通过 graphql-transport-ws 协议执行订阅
+func (h *GraphQLHandler) Serve(c *gin.Context) {
+	if c.Request.Method == http.MethodGet && websocket.IsWebSocketUpgrade(c.Request) {
+		h.serveWebSocket(c)
+		return
+	}
+
+	var req graphqlRequest
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				respondGraphQLError(c, http.StatusBadRequest, "variables must be a JSON object")
+				return
+			}
+		}
+	} else if err := h.validator.DecodeJSON(c.Writer, c.Request, &req); err != nil {
+		respondGraphQLError(c, validation.StatusCode(err), h.validator.Message(err, validation.Locale(c.GetHeader("Accept-Language"))))
+		return
+	}
+
+	operation, errs := h.prepare(&req)
+	if errs != nil {
+		c.JSON(http.StatusBadRequest, &graphql.Result{Errors: errs})
+		return
+	}
+
+	switch {
+	case operation.op.Operation == ast.OperationTypeSubscription:
+		respondGraphQLError(c, http.StatusBadRequest, "subscriptions are only supported over WebSocket")
+		return
+	case operation.op.Operation == ast.OperationTypeMutation && c.Request.Method == http.MethodGet:
+		c.Header("Allow", "POST")
+		respondGraphQLError(c, http.StatusMethodNotAllowed, "mutations must use POST")
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), graphqlLocaleKey{}, validation.Locale(c.GetHeader("Accept-Language")))
+	c.JSON(http.StatusOK, h.execute(ctx, operation))
+}
+
+// prepare 解析并校验请求, 然后检查深度和复杂度限制
+func (h *GraphQLHandler) prepare(req *graphqlRequest) (*graphqlOperation, []gqlerrors.FormattedError) {
+	if req.Query == "" {
+		return nil, []gqlerrors.FormattedError{gqlerrors.NewFormattedError("query is required")}
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		return nil, gqlerrors.FormatErrors(err)
+	}
+
+	if result := graphql.ValidateDocument(&h.schema, doc, nil); !result.IsValid {
+		return nil, result.Errors
+	}
+
+	op := findOperation(doc, req.OperationName)
+	if op == nil {
+		return nil, []gqlerrors.FormattedError{gqlerrors.NewFormattedError("unknown operation or operationName is required")}
+	}
+
+	cost := analyzeQuery(doc, op, req.Variables)
+	if h.maxDepth > 0 && cost.depth > h.maxDepth {
+		return nil, []gqlerrors.FormattedError{limitError(fmt.Sprintf("query depth %d exceeds the limit of %d", cost.depth, h.maxDepth))}
+	}
+	if h.maxComplexity > 0 && cost.complexity > h.maxComplexity {
+		return nil, []gqlerrors.FormattedError{limitError(fmt.Sprintf("query complexity %d exceeds the limit of %d", cost.complexity, h.maxComplexity))}
+	}
+
+	return &graphqlOperation{req: req, doc: doc, op: op}, nil
+}
+
+func (h *GraphQLHandler) execute(ctx context.Context, operation *graphqlOperation) *graphql.Result {
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           operation.doc,
+		OperationName: operation.req.OperationName,
+		Args:          operation.req.Variables,
+		Context:       ctx,
+	})
+}
+
+func (h *GraphQLHandler) subscribe(ctx context.Context, operation *graphqlOperation) chan *graphql.Result {
+	return graphql.ExecuteSubscription(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           operation.doc,
+		OperationName: operation.req.OperationName,
+		Args:          operation.req.Variables,
+		Context:       ctx,
+	})
+}
+
+func limitError(message string) gqlerrors.FormattedError {
+	err := gqlerrors.NewFormattedError(message)
+	err.Extensions = map[string]interface{}{"code": "QUERY_TOO_COMPLEX"}
+	return err
+}
+
+func respondGraphQLError(c *gin.Context, status int, message string) {
+	c.JSON(status, &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(message)}})
+}
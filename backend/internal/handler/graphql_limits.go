@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// queryCost 是一次操作的深度和复杂度
+type queryCost struct {
+	depth      int
+	complexity int
+}
+
+// analyzeQuery 计算 operation 的嵌套深度和复杂度. 每个字段计 1 点, 带 first 参数的分页字段
+// 其子字段的复杂度乘以 first. 内省字段 (__schema 等) 不计入, 以便 GraphiQL 等工具正常工作.
+// 调用前文档需要已经通过校验, 因此不会出现循环引用的片段
+func analyzeQuery(doc *ast.Document, op *ast.OperationDefinition, variables map[string]interface{}) queryCost {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	var walk func(set *ast.SelectionSet) queryCost
+	walk = func(set *ast.SelectionSet) queryCost {
+		var cost queryCost
+		if set == nil {
+			return cost
+		}
+
+		for _, selection := range set.Selections {
+			var child queryCost
+			switch node := selection.(type) {
+			case *ast.Field:
+				if strings.HasPrefix(node.Name.Value, "__") {
+					continue
+				}
+				child = walk(node.SelectionSet)
+				child.complexity = 1 + child.complexity*pageSize(node, variables)
+				child.depth++
+			case *ast.InlineFragment:
+				child = walk(node.SelectionSet)
+			case *ast.FragmentSpread:
+				if fragment, ok := fragments[node.Name.Value]; ok {
+					child = walk(fragment.SelectionSet)
+				}
+			}
+
+			cost.complexity += child.complexity
+			if child.depth > cost.depth {
+				cost.depth = child.depth
+			}
+		}
+		return cost
+	}
+
+	return walk(op.SelectionSet)
+}
+
+// pageSize 返回分页字段的 first 参数, 其他字段为 1
+func pageSize(field *ast.Field, variables map[string]interface{}) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			switch n := variables[value.Name.Value].(type) {
+			case float64:
+				if n > 0 {
+					return int(n)
+				}
+			case int:
+				if n > 0 {
+					return n
+				}
+			}
+		}
+		return 1
+	}
+
+	if field.Name.Value == "todos" {
+		return graphqlDefaultPageSize
+	}
+	return 1
+}
+
+// findOperation 按名称选择要执行的操作, 文档只有一个操作时可以不指定名称
+func findOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = op
+		} else if op.Name != nil && op.Name.Value == name {
+			return op
+		}
+	}
+	return found
+}
@@ -0,0 +1,516 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"todo-backend/internal/events"
+	"todo-backend/internal/model"
+	"todo-backend/internal/realtime"
+	"todo-backend/internal/repository"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	graphqlDefaultPageSize = 20
+	graphqlMaxPageSize     = 100
+	graphqlCursorPrefix    = "todo:"
+)
+
+type graphqlLocaleKey struct{}
+
+// graphqlError 在 errors[].extensions 中带上错误码和对应的 HTTP 状态码
+type graphqlError struct {
+	message string
+	code    string
+	status  int
+}
+
+func (e *graphqlError) Error() string {
+	return e.message
+}
+
+func (e *graphqlError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code, "status": e.status}
+}
+
+func newGraphQLError(status int, message string) *graphqlError {
+	code := "INTERNAL_SERVER_ERROR"
+	switch status {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		code = "BAD_USER_INPUT"
+	case http.StatusNotFound:
+		code = "NOT_FOUND"
+	case http.StatusPreconditionFailed:
+		code = "VERSION_CONFLICT"
+	case http.StatusForbidden:
+		code = "QUOTA_EXCEEDED"
+	case http.StatusGatewayTimeout:
+		code = "TIMEOUT"
+	case http.StatusServiceUnavailable:
+		code = "UNAVAILABLE"
+	}
+	return &graphqlError{message: message, code: code, status: status}
+}
+
+// todoConnection 是分页查询的结果, totalCount 只在被查询时才计算
+type todoConnection struct {
+	todos   []model.Todo
+	hasNext bool
+	filter  repository.TodoFilter
+}
+
+// todoEvent 是 todoChanged 订阅推送的内容
+type todoEvent struct {
+	event  events.Event
+	todoID uint
+	todo   *model.Todo
+}
+
+func encodeCursor(id uint) string {
+	return base64.StdEncoding.EncodeToString([]byte(graphqlCursorPrefix + strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeCursor(cursor string) (uint, error) {
+	data, err := base64.StdEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(data), graphqlCursorPrefix) {
+		id, err := strconv.ParseUint(strings.TrimPrefix(string(data), graphqlCursorPrefix), 10, 32)
+		if err == nil {
+			return uint(id), nil
+		}
+	}
+	return 0, newGraphQLError(http.StatusBadRequest, "invalid cursor")
+}
+
+func parseGraphQLID(value interface{}) (uint, error) {
+	s, _ := value.(string)
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || id == 0 {
+		return 0, newGraphQLError(http.StatusBadRequest, "invalid id")
+	}
+	return uint(id), nil
+}
+
+func graphqlLocale(ctx context.Context) string {
+	locale, _ := ctx.Value(graphqlLocaleKey{}).(string)
+	return locale
+}
+
+// buildGraphQLSchema 定义 GraphQL schema, 查询直接读取 repository, 修改与 REST、WebSocket 共用 TodoHandler 的逻辑
+func buildGraphQLSchema(todos *TodoHandler, hub *events.Hub) (graphql.Schema, error) {
+	todoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Todo",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return strconv.FormatUint(uint64(p.Source.(*model.Todo).ID), 10), nil
+				},
+			},
+			"title":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"content":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"completed":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"version":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "每次修改递增, 可作为修改和删除的 version 参数"},
+			"completedAt": &graphql.Field{Type: graphql.DateTime},
+			"dueAt":       &graphql.Field{Type: graphql.DateTime},
+			"createdAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"updatedAt":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	edgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TodoEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return encodeCursor(p.Source.(*model.Todo).ID), nil
+				},
+			},
+			"node": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*todoConnection).hasNext, nil
+				},
+			},
+			"endCursor": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					conn := p.Source.(*todoConnection)
+					if len(conn.todos) == 0 {
+						return nil, nil
+					}
+					return encodeCursor(conn.todos[len(conn.todos)-1].ID), nil
+				},
+			},
+		},
+	})
+
+	nodes := func(p graphql.ResolveParams) (interface{}, error) {
+		conn := p.Source.(*todoConnection)
+		items := make([]interface{}, len(conn.todos))
+		for i := range conn.todos {
+			items[i] = &conn.todos[i]
+		}
+		return items, nil
+	}
+
+	connectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TodoConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edgeType))), Resolve: nodes},
+			"nodes": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(todoType))), Resolve: nodes},
+			"pageInfo": &graphql.Field{
+				Type: graphql.NewNonNull(pageInfoType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+			"totalCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					count, err := todos.repo.Count(p.Context, p.Source.(*todoConnection).filter)
+					if err != nil {
+						return nil, queryError(err)
+					}
+					return count, nil
+				},
+			},
+		},
+	})
+
+	eventType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TodoEvent",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "事件 ID, 与 SSE 的 Last-Event-ID 相同",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return strconv.FormatUint(p.Source.(*todoEvent).event.ID, 10), nil
+				},
+			},
+			"type": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*todoEvent).event.Type, nil
+				},
+			},
+			"todoId": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return strconv.FormatUint(uint64(p.Source.(*todoEvent).todoID), 10), nil
+				},
+			},
+			"todo": &graphql.Field{
+				Type:        todoType,
+				Description: "删除事件为 null",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if todo := p.Source.(*todoEvent).todo; todo != nil {
+						return todo, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"todos": &graphql.Field{
+				Type: graphql.NewNonNull(connectionType),
+				Args: graphql.FieldConfigArgument{
+					"completed": &graphql.ArgumentConfig{Type: graphql.Boolean},
+					"query":     &graphql.ArgumentConfig{Type: graphql.String, Description: "标题或内容包含的文本"},
+					"first":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: graphqlDefaultPageSize},
+					"after":     &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					conn := &todoConnection{}
+					if completed, ok := p.Args["completed"].(bool); ok {
+						conn.filter.Completed = &completed
+					}
+					conn.filter.Query, _ = p.Args["query"].(string)
+
+					first, _ := p.Args["first"].(int)
+					if first < 0 || first > graphqlMaxPageSize {
+						return nil, newGraphQLError(http.StatusBadRequest, "first must be between 0 and "+strconv.Itoa(graphqlMaxPageSize))
+					}
+					var after uint
+					if cursor, ok := p.Args["after"].(string); ok {
+						id, err := decodeCursor(cursor)
+						if err != nil {
+							return nil, err
+						}
+						after = id
+					}
+
+					// 多取一条用来判断是否还有下一页
+					list, err := todos.repo.List(p.Context, conn.filter, after, first+1)
+					if err != nil {
+						return nil, queryError(err)
+					}
+					if len(list) > first {
+						conn.hasNext = true
+						list = list[:first]
+					}
+					conn.todos = list
+					return conn, nil
+				},
+			},
+			"todo": &graphql.Field{
+				Type: todoType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := parseGraphQLID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					todo, err := todos.repo.GetByID(p.Context, id)
+					if todos.repo.IsNotFound(err) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, queryError(err)
+					}
+					return todo, nil
+				},
+			},
+		},
+	})
+
+	createInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreateTodoInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"content": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	updateInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "UpdateTodoInput",
+		Description: "未提供的字段保持不变",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"content":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"completed": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		},
+	})
+
+	versionArg := &graphql.ArgumentConfig{Type: graphql.Int, Description: "与 If-Match 相同, 版本不匹配时返回 VERSION_CONFLICT"}
+	idArg := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}
+
+	// update 只写入 apply 设置的字段, 之后按 REST 接口的规则校验和写入
+	update := func(p graphql.ResolveParams, apply func(todo *model.Todo, req *model.UpdateTodoRequest)) (interface{}, error) {
+		id, err := parseGraphQLID(p.Args["id"])
+		if err != nil {
+			return nil, err
+		}
+		current, err := todos.repo.GetByID(p.Context, id)
+		if err != nil {
+			return nil, mutationError(todos, err)
+		}
+
+		var req model.UpdateTodoRequest
+		apply(current, &req)
+		if err := todos.validator.Validate(&req); err != nil {
+			return nil, newGraphQLError(http.StatusBadRequest, todos.validator.Message(err, graphqlLocale(p.Context)))
+		}
+
+		todo, err := todos.updateTodo(p.Context, id, &req, versionETag(id, p.Args["version"]))
+		if err != nil {
+			return nil, mutationError(todos, err)
+		}
+		return todo, nil
+	}
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := p.Args["input"].(map[string]interface{})
+					req := model.CreateTodoRequest{}
+					req.Title, _ = input["title"].(string)
+					req.Content, _ = input["content"].(string)
+					if err := todos.validator.Validate(&req); err != nil {
+						return nil, newGraphQLError(http.StatusBadRequest, todos.validator.Message(err, graphqlLocale(p.Context)))
+					}
+					todo, err := todos.createTodo(p.Context, &req)
+					if err != nil {
+						return nil, mutationError(todos, err)
+					}
+					return todo, nil
+				},
+			},
+			"updateTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id":      idArg,
+					"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateInput)},
+					"version": versionArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := p.Args["input"].(map[string]interface{})
+					return update(p, func(_ *model.Todo, req *model.UpdateTodoRequest) {
+						if title, ok := input["title"].(string); ok {
+							req.Title = title
+						}
+						if content, ok := input["content"].(string); ok {
+							req.Content = content
+						}
+						if completed, ok := input["completed"].(bool); ok {
+							req.Completed = &completed
+						}
+					})
+				},
+			},
+			"toggleTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id":      idArg,
+					"version": versionArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return update(p, func(todo *model.Todo, req *model.UpdateTodoRequest) {
+						completed := !todo.Completed
+						req.Completed = &completed
+					})
+				},
+			},
+			"deleteTodo": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "返回被删除的 Todo 的 ID",
+				Args: graphql.FieldConfigArgument{
+					"id":      idArg,
+					"version": versionArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := parseGraphQLID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					if err := todos.deleteTodo(p.Context, id, versionETag(id, p.Args["version"])); err != nil {
+						return nil, mutationError(todos, err)
+					}
+					return strconv.FormatUint(uint64(id), 10), nil
+				},
+			},
+		},
+	})
+
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"todoChanged": &graphql.Field{
+				Type:        graphql.NewNonNull(eventType),
+				Description: "Todo 的创建、修改和删除事件, 指定 id 时只推送该 Todo 的事件",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.ID},
+				},
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					var only uint
+					if p.Args["id"] != nil {
+						id, err := parseGraphQLID(p.Args["id"])
+						if err != nil {
+							return nil, err
+						}
+						only = id
+					}
+
+					sub := hub.Subscribe(wsSendBuffer)
+					out := make(chan interface{})
+					go func() {
+						defer close(out)
+						defer sub.Close()
+						for {
+							select {
+							case <-p.Context.Done():
+								return
+							case event, ok := <-sub.C:
+								if !ok {
+									return
+								}
+								payload := newTodoEvent(event)
+								if only != 0 && payload.todoID != only {
+									continue
+								}
+								select {
+								case out <- payload:
+								case <-p.Context.Done():
+									return
+								}
+							}
+						}
+					}()
+					return out, nil
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:        query,
+		Mutation:     mutation,
+		Subscription: subscription,
+	})
+}
+
+func newTodoEvent(event events.Event) *todoEvent {
+	payload := &todoEvent{event: event, todoID: realtime.EventTodoID(event)}
+	payload.todo, _ = event.Data.(*model.Todo)
+	return payload
+}
+
+// versionETag 把 version 参数转换为 If-Match, 以复用 REST 接口的乐观锁逻辑
+func versionETag(id uint, version interface{}) string {
+	v, ok := version.(int)
+	if !ok {
+		return ""
+	}
+	return todoETag(&model.Todo{ID: id, Version: uint(v)})
+}
+
+// queryError 把查询超时和请求取消转换为带状态码的错误, 其余错误原样返回
+func queryError(err error) error {
+	if status := serverErrorStatus(err); status != http.StatusInternalServerError {
+		return newGraphQLError(status, err.Error())
+	}
+	return err
+}
+
+func mutationError(todos *TodoHandler, err error) error {
+	status := todos.mutationStatus(err)
+	if status == http.StatusNotFound {
+		return newGraphQLError(status, "todo not found")
+	}
+	if status == http.StatusPreconditionFailed {
+		return newGraphQLError(status, "todo has been modified")
+	}
+	return newGraphQLError(status, err.Error())
+}
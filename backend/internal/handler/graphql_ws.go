@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// graphql-transport-ws 协议, 见 https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const (
+	graphqlWSProtocol    = "graphql-transport-ws"
+	graphqlWSInitTimeout = 10 * time.Second
+
+	gqlConnectionInit = "connection_init"
+	gqlConnectionAck  = "connection_ack"
+	gqlPing           = "ping"
+	gqlPong           = "pong"
+	gqlSubscribe      = "subscribe"
+	gqlNext           = "next"
+	gqlError          = "error"
+	gqlComplete       = "complete"
+
+	gqlCloseBadRequest     = 4400
+	gqlCloseUnauthorized   = 4401
+	gqlCloseInitTimeout    = 4408
+	gqlCloseDuplicateID    = 4409
+	gqlCloseTooManyInits   = 4429
+	gqlCloseUnsupportedSub = 4406
+)
+
+type graphqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type graphqlWSSession struct {
+	handler *GraphQLHandler
+	conn    *websocket.Conn
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	writeMu sync.Mutex
+
+	mu         sync.Mutex
+	acked      bool
+	operations map[string]*graphqlWSOperation
+}
+
+type graphqlWSOperation struct {
+	cancel context.CancelFunc
+}
+
+func (h *GraphQLHandler) serveWebSocket(c *gin.Context) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	if conn.Subprotocol() != graphqlWSProtocol {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(gqlCloseUnsupportedSub, "Subprotocol not acceptable"), time.Now().Add(wsWriteWait))
+		conn.Close()
+		return
+	}
+
+	// 会话比请求存活得久, 不继承请求的 context; 配额仍按建立连接的调用方计算
+	ctx := context.WithValue(context.Background(), graphqlLocaleKey{}, validation.Locale(c.GetHeader("Accept-Language")))
+	ctx = repository.WithOwner(ctx, repository.OwnerFromContext(c.Request.Context()))
+	ctx, cancel := context.WithCancel(ctx)
+	s := &graphqlWSSession{
+		handler:    h,
+		conn:       conn,
+		ctx:        ctx,
+		cancel:     cancel,
+		operations: make(map[string]*graphqlWSOperation),
+	}
+	s.readLoop()
+}
+
+func (s *graphqlWSSession) readLoop() {
+	defer s.cancel()
+	defer s.conn.Close()
+
+	s.conn.SetReadLimit(s.handler.validator.Rules().MaxBodyBytes)
+
+	// 连接建立后必须在限定时间内发送 connection_init
+	initTimer := time.AfterFunc(graphqlWSInitTimeout, func() {
+		s.mu.Lock()
+		acked := s.acked
+		s.mu.Unlock()
+		if !acked {
+			s.close(gqlCloseInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	for {
+		var msg graphqlWSMessage
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			if _, ok := err.(*websocket.CloseError); !ok && s.ctx.Err() == nil {
+				s.close(gqlCloseBadRequest, "Invalid message received")
+			}
+			return
+		}
+
+		s.mu.Lock()
+		acked := s.acked
+		s.mu.Unlock()
+
+		switch msg.Type {
+		case gqlConnectionInit:
+			if acked {
+				s.close(gqlCloseTooManyInits, "Too many initialisation requests")
+				return
+			}
+			s.mu.Lock()
+			s.acked = true
+			s.mu.Unlock()
+			s.write(graphqlWSMessage{Type: gqlConnectionAck})
+
+		case gqlPing:
+			s.write(graphqlWSMessage{Type: gqlPong})
+
+		case gqlPong:
+
+		case gqlSubscribe:
+			if !acked {
+				s.close(gqlCloseUnauthorized, "Unauthorized")
+				return
+			}
+			if msg.ID == "" {
+				s.close(gqlCloseBadRequest, "Subscribe message requires an id")
+				return
+			}
+			if !s.start(msg) {
+				return
+			}
+
+		case gqlComplete:
+			s.mu.Lock()
+			if running, ok := s.operations[msg.ID]; ok {
+				running.cancel()
+				delete(s.operations, msg.ID)
+			}
+			s.mu.Unlock()
+
+		default:
+			s.close(gqlCloseBadRequest, "Unknown message type")
+			return
+		}
+	}
+}
+
+// start 执行一个操作, 查询和修改返回一条 next 后结束, 订阅持续推送直到任意一方发送 complete.
+// 返回 false 表示连接已被关闭
+func (s *graphqlWSSession) start(msg graphqlWSMessage) bool {
+	var req graphqlRequest
+	if err := validation.Unmarshal(msg.Payload, &req); err != nil {
+		s.writeErrors(msg.ID, []gqlerrors.FormattedError{gqlerrors.NewFormattedError(s.handler.validator.Message(err, graphqlLocale(s.ctx)))})
+		return true
+	}
+
+	operation, errs := s.handler.prepare(&req)
+	if errs != nil {
+		s.writeErrors(msg.ID, errs)
+		return true
+	}
+
+	s.mu.Lock()
+	if _, exists := s.operations[msg.ID]; exists {
+		s.mu.Unlock()
+		s.close(gqlCloseDuplicateID, "Subscriber for "+msg.ID+" already exists")
+		return false
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	running := &graphqlWSOperation{cancel: cancel}
+	s.operations[msg.ID] = running
+	s.mu.Unlock()
+
+	go func() {
+		defer s.finish(msg.ID, running)
+
+		if operation.op.Operation != ast.OperationTypeSubscription {
+			s.writeNext(msg.ID, s.handler.execute(ctx, operation))
+			return
+		}
+
+		for result := range s.handler.subscribe(ctx, operation) {
+			if ctx.Err() != nil {
+				return
+			}
+			s.writeNext(msg.ID, result)
+		}
+	}()
+	return true
+}
+
+// finish 在操作结束时通知客户端; 客户端主动 complete 的操作不再回复
+func (s *graphqlWSSession) finish(id string, running *graphqlWSOperation) {
+	running.cancel()
+
+	s.mu.Lock()
+	active := s.operations[id] == running
+	if active {
+		delete(s.operations, id)
+	}
+	s.mu.Unlock()
+
+	if active && s.ctx.Err() == nil {
+		s.write(graphqlWSMessage{ID: id, Type: gqlComplete})
+	}
+}
+
+func (s *graphqlWSSession) writeNext(id string, result interface{}) {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	s.write(graphqlWSMessage{ID: id, Type: gqlNext, Payload: payload})
+}
+
+func (s *graphqlWSSession) writeErrors(id string, errs []gqlerrors.FormattedError) {
+	payload, err := json.Marshal(errs)
+	if err != nil {
+		return
+	}
+	s.write(graphqlWSMessage{ID: id, Type: gqlError, Payload: payload})
+}
+
+func (s *graphqlWSSession) write(msg graphqlWSMessage) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if err := s.conn.WriteJSON(msg); err != nil {
+		s.cancel()
+	}
+}
+
+func (s *graphqlWSSession) close(code int, reason string) {
+	s.writeMu.Lock()
+	s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteWait))
+	s.writeMu.Unlock()
+
+	s.cancel()
+	s.conn.Close()
+}
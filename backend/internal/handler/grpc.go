@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"todo-backend/internal/events"
+	"todo-backend/internal/model"
+	"todo-backend/internal/realtime"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
+	todov1 "todo-backend/proto/todo/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+const (
+	grpcDefaultPageSize = 20
+	grpcMaxPageSize     = 100
+	grpcWatchBuffer     = 64
+	grpcResetEvent      = "reset"
+)
+
+// TodoGRPCServer 实现 todo.v1.TodoService, 与 REST 接口共用 TodoHandler 的读写逻辑
+type TodoGRPCServer struct {
+	todov1.UnimplementedTodoServiceServer
+
+	todos *TodoHandler
+	hub   *events.Hub
+}
+
+func NewTodoGRPCServer(db *gorm.DB, hub *events.Hub, quota repository.Quota, v *validation.Validator) *TodoGRPCServer {
+	return &TodoGRPCServer{
+		todos: NewTodoHandler(db, hub, quota, v),
+		hub:   hub,
+	}
+}
+
+func (s *TodoGRPCServer) ListTodos(ctx context.Context, req *todov1.ListTodosRequest) (*todov1.ListTodosResponse, error) {
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize < 0 || pageSize > grpcMaxPageSize:
+		return nil, status.Errorf(codes.InvalidArgument, "page_size must be between 0 and %d", grpcMaxPageSize)
+	case pageSize == 0:
+		pageSize = grpcDefaultPageSize
+	}
+
+	var after uint
+	if req.GetPageToken() != "" {
+		id, err := decodeCursor(req.GetPageToken())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		after = id
+	}
+
+	filter := repository.TodoFilter{Completed: req.Completed, Query: req.GetQuery()}
+	// 多取一条用来判断是否还有下一页
+	list, err := s.todos.repo.List(ctx, filter, after, pageSize+1)
+	if err != nil {
+		return nil, s.mutationError(err)
+	}
+	total, err := s.todos.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, s.mutationError(err)
+	}
+
+	resp := &todov1.ListTodosResponse{TotalSize: total}
+	if len(list) > pageSize {
+		list = list[:pageSize]
+		resp.NextPageToken = encodeCursor(list[len(list)-1].ID)
+	}
+	for i := range list {
+		resp.Todos = append(resp.Todos, todoToProto(&list[i]))
+	}
+	return resp, nil
+}
+
+func (s *TodoGRPCServer) GetTodo(ctx context.Context, req *todov1.GetTodoRequest) (*todov1.GetTodoResponse, error) {
+	todo, err := s.todos.repo.GetByID(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, s.mutationError(err)
+	}
+	return &todov1.GetTodoResponse{Todo: todoToProto(todo)}, nil
+}
+
+func (s *TodoGRPCServer) CreateTodo(ctx context.Context, req *todov1.CreateTodoRequest) (*todov1.CreateTodoResponse, error) {
+	create := model.CreateTodoRequest{Title: req.GetTitle(), Content: req.GetContent()}
+	if err := s.todos.validator.Validate(&create); err != nil {
+		return nil, status.Error(codes.InvalidArgument, s.todos.validator.Message(err, grpcLocale(ctx)))
+	}
+
+	todo, err := s.todos.createTodo(ctx, &create)
+	if err != nil {
+		return nil, s.mutationError(err)
+	}
+	return &todov1.CreateTodoResponse{Todo: todoToProto(todo)}, nil
+}
+
+func (s *TodoGRPCServer) UpdateTodo(ctx context.Context, req *todov1.UpdateTodoRequest) (*todov1.UpdateTodoResponse, error) {
+	update := model.UpdateTodoRequest{Title: req.GetTitle(), Content: req.GetContent(), Completed: req.Completed}
+	if err := s.todos.validator.Validate(&update); err != nil {
+		return nil, status.Error(codes.InvalidArgument, s.todos.validator.Message(err, grpcLocale(ctx)))
+	}
+
+	id := uint(req.GetId())
+	todo, err := s.todos.updateTodo(ctx, id, &update, grpcIfMatch(id, req.GetVersion()))
+	if err != nil {
+		return nil, s.mutationError(err)
+	}
+	return &todov1.UpdateTodoResponse{Todo: todoToProto(todo)}, nil
+}
+
+func (s *TodoGRPCServer) DeleteTodo(ctx context.Context, req *todov1.DeleteTodoRequest) (*todov1.DeleteTodoResponse, error) {
+	id := uint(req.GetId())
+	if _, err := s.todos.repo.GetByID(ctx, id); err != nil {
+		return nil, s.mutationError(err)
+	}
+	if err := s.todos.deleteTodo(ctx, id, grpcIfMatch(id, req.GetVersion())); err != nil {
+		return nil, s.mutationError(err)
+	}
+	return &todov1.DeleteTodoResponse{}, nil
+}
+
+func (s *TodoGRPCServer) WatchTodos(req *todov1.WatchTodosRequest, stream todov1.TodoService_WatchTodosServer) error {
+	sub, missed, ok := s.hub.SubscribeSince(req.GetLastEventId(), req.LastEventId != nil, grpcWatchBuffer)
+	defer sub.Close()
+
+	if !ok {
+		// 请求的事件已不在日志中, 通知客户端重新拉取全量数据
+		reset := &todov1.TodoEvent{Id: s.hub.LastID(), Type: grpcResetEvent}
+		if err := stream.Send(&todov1.WatchTodosResponse{Event: reset}); err != nil {
+			return err
+		}
+	}
+
+	send := func(event events.Event) error {
+		todoID := realtime.EventTodoID(event)
+		if req.GetId() != 0 && uint(req.GetId()) != todoID {
+			return nil
+		}
+		return stream.Send(&todov1.WatchTodosResponse{Event: eventToProto(event, todoID)})
+	}
+
+	for _, event := range missed {
+		if err := send(event); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, open := <-sub.C:
+			if !open {
+				// 消费过慢被断开, 客户端可以凭最后的事件 ID 重新订阅
+				return status.Error(codes.ResourceExhausted, "watcher is too slow")
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// mutationError 把 REST 接口使用的错误转换为 gRPC 状态码
+func (s *TodoGRPCServer) mutationError(err error) error {
+	switch s.todos.mutationStatus(err) {
+	case http.StatusNotFound:
+		return status.Error(codes.NotFound, "todo not found")
+	case http.StatusPreconditionFailed:
+		return status.Error(codes.FailedPrecondition, "todo has been modified")
+	case http.StatusForbidden:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case http.StatusGatewayTimeout:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case http.StatusServiceUnavailable:
+		return status.Error(codes.Canceled, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// grpcIfMatch 把 version 字段转换为 If-Match, 0 表示不检查版本
+func grpcIfMatch(id uint, version uint32) string {
+	if version == 0 {
+		return ""
+	}
+	return todoETag(&model.Todo{ID: id, Version: uint(version)})
+}
+
+// grpcLocale 根据 accept-language 元数据选择错误信息的语言
+func grpcLocale(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var acceptLanguage string
+	if values := md.Get("accept-language"); len(values) > 0 {
+		acceptLanguage = values[0]
+	}
+	return validation.Locale(acceptLanguage)
+}
+
+func todoToProto(todo *model.Todo) *todov1.Todo {
+	pb := &todov1.Todo{
+		Id:        uint32(todo.ID),
+		Title:     todo.Title,
+		Content:   todo.Content,
+		Completed: todo.Completed,
+		Version:   uint32(todo.Version),
+		CreatedAt: timestamppb.New(todo.CreatedAt),
+		UpdatedAt: timestamppb.New(todo.UpdatedAt),
+	}
+	if todo.CompletedAt != nil {
+		pb.CompletedAt = timestamppb.New(*todo.CompletedAt)
+	}
+	if todo.DueAt != nil {
+		pb.DueAt = timestamppb.New(*todo.DueAt)
+	}
+	return pb
+}
+
+func eventToProto(event events.Event, todoID uint) *todov1.TodoEvent {
+	pb := &todov1.TodoEvent{
+		Id:     event.ID,
+		Type:   event.Type,
+		TodoId: uint32(todoID),
+		Time:   timestamppb.New(event.Time),
+	}
+	if todo, ok := event.Data.(*model.Todo); ok {
+		pb.Todo = todoToProto(todo)
+	}
+	return pb
+}
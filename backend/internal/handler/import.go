@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"todo-backend/internal/events"
+	"todo-backend/internal/importer"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ImportHandler struct {
+	repo      *repository.TodoRepository
+	hub       *events.Hub
+	validator *validation.Validator
+	maxBytes  int64
+	maxRows   int
+}
+
+func NewImportHandler(db *gorm.DB, hub *events.Hub, quota repository.Quota, v *validation.Validator, maxBytes int64, maxRows int) *ImportHandler {
+	return &ImportHandler{
+		repo:      repository.NewTodoRepository(db).WithQuota(quota),
+		hub:       hub,
+		validator: v,
+		maxBytes:  maxBytes,
+		maxRows:   maxRows,
+	}
+}
+
+// ImportTodos 从 multipart 上传的文件导入 Todo. format 为空时根据文件名和内容识别,
+// dry_run=true 时只返回导入报告, 不写入数据
+func (h *ImportHandler) ImportTodos(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondImportError(c, http.StatusRequestEntityTooLarge, "file is too large")
+			return
+		}
+		respondImportError(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	dryRun, err := strconv.ParseBool(c.DefaultPostForm("dry_run", c.DefaultQuery("dry_run", "false")))
+	if err != nil {
+		respondImportError(c, http.StatusBadRequest, "dry_run must be true or false")
+		return
+	}
+
+	reader := bufio.NewReader(file)
+	format := c.DefaultPostForm("format", c.Query("format"))
+	if format == "" {
+		head, _ := reader.Peek(512)
+		format = importer.Detect(header.Filename, head)
+	}
+
+	records, err := importer.Parse(format, reader, h.maxRows)
+	if err != nil {
+		switch {
+		case errors.Is(err, importer.ErrUnknownFormat):
+			respondImportError(c, http.StatusBadRequest, "format must be one of csv, json, todotxt, todoist, trello")
+		case errors.Is(err, importer.ErrTooManyRows):
+			respondImportError(c, http.StatusRequestEntityTooLarge, "file has more than "+strconv.Itoa(h.maxRows)+" rows")
+		default:
+			respondImportError(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	locale := validation.Locale(c.GetHeader("Accept-Language"))
+	report := model.ImportReport{
+		Format: format,
+		DryRun: dryRun,
+		Rows:   make([]model.ImportRow, 0, len(records)),
+	}
+
+	// 所有行在一个事务中导入, 每行的查询重新计算截止时间, 行数多时不会因请求的总时间预算超时
+	ctx := c.Request.Context()
+	var created []*model.Todo
+	err = h.repo.Transaction(repository.WithoutQueryTimeout(ctx), func(repo *repository.TodoRepository) error {
+		seen := make(map[string]bool)
+		for i := range records {
+			row, todo, err := h.importRecord(repository.RenewQueryTimeout(ctx), repo, &records[i], seen, dryRun, locale)
+			if err != nil {
+				return err
+			}
+			if todo != nil {
+				created = append(created, todo)
+			}
+			report.Rows = append(report.Rows, row)
+		}
+		return nil
+	})
+	if err != nil {
+		respondImportError(c, writeStatus(err), err.Error())
+		return
+	}
+
+	for _, todo := range created {
+		h.hub.Publish(events.TodoCreated, todo)
+	}
+
+	for _, row := range report.Rows {
+		switch row.Status {
+		case model.ImportCreated, model.ImportWouldCreate:
+			report.Created++
+		case model.ImportDuplicate:
+			report.Duplicates++
+		case model.ImportSkipped:
+			report.Skipped++
+		case model.ImportError:
+			report.Failed++
+		}
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Code:    0,
+		Data:    report,
+		Message: "success",
+	})
+}
+
+// importRecord 校验并导入一行, 只有数据库错误会中止整个导入
+func (h *ImportHandler) importRecord(ctx context.Context, repo *repository.TodoRepository, record *importer.Record, seen map[string]bool, dryRun bool, locale string) (model.ImportRow, *model.Todo, error) {
+	row := model.ImportRow{Row: record.Row, Title: record.Title}
+
+	if record.Err != nil {
+		row.Status = model.ImportError
+		row.Error = record.Err.Error()
+		return row, nil, nil
+	}
+	if record.Skip != "" {
+		row.Status = model.ImportSkipped
+		row.Error = record.Skip
+		return row, nil, nil
+	}
+
+	todo := record.Todo()
+	req := model.CreateTodoRequest{Title: todo.Title, Content: todo.Content}
+	if err := h.validator.Validate(&req); err != nil {
+		row.Status = model.ImportError
+		row.Error = h.validator.Message(err, locale)
+		return row, nil, nil
+	}
+	todo.Title = req.Title
+	todo.Content = req.Content
+	row.Title = todo.Title
+
+	// 以标题和创建日期去重, 没有创建日期的记录按今天创建处理
+	createdAt := time.Now()
+	if !todo.CreatedAt.IsZero() {
+		createdAt = todo.CreatedAt
+	}
+	day := createdAt.In(time.Local).Format("2006-01-02")
+	key := day + "\x00" + todo.Title
+	if seen[key] {
+		row.Status = model.ImportDuplicate
+		return row, nil, nil
+	}
+	seen[key] = true
+
+	existing, err := repo.FindByTitle(ctx, todo.Title)
+	if err != nil {
+		return row, nil, err
+	}
+	for _, other := range existing {
+		if other.CreatedAt.In(time.Local).Format("2006-01-02") == day {
+			row.Status = model.ImportDuplicate
+			row.ID = other.ID
+			return row, nil, nil
+		}
+	}
+
+	if dryRun {
+		row.Status = model.ImportWouldCreate
+		return row, nil, nil
+	}
+
+	if err := repo.Create(ctx, todo); err != nil {
+		return row, nil, err
+	}
+	row.Status = model.ImportCreated
+	row.ID = todo.ID
+	return row, todo, nil
+}
+
+func respondImportError(c *gin.Context, status int, message string) {
+	c.JSON(status, model.Response{
+		Code:    status,
+		Data:    nil,
+		Message: message,
+	})
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"todo-backend/internal/events"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 以下方法在 REST 和 WebSocket 之间共用, 调用前请求需要已经通过 validation 校验
+
+func (h *TodoHandler) createTodo(ctx context.Context, req *model.CreateTodoRequest) (*model.Todo, error) {
+	todo := &model.Todo{
+		Title:   req.Title,
+		Content: req.Content,
+	}
+	if err := h.repo.Create(ctx, todo); err != nil {
+		return nil, err
+	}
+
+	h.hub.Publish(events.TodoCreated, todo)
+	return todo, nil
+}
+
+// updateTodo 在同一个事务中检查版本、更新并读取更新后的 Todo, 提交后再发布事件
+func (h *TodoHandler) updateTodo(ctx context.Context, id uint, req *model.UpdateTodoRequest, ifMatch string) (*model.Todo, error) {
+	var todo *model.Todo
+	err := h.uow.Do(ctx, func(tx *repository.UnitOfWork) error {
+		repo := tx.Todos()
+		version, err := resolveIfMatch(ctx, repo, id, ifMatch)
+		if err != nil {
+			return err
+		}
+		if err := repo.UpdateIfVersion(ctx, id, req, version); err != nil {
+			return err
+		}
+		todo, err = repo.GetByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h.hub.Publish(events.TodoUpdated, todo)
+	return todo, nil
+}
+
+func (h *TodoHandler) deleteTodo(ctx context.Context, id uint, ifMatch string) error {
+	err := h.uow.Do(ctx, func(tx *repository.UnitOfWork) error {
+		repo := tx.Todos()
+		version, err := resolveIfMatch(ctx, repo, id, ifMatch)
+		if err != nil {
+			return err
+		}
+		return repo.DeleteIfVersion(ctx, id, version)
+	})
+	if err != nil {
+		return err
+	}
+
+	h.hub.Publish(events.TodoDeleted, deletedTodo(id))
+	return nil
+}
+
+// resolveIfMatch 校验 If-Match, 返回需要匹配的版本号 (ifMatch 为空时为 0)
+func resolveIfMatch(ctx context.Context, repo *repository.TodoRepository, id uint, ifMatch string) (uint, error) {
+	if ifMatch == "" {
+		return 0, nil
+	}
+
+	todo, err := repo.GetByID(ctx, id)
+	if err != nil {
+		if repo.IsNotFound(err) {
+			return 0, repository.ErrVersionConflict
+		}
+		return 0, err
+	}
+
+	if !matchETag(ifMatch, todoETag(todo), false) {
+		return 0, repository.ErrVersionConflict
+	}
+	return todo.Version, nil
+}
+
+func (h *TodoHandler) mutationStatus(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case h.repo.IsNotFound(err):
+		return http.StatusNotFound
+	}
+	return writeStatus(err)
+}
+
+// writeStatus 返回写入 Todo 失败时的状态码: 超出配额为 403, 其余同 serverErrorStatus
+func writeStatus(err error) int {
+	if errors.Is(err, repository.ErrQuotaExceeded) {
+		return http.StatusForbidden
+	}
+	return serverErrorStatus(err)
+}
+
+// serverErrorStatus 返回查询失败时的状态码: 查询超时为 504, 请求被取消为 503, 其余为 500
+func serverErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrQueryTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, repository.ErrCanceled):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func respondServerError(c *gin.Context, err error) {
+	status := serverErrorStatus(err)
+	c.JSON(status, model.Response{
+		Code:    status,
+		Data:    nil,
+		Message: err.Error(),
+	})
+}
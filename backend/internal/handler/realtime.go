@@ -0,0 +1,317 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"todo-backend/internal/events"
+	"todo-backend/internal/model"
+	"todo-backend/internal/realtime"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+	wsSendBuffer = 256
+)
+
+type RealtimeHandler struct {
+	todos    *TodoHandler
+	hub      *events.Hub
+	registry *realtime.Registry
+	upgrader websocket.Upgrader
+}
+
+// NewRealtimeHandler 创建 WebSocket 处理器, checkOrigin 决定是否接受浏览器页面的来源
+func NewRealtimeHandler(db *gorm.DB, hub *events.Hub, quota repository.Quota, v *validation.Validator, checkOrigin func(r *http.Request) bool) *RealtimeHandler {
+	return &RealtimeHandler{
+		todos:    NewTodoHandler(db, hub, quota, v),
+		hub:      hub,
+		registry: realtime.NewRegistry(),
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     checkOrigin,
+		},
+	}
+}
+
+type wsSession struct {
+	handler *RealtimeHandler
+	ctx     context.Context
+	conn    *websocket.Conn
+	client  *realtime.Client
+	locale  string
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu  sync.Mutex
+	sub *events.Subscription
+}
+
+// Connect 升级为 WebSocket 连接, 客户端通过 JSON 消息订阅、提交修改和上报在线状态
+func (h *RealtimeHandler) Connect(c *gin.Context) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+
+	user := c.Query("user")
+	if user == "" {
+		user = "anonymous"
+	}
+
+	s := &wsSession{
+		handler: h,
+		ctx:     c.Request.Context(),
+		conn:    conn,
+		client:  h.registry.Register(user, wsSendBuffer),
+		locale:  validation.Locale(c.GetHeader("Accept-Language")),
+		done:    make(chan struct{}),
+	}
+
+	go s.writeLoop()
+	s.send(realtime.ServerMessage{
+		Type:        "welcome",
+		ClientID:    s.client.ID,
+		User:        s.client.User,
+		LastEventID: h.hub.LastID(),
+	})
+	s.readLoop()
+}
+
+func (s *wsSession) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+
+		s.mu.Lock()
+		if s.sub != nil {
+			s.sub.Close()
+		}
+		s.mu.Unlock()
+
+		s.handler.registry.Unregister(s.client)
+		s.conn.Close()
+	})
+}
+
+func (s *wsSession) send(msg realtime.ServerMessage) {
+	if !s.client.Send(msg) {
+		// 客户端消费过慢, 断开后由客户端携带 last_event_id 重连
+		s.close()
+	}
+}
+
+func (s *wsSession) readLoop() {
+	defer s.close()
+
+	s.conn.SetReadLimit(s.handler.todos.validator.Rules().MaxBodyBytes)
+	s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		s.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		var msg realtime.ClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.sendError("", http.StatusBadRequest, "invalid message: "+err.Error())
+			continue
+		}
+		s.handle(msg)
+	}
+}
+
+func (s *wsSession) writeLoop() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg := <-s.client.Outbox():
+			s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := s.conn.WriteJSON(msg); err != nil {
+				s.close()
+				return
+			}
+		case <-ticker.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				s.close()
+				return
+			}
+		}
+	}
+}
+
+func (s *wsSession) eventLoop(sub *events.Subscription) {
+	for event := range sub.C {
+		if s.client.Interested(realtime.EventTodoID(event)) {
+			event := event
+			s.send(realtime.ServerMessage{Type: "event", Event: &event})
+		}
+	}
+
+	select {
+	case <-s.done:
+	default:
+		s.close()
+	}
+}
+
+func (s *wsSession) handle(msg realtime.ClientMessage) {
+	switch msg.Type {
+	case "ping":
+		s.send(realtime.ServerMessage{Type: "pong", RequestID: msg.RequestID})
+	case "subscribe":
+		s.subscribe(msg)
+	case "unsubscribe":
+		s.client.Unsubscribe(msg.Topics)
+		s.send(realtime.ServerMessage{Type: "subscribed", RequestID: msg.RequestID, Topics: s.client.Topics()})
+	case "mutate":
+		s.mutate(msg)
+	case "presence":
+		if msg.TodoID == 0 {
+			s.sendError(msg.RequestID, http.StatusBadRequest, "todo_id is required")
+			return
+		}
+		if err := s.handler.registry.SetPresence(s.client, msg.TodoID, msg.State); err != nil {
+			s.sendError(msg.RequestID, http.StatusBadRequest, err.Error())
+		}
+	default:
+		s.sendError(msg.RequestID, http.StatusBadRequest, "unknown message type")
+	}
+}
+
+func (s *wsSession) subscribe(msg realtime.ClientMessage) {
+	if err := s.client.Subscribe(msg.Topics); err != nil {
+		s.sendError(msg.RequestID, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var (
+		sub     *events.Subscription
+		missed  []events.Event
+		resumed = true
+	)
+
+	s.mu.Lock()
+	if s.sub == nil {
+		var lastID uint64
+		if msg.LastEventID != nil {
+			lastID = *msg.LastEventID
+		}
+		s.sub, missed, resumed = s.handler.hub.SubscribeSince(lastID, msg.LastEventID != nil, wsSendBuffer)
+		sub = s.sub
+	}
+	s.mu.Unlock()
+
+	s.send(realtime.ServerMessage{Type: "subscribed", RequestID: msg.RequestID, Topics: s.client.Topics()})
+
+	if !resumed {
+		s.send(realtime.ServerMessage{Type: "reset", LastEventID: s.handler.hub.LastID()})
+	}
+	for i := range missed {
+		if s.client.Interested(realtime.EventTodoID(missed[i])) {
+			s.send(realtime.ServerMessage{Type: "event", Event: &missed[i]})
+		}
+	}
+	// 补发完成后再开始转发新事件, 保证事件顺序
+	if sub != nil {
+		go s.eventLoop(sub)
+	}
+
+	for _, topic := range msg.Topics {
+		if todoID, _ := realtime.ParseTopic(topic); todoID > 0 {
+			s.send(s.handler.registry.PresenceMessage(todoID))
+		}
+	}
+}
+
+// mutate 与 REST 接口共用校验和写入逻辑
+func (s *wsSession) mutate(msg realtime.ClientMessage) {
+	todos := s.handler.todos
+	// 连接会一直保持, 每条消息单独计算查询超时
+	ctx := repository.RenewQueryTimeout(s.ctx)
+
+	switch msg.Op {
+	case "create":
+		var req model.CreateTodoRequest
+		if err := todos.decodeBatchData(msg.Data, &req); err != nil {
+			s.sendError(msg.RequestID, validation.StatusCode(err), todos.validator.Message(err, s.locale))
+			return
+		}
+		todo, err := todos.createTodo(ctx, &req)
+		if err != nil {
+			s.sendError(msg.RequestID, writeStatus(err), err.Error())
+			return
+		}
+		s.sendResult(msg.RequestID, http.StatusCreated, todo)
+
+	case "update":
+		if msg.ID == 0 {
+			s.sendError(msg.RequestID, http.StatusBadRequest, "id is required")
+			return
+		}
+		var req model.UpdateTodoRequest
+		if err := todos.decodeBatchData(msg.Data, &req); err != nil {
+			s.sendError(msg.RequestID, validation.StatusCode(err), todos.validator.Message(err, s.locale))
+			return
+		}
+		todo, err := todos.updateTodo(ctx, msg.ID, &req, msg.IfMatch)
+		if err != nil {
+			s.sendError(msg.RequestID, todos.mutationStatus(err), err.Error())
+			return
+		}
+		s.sendResult(msg.RequestID, http.StatusOK, todo)
+
+	case "delete":
+		if msg.ID == 0 {
+			s.sendError(msg.RequestID, http.StatusBadRequest, "id is required")
+			return
+		}
+		if err := todos.deleteTodo(ctx, msg.ID, msg.IfMatch); err != nil {
+			s.sendError(msg.RequestID, todos.mutationStatus(err), err.Error())
+			return
+		}
+		s.sendResult(msg.RequestID, http.StatusOK, deletedTodo(msg.ID))
+
+	default:
+		s.sendError(msg.RequestID, http.StatusBadRequest, "unknown op")
+	}
+}
+
+func (s *wsSession) sendResult(requestID string, status int, data interface{}) {
+	s.send(realtime.ServerMessage{
+		Type:      "result",
+		RequestID: requestID,
+		Status:    status,
+		Data:      data,
+	})
+}
+
+func (s *wsSession) sendError(requestID string, status int, message string) {
+	s.send(realtime.ServerMessage{
+		Type:      "error",
+		RequestID: requestID,
+		Status:    status,
+		Message:   message,
+	})
+}
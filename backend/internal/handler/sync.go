@@ -0,0 +1,353 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"todo-backend/internal/events"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errInvalidSyncToken = errors.New("invalid sync token")
+	errSyncItemFailed   = errors.New("sync change failed")
+)
+
+type SyncHandler struct {
+	repo      *repository.TodoRepository
+	hub       *events.Hub
+	validator *validation.Validator
+}
+
+func NewSyncHandler(db *gorm.DB, hub *events.Hub, quota repository.Quota, v *validation.Validator) *SyncHandler {
+	return &SyncHandler{
+		repo:      repository.NewTodoRepository(db).WithQuota(quota),
+		hub:       hub,
+		validator: v,
+	}
+}
+
+// GetChanges 返回 since 之后的所有变更; 没有 since 时返回全量数据并设置 reset
+func (h *SyncHandler) GetChanges(c *gin.Context) {
+	resp, err := h.changesSince(c.Request.Context(), c.Query("since"))
+	if err != nil {
+		respondSyncError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Code:    0,
+		Data:    resp,
+		Message: "success",
+	})
+}
+
+// PushChanges 合并客户端离线期间的修改, 并返回 since 之后的变更
+func (h *SyncHandler) PushChanges(c *gin.Context) {
+	var req model.SyncPushRequest
+	if err := h.validator.BindJSON(c, &req); err != nil {
+		respondBindError(c, h.validator, err)
+		return
+	}
+	if _, _, err := parseSyncToken(req.Since); err != nil {
+		respondSyncError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	results := make([]model.SyncResult, 0, len(req.Changes))
+	err := h.repo.Transaction(ctx, func(repo *repository.TodoRepository) error {
+		for i, change := range req.Changes {
+			var result model.SyncResult
+			// 每条修改使用独立的 SAVEPOINT, 失败只回滚该条
+			err := repo.Transaction(ctx, func(item *repository.TodoRepository) error {
+				result = h.applyChange(ctx, item, i, change)
+				if result.Status == model.SyncError {
+					return errSyncItemFailed
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errSyncItemFailed) {
+				// SAVEPOINT 本身失败时修改没有执行或已回滚, 不能报告为成功
+				result = syncFailed(model.SyncResult{Index: i, ClientRef: change.ClientRef, ID: change.ID}, err.Error())
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		respondSyncError(c, err)
+		return
+	}
+
+	for _, result := range results {
+		h.publishSyncResult(result)
+	}
+
+	changes, err := h.changesSince(c.Request.Context(), req.Since)
+	if err != nil {
+		respondSyncError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Code: 0,
+		Data: model.SyncPushResponse{
+			SyncResponse: *changes,
+			Results:      results,
+		},
+		Message: "success",
+	})
+}
+
+func (h *SyncHandler) changesSince(ctx context.Context, token string) (*model.SyncResponse, error) {
+	since, full, err := parseSyncToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &model.SyncResponse{Changes: []model.SyncChange{}}
+	err = h.repo.Transaction(ctx, func(repo *repository.TodoRepository) error {
+		last, err := repo.LastChangeSeq(ctx)
+		if err != nil {
+			return err
+		}
+		resp.Token = strconv.FormatUint(last, 10)
+
+		// 令牌比服务端还新 (例如数据库被重建), 让客户端重新全量同步
+		if full || since > last {
+			todos, err := repo.GetAll(ctx)
+			if err != nil {
+				return err
+			}
+			resp.Reset = true
+			for i := range todos {
+				resp.Changes = append(resp.Changes, model.SyncChange{Type: model.SyncCreated, ID: todos[i].ID, Todo: &todos[i]})
+			}
+			return nil
+		}
+
+		todos, err := repo.ChangesSince(ctx, since)
+		if err != nil {
+			return err
+		}
+		for i := range todos {
+			todo := &todos[i]
+			switch {
+			case todo.DeletedAt.Valid && todo.CreatedSeq > since:
+				// 客户端从未见过的记录, 无需下发删除标记
+			case todo.DeletedAt.Valid:
+				resp.Changes = append(resp.Changes, model.SyncChange{Type: model.SyncDeleted, ID: todo.ID, DeletedAt: &todo.DeletedAt.Time})
+			case todo.CreatedSeq > since:
+				resp.Changes = append(resp.Changes, model.SyncChange{Type: model.SyncCreated, ID: todo.ID, Todo: todo})
+			default:
+				resp.Changes = append(resp.Changes, model.SyncChange{Type: model.SyncUpdated, ID: todo.ID, Todo: todo})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (h *SyncHandler) applyChange(ctx context.Context, repo *repository.TodoRepository, index int, change model.SyncPushChange) model.SyncResult {
+	result := model.SyncResult{Index: index, ClientRef: change.ClientRef, ID: change.ID}
+
+	if change.Title != nil && *change.Title == "" {
+		return syncFailed(result, "title cannot be empty")
+	}
+
+	if change.ID == 0 {
+		if change.Deleted {
+			return syncFailed(result, "id is required to delete a todo")
+		}
+		if change.Title == nil {
+			return syncFailed(result, "title is required to create a todo")
+		}
+
+		todo := &model.Todo{Title: *change.Title}
+		if change.Content != nil {
+			todo.Content = *change.Content
+		}
+		if change.Completed != nil {
+			todo.Completed = *change.Completed
+		}
+		if err := repo.Create(ctx, todo); err != nil {
+			return syncFailed(result, err.Error())
+		}
+
+		result.ID = todo.ID
+		result.Status = model.SyncCreated
+		result.Todo = todo
+		return result
+	}
+
+	if change.BaseVersion == 0 && change.ModifiedAt.IsZero() {
+		return syncFailed(result, "base_version or modified_at is required")
+	}
+
+	current, err := repo.GetByIDUnscoped(ctx, change.ID)
+	if err != nil {
+		if repo.IsNotFound(err) {
+			return syncFailed(result, "todo not found")
+		}
+		return syncFailed(result, err.Error())
+	}
+
+	// 服务端在客户端基准版本之后没有修改, 或者客户端的修改时间更晚时, 客户端的值生效
+	clientNewer := !change.ModifiedAt.Before(current.UpdatedAt)
+	clientWins := change.BaseVersion == current.Version || clientNewer
+
+	if current.DeletedAt.Valid {
+		if change.Deleted {
+			result.Status = model.SyncUnchanged
+			return result
+		}
+		result.Status = model.SyncConflict
+		result.Conflicts = []model.SyncFieldConflict{{Field: "deleted", ServerValue: true, ClientValue: false}}
+		return result
+	}
+
+	if change.Deleted {
+		if !clientWins {
+			result.Status = model.SyncConflict
+			result.Todo = current
+			result.Conflicts = []model.SyncFieldConflict{{Field: "deleted", ServerValue: false, ClientValue: true}}
+			return result
+		}
+		if err := repo.DeleteIfVersion(ctx, current.ID, current.Version); err != nil {
+			return syncFailed(result, err.Error())
+		}
+		result.Status = model.SyncDeleted
+		return result
+	}
+
+	// 带有基准值的字段单独判断: 只有一方修改时采用修改方的值, 双方都修改时才比较修改时间
+	values := map[string]interface{}{}
+	merge := func(field string, client, server, base interface{}) {
+		if client == server {
+			return
+		}
+		wins := clientWins
+		if base != nil {
+			switch {
+			case server == base:
+				wins = true
+			case client == base:
+				return
+			default:
+				wins = clientNewer
+			}
+		}
+		if wins {
+			values[field] = client
+			return
+		}
+		result.Conflicts = append(result.Conflicts, model.SyncFieldConflict{Field: field, ServerValue: server, ClientValue: client})
+	}
+	base := model.SyncBase{}
+	if change.Base != nil {
+		base = *change.Base
+	}
+	if change.Title != nil {
+		merge("title", *change.Title, current.Title, stringBase(base.Title))
+	}
+	if change.Content != nil {
+		merge("content", *change.Content, current.Content, stringBase(base.Content))
+	}
+	if change.Completed != nil {
+		merge("completed", *change.Completed, current.Completed, boolBase(base.Completed))
+	}
+
+	result.Todo = current
+	if len(values) == 0 {
+		result.Status = model.SyncUnchanged
+		if len(result.Conflicts) > 0 {
+			result.Status = model.SyncConflict
+		}
+		return result
+	}
+
+	if err := repo.UpdateFields(ctx, current.ID, values, current.Version); err != nil {
+		return syncFailed(result, err.Error())
+	}
+	todo, err := repo.GetByID(ctx, current.ID)
+	if err != nil {
+		return syncFailed(result, err.Error())
+	}
+
+	result.Todo = todo
+	result.Status = model.SyncApplied
+	if len(result.Conflicts) > 0 {
+		result.Status = model.SyncMerged
+	}
+	return result
+}
+
+// stringBase 和 boolBase 在客户端没有提供基准值时返回 nil, 此时按整条记录判断
+func stringBase(v *string) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func boolBase(v *bool) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func (h *SyncHandler) publishSyncResult(result model.SyncResult) {
+	switch result.Status {
+	case model.SyncCreated:
+		h.hub.Publish(events.TodoCreated, result.Todo)
+	case model.SyncApplied, model.SyncMerged:
+		h.hub.Publish(events.TodoUpdated, result.Todo)
+	case model.SyncDeleted:
+		h.hub.Publish(events.TodoDeleted, deletedTodo(result.ID))
+	}
+}
+
+// parseSyncToken 解析客户端保存的同步令牌, 空令牌表示全量同步
+func parseSyncToken(token string) (uint64, bool, error) {
+	if token == "" {
+		return 0, true, nil
+	}
+	seq, err := strconv.ParseUint(token, 10, 64)
+	if err != nil {
+		return 0, false, errInvalidSyncToken
+	}
+	return seq, false, nil
+}
+
+func syncFailed(result model.SyncResult, message string) model.SyncResult {
+	result.Status = model.SyncError
+	result.Todo = nil
+	result.Conflicts = nil
+	result.Error = message
+	return result
+}
+
+func respondSyncError(c *gin.Context, err error) {
+	if errors.Is(err, errInvalidSyncToken) {
+		c.JSON(http.StatusBadRequest, model.Response{
+			Code:    400,
+			Data:    nil,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	respondServerError(c, err)
+}
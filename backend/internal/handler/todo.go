@@ -1,35 +1,49 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"todo-backend/internal/events"
 	"todo-backend/internal/model"
 	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type TodoHandler struct {
-	repo *repository.TodoRepository
+	repo      *repository.TodoRepository
+	uow       *repository.UnitOfWork
+	hub       *events.Hub
+	validator *validation.Validator
 }
 
-func NewTodoHandler() *TodoHandler {
+func NewTodoHandler(db *gorm.DB, hub *events.Hub, quota repository.Quota, v *validation.Validator) *TodoHandler {
 	return &TodoHandler{
-		repo: repository.NewTodoRepository(),
+		repo:      repository.NewTodoRepository(db).WithQuota(quota),
+		uow:       repository.NewUnitOfWork(db).WithQuota(quota),
+		hub:       hub,
+		validator: v,
 	}
 }
 
 func (h *TodoHandler) GetAllTodos(c *gin.Context) {
-	todos, err := h.repo.GetAll()
+	todos, err := h.repo.GetAll(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.Response{
-			Code:    500,
-			Data:    nil,
-			Message: err.Error(),
-		})
+		respondServerError(c, err)
 		return
 	}
+
+	etag := listETag(todos)
+	c.Header("ETag", etag)
+	if matchETag(c.GetHeader("If-None-Match"), etag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	c.JSON(http.StatusOK, model.Response{
 		Code:    0,
 		Data:    todos,
@@ -49,7 +63,11 @@ func (h *TodoHandler) GetTodoByID(c *gin.Context) {
 		return
 	}
 
-	todo, err := h.repo.GetByID(uint(id))
+	todo, err := h.repo.GetByID(c.Request.Context(), uint(id))
+	if err != nil && !h.repo.IsNotFound(err) {
+		respondServerError(c, err)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, model.Response{
 			Code:    404,
@@ -59,6 +77,13 @@ func (h *TodoHandler) GetTodoByID(c *gin.Context) {
 		return
 	}
 
+	etag := todoETag(todo)
+	c.Header("ETag", etag)
+	if matchETag(c.GetHeader("If-None-Match"), etag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	c.JSON(http.StatusOK, model.Response{
 		Code:    0,
 		Data:    todo,
@@ -68,30 +93,23 @@ func (h *TodoHandler) GetTodoByID(c *gin.Context) {
 
 func (h *TodoHandler) CreateTodo(c *gin.Context) {
 	var req model.CreateTodoRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.Response{
-			Code:    400,
-			Data:    nil,
-			Message: err.Error(),
-		})
+	if err := h.validator.BindJSON(c, &req); err != nil {
+		respondBindError(c, h.validator, err)
 		return
 	}
 
-	todo := &model.Todo{
-		Title:   req.Title,
-		Content: req.Content,
-	}
-
-	err := h.repo.Create(todo)
+	todo, err := h.createTodo(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.Response{
-			Code:    500,
+		status := writeStatus(err)
+		c.JSON(status, model.Response{
+			Code:    status,
 			Data:    nil,
 			Message: err.Error(),
 		})
 		return
 	}
 
+	c.Header("ETag", todoETag(todo))
 	c.JSON(http.StatusCreated, model.Response{
 		Code:    0,
 		Data:    todo,
@@ -112,32 +130,18 @@ func (h *TodoHandler) UpdateTodo(c *gin.Context) {
 	}
 
 	var req model.UpdateTodoRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.Response{
-			Code:    400,
-			Data:    nil,
-			Message: err.Error(),
-		})
+	if err := h.validator.BindJSON(c, &req); err != nil {
+		respondBindError(c, h.validator, err)
 		return
 	}
 
-	updates := &model.Todo{
-		Title:     req.Title,
-		Content:   req.Content,
-		Completed: req.Completed,
-	}
-
-	err = h.repo.Update(uint(id), updates)
+	todo, err := h.updateTodo(c.Request.Context(), uint(id), &req, c.GetHeader("If-Match"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.Response{
-			Code:    500,
-			Data:    nil,
-			Message: err.Error(),
-		})
+		h.respondMutationError(c, uint(id), err)
 		return
 	}
 
-	todo, _ := h.repo.GetByID(uint(id))
+	c.Header("ETag", todoETag(todo))
 	c.JSON(http.StatusOK, model.Response{
 		Code:    0,
 		Data:    todo,
@@ -157,13 +161,8 @@ func (h *TodoHandler) DeleteTodo(c *gin.Context) {
 		return
 	}
 
-	err = h.repo.Delete(uint(id))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.Response{
-			Code:    500,
-			Data:    nil,
-			Message: err.Error(),
-		})
+	if err := h.deleteTodo(c.Request.Context(), uint(id), c.GetHeader("If-Match")); err != nil {
+		h.respondMutationError(c, uint(id), err)
 		return
 	}
 
@@ -173,3 +172,40 @@ func (h *TodoHandler) DeleteTodo(c *gin.Context) {
 		Message: "success",
 	})
 }
+
+func (h *TodoHandler) respondMutationError(c *gin.Context, id uint, err error) {
+	switch {
+	case errors.Is(err, repository.ErrVersionConflict):
+		todo, _ := h.repo.GetByID(c.Request.Context(), id)
+		if todo != nil {
+			c.Header("ETag", todoETag(todo))
+		}
+		c.JSON(http.StatusPreconditionFailed, model.Response{
+			Code:    412,
+			Data:    todo,
+			Message: "todo has been modified",
+		})
+	case h.repo.IsNotFound(err):
+		c.JSON(http.StatusNotFound, model.Response{
+			Code:    404,
+			Data:    nil,
+			Message: "todo not found",
+		})
+	default:
+		status := writeStatus(err)
+		c.JSON(status, model.Response{
+			Code:    status,
+			Data:    nil,
+			Message: err.Error(),
+		})
+	}
+}
+
+func respondBindError(c *gin.Context, v *validation.Validator, err error) {
+	status := validation.StatusCode(err)
+	c.JSON(status, model.Response{
+		Code:    status,
+		Data:    nil,
+		Message: v.Message(err, validation.Locale(c.GetHeader("Accept-Language"))),
+	})
+}
@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
+	"todo-backend/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const deliveryLogLimit = 100
+
+type WebhookHandler struct {
+	repo       *repository.WebhookRepository
+	uow        *repository.UnitOfWork
+	dispatcher *webhook.Dispatcher
+	validator  *validation.Validator
+}
+
+func NewWebhookHandler(db *gorm.DB, dispatcher *webhook.Dispatcher, v *validation.Validator) *WebhookHandler {
+	return &WebhookHandler{
+		repo:       repository.NewWebhookRepository(db),
+		uow:        repository.NewUnitOfWork(db),
+		dispatcher: dispatcher,
+		validator:  v,
+	}
+}
+
+func (h *WebhookHandler) GetAllWebhooks(c *gin.Context) {
+	webhooks, err := h.repo.GetAll(c.Request.Context())
+	if err != nil {
+		respondServerError(c, err)
+		return
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Code:    0,
+		Data:    webhooks,
+		Message: "success",
+	})
+}
+
+func (h *WebhookHandler) GetWebhookByID(c *gin.Context) {
+	hook, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	hook.Secret = ""
+	c.JSON(http.StatusOK, model.Response{
+		Code:    0,
+		Data:    hook,
+		Message: "success",
+	})
+}
+
+// CreateWebhook 创建订阅, 未提供 secret 时自动生成; secret 只在创建时返回一次
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req model.CreateWebhookRequest
+	if err := h.validator.BindJSON(c, &req); err != nil {
+		respondBindError(c, h.validator, err)
+		return
+	}
+	if !isHTTPURL(req.URL) {
+		respondInvalidWebhookURL(c)
+		return
+	}
+
+	hook := &model.Webhook{
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: req.Events,
+		Active: true,
+	}
+	if hook.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		hook.Secret = secret
+	}
+
+	ctx := c.Request.Context()
+	err := h.uow.Do(ctx, func(tx *repository.UnitOfWork) error {
+		if err := tx.Webhooks().Create(ctx, hook); err != nil {
+			return err
+		}
+		// Active 有数据库默认值, 需要单独写入 false
+		if req.Active != nil && !*req.Active {
+			hook.Active = false
+			return tx.Webhooks().Save(ctx, hook)
+		}
+		return nil
+	})
+	if err != nil {
+		respondServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, model.Response{
+		Code:    0,
+		Data:    hook,
+		Message: "success",
+	})
+}
+
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	hook, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateWebhookRequest
+	if err := h.validator.BindJSON(c, &req); err != nil {
+		respondBindError(c, h.validator, err)
+		return
+	}
+
+	if req.URL != "" {
+		if !isHTTPURL(req.URL) {
+			respondInvalidWebhookURL(c)
+			return
+		}
+		hook.URL = req.URL
+	}
+	if req.Secret != "" {
+		hook.Secret = req.Secret
+	}
+	if req.Events != nil {
+		hook.Events = *req.Events
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+
+	if err := h.repo.Save(c.Request.Context(), hook); err != nil {
+		respondServerError(c, err)
+		return
+	}
+
+	hook.Secret = ""
+	c.JSON(http.StatusOK, model.Response{
+		Code:    0,
+		Data:    hook,
+		Message: "success",
+	})
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	hook, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), hook.ID); err != nil {
+		respondServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Code:    0,
+		Data:    nil,
+		Message: "success",
+	})
+}
+
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	hook, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.repo.GetDeliveries(c.Request.Context(), hook.ID, deliveryLogLimit)
+	if err != nil {
+		respondServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Code:    0,
+		Data:    deliveries,
+		Message: "success",
+	})
+}
+
+// SendTestEvent 投递一个 webhook.test 事件, 投递结果可在投递记录中查看
+func (h *WebhookHandler) SendTestEvent(c *gin.Context) {
+	hook, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.dispatcher.SendTest(c.Request.Context(), hook)
+	if err != nil {
+		respondServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, model.Response{
+		Code:    0,
+		Data:    delivery,
+		Message: "success",
+	})
+}
+
+func (h *WebhookHandler) lookup(c *gin.Context) (*model.Webhook, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.Response{
+			Code:    400,
+			Data:    nil,
+			Message: "invalid id",
+		})
+		return nil, false
+	}
+
+	hook, err := h.repo.GetByID(c.Request.Context(), uint(id))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		respondServerError(c, err)
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.Response{
+			Code:    404,
+			Data:    nil,
+			Message: "webhook not found",
+		})
+		return nil, false
+	}
+	return hook, true
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func respondInvalidWebhookURL(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, model.Response{
+		Code:    400,
+		Data:    nil,
+		Message: "url must be an http or https URL",
+	})
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
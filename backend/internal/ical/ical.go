@@ -0,0 +1,197 @@
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"todo-backend/internal/model"
+)
+
+const (
+	ProductID = "-//todo-backend//Todo Calendar//EN"
+
+	maxLineOctets = 75
+	dateLayout    = "20060102"
+	utcLayout     = "20060102T150405Z"
+)
+
+type Options struct {
+	// Domain 是 UID 的域名部分, 修改后客户端会把所有条目视为新条目
+	Domain string
+	Name   string
+	// Events 为 true 时, 为有截止日期的 Todo 额外生成 VEVENT, 供只显示事件的日历应用使用
+	Events bool
+}
+
+// UID 只由 ID 和域名决定, 标题或状态变化不会改变 UID
+func UID(id uint, domain string) string {
+	return fmt.Sprintf("todo-%d@%s", id, domain)
+}
+
+func eventUID(todo *model.Todo, domain string) string {
+	if todo.UID != nil {
+		return "due-" + *todo.UID
+	}
+	return fmt.Sprintf("todo-%d-due@%s", todo.ID, domain)
+}
+
+// Escape 按 RFC 5545 3.3.11 转义 TEXT 值
+func Escape(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// Fold 按 RFC 5545 3.1 折行: 每行不超过 75 个字节, 续行以空格开头, 不拆分 UTF-8 字符
+func Fold(line string) string {
+	if len(line) <= maxLineOctets {
+		return line
+	}
+
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// 续行开头的空格占用一个字节
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+// Writer 输出以 CRLF 结尾、自动折行的内容行, 第一次写入失败后忽略后续写入
+type Writer struct {
+	w   io.Writer
+	err error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (w *Writer) Line(name, value string) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = io.WriteString(w.w, Fold(name+":"+value)+"\r\n")
+}
+
+func (w *Writer) Text(name, value string) {
+	w.Line(name, Escape(value))
+}
+
+func (w *Writer) Time(name string, t time.Time) {
+	w.Line(name, t.UTC().Format(utcLayout))
+}
+
+// DateOrTime 在本地零点时输出 DATE 值 (全天), 否则输出 UTC 时间
+func (w *Writer) DateOrTime(name string, t time.Time) {
+	local := t.In(time.Local)
+	if local.Hour() == 0 && local.Minute() == 0 && local.Second() == 0 {
+		w.Line(name+";VALUE=DATE", local.Format(dateLayout))
+		return
+	}
+	w.Time(name, t)
+}
+
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func (w *Writer) BeginCalendar(name string) {
+	w.Line("BEGIN", "VCALENDAR")
+	w.Line("VERSION", "2.0")
+	w.Text("PRODID", ProductID)
+	w.Line("CALSCALE", "GREGORIAN")
+	if name != "" {
+		w.Line("METHOD", "PUBLISH")
+		w.Text("X-WR-CALNAME", name)
+	}
+}
+
+func (w *Writer) EndCalendar() {
+	w.Line("END", "VCALENDAR")
+}
+
+// Todo 输出一个 VTODO 组件
+func (w *Writer) Todo(todo *model.Todo, domain string) {
+	w.Line("BEGIN", "VTODO")
+	w.Text("UID", TodoUID(todo, domain))
+	w.Time("DTSTAMP", todo.UpdatedAt)
+	w.Time("CREATED", todo.CreatedAt)
+	w.Time("LAST-MODIFIED", todo.UpdatedAt)
+	w.Line("SEQUENCE", fmt.Sprint(sequence(todo)))
+	w.Text("SUMMARY", todo.Title)
+	if todo.Content != "" {
+		w.Text("DESCRIPTION", todo.Content)
+	}
+	if todo.DueAt != nil {
+		w.DateOrTime("DUE", *todo.DueAt)
+	}
+	if todo.Completed {
+		w.Line("STATUS", "COMPLETED")
+		w.Line("PERCENT-COMPLETE", "100")
+		if todo.CompletedAt != nil {
+			w.Time("COMPLETED", *todo.CompletedAt)
+		}
+	} else {
+		w.Line("STATUS", "NEEDS-ACTION")
+	}
+	w.Line("END", "VTODO")
+}
+
+// Event 为有截止日期的 Todo 输出一个 VEVENT, UID 与 VTODO 不同
+func (w *Writer) Event(todo *model.Todo, domain string) {
+	if todo.DueAt == nil {
+		return
+	}
+
+	w.Line("BEGIN", "VEVENT")
+	w.Text("UID", eventUID(todo, domain))
+	w.Time("DTSTAMP", todo.UpdatedAt)
+	w.Time("CREATED", todo.CreatedAt)
+	w.Time("LAST-MODIFIED", todo.UpdatedAt)
+	w.Line("SEQUENCE", fmt.Sprint(sequence(todo)))
+	w.DateOrTime("DTSTART", *todo.DueAt)
+	w.Text("SUMMARY", todo.Title)
+	if todo.Content != "" {
+		w.Text("DESCRIPTION", todo.Content)
+	}
+	w.Line("TRANSP", "TRANSPARENT")
+	w.Line("END", "VEVENT")
+}
+
+func sequence(todo *model.Todo) uint {
+	if todo.Version == 0 {
+		return 0
+	}
+	return todo.Version - 1
+}
+
+// WriteCalendar 把 Todo 列表输出为完整的 VCALENDAR
+func WriteCalendar(out io.Writer, todos []model.Todo, opts Options) error {
+	w := NewWriter(out)
+	w.BeginCalendar(opts.Name)
+	for i := range todos {
+		w.Todo(&todos[i], opts.Domain)
+		if opts.Events {
+			w.Event(&todos[i], opts.Domain)
+		}
+	}
+	w.EndCalendar()
+	return w.Err()
+}
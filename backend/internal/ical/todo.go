@@ -0,0 +1,96 @@
+package ical
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"todo-backend/internal/model"
+)
+
+// VTodo 是客户端上传的 VTODO 中能映射到 Todo 的字段
+type VTodo struct {
+	UID         string
+	Summary     string
+	Description string
+	Completed   bool
+	CompletedAt *time.Time
+	Due         *time.Time
+}
+
+// TodoUID 返回 Todo 的 UID: 由 CalDAV 客户端创建的 Todo 使用客户端的 UID, 其余由 ID 生成
+func TodoUID(todo *model.Todo, domain string) string {
+	if todo.UID != nil && *todo.UID != "" {
+		return *todo.UID
+	}
+	return UID(todo.ID, domain)
+}
+
+// DecodeTodo 从 VCALENDAR 中取出唯一的 VTODO
+func DecodeTodo(r io.Reader) (*VTodo, error) {
+	cal, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	if cal.Name != "VCALENDAR" {
+		return nil, errors.New("expected VCALENDAR")
+	}
+
+	var comp *Component
+	for _, child := range cal.Children {
+		switch child.Name {
+		case "VTODO":
+			if comp != nil {
+				return nil, errors.New("calendar object must contain a single VTODO")
+			}
+			comp = child
+		case "VTIMEZONE":
+		default:
+			return nil, errors.New("unsupported component " + child.Name)
+		}
+	}
+	if comp == nil {
+		return nil, errors.New("calendar object must contain a VTODO")
+	}
+
+	todo := &VTodo{
+		UID:         strings.TrimSpace(comp.Text("UID")),
+		Summary:     comp.Text("SUMMARY"),
+		Description: comp.Text("DESCRIPTION"),
+	}
+	if todo.UID == "" {
+		return nil, errors.New("VTODO must have a UID")
+	}
+
+	status := strings.ToUpper(comp.Text("STATUS"))
+	todo.Completed = status == "COMPLETED" || comp.Text("PERCENT-COMPLETE") == "100"
+	if p := comp.Prop("COMPLETED"); p != nil {
+		t, err := p.Time()
+		if err != nil {
+			return nil, errors.New("invalid COMPLETED: " + p.Value)
+		}
+		todo.CompletedAt = &t
+		// 部分客户端只写 COMPLETED 不写 STATUS
+		if status == "" {
+			todo.Completed = true
+		}
+	}
+	if p := comp.Prop("DUE"); p != nil {
+		t, err := p.Time()
+		if err != nil {
+			return nil, errors.New("invalid DUE: " + p.Value)
+		}
+		todo.Due = &t
+	}
+	return todo, nil
+}
+
+// WriteTodoObject 输出只包含一个 VTODO 的日历对象, 用于 CalDAV 资源
+func WriteTodoObject(out io.Writer, todo *model.Todo, domain string) error {
+	w := NewWriter(out)
+	w.BeginCalendar("")
+	w.Todo(todo, domain)
+	w.EndCalendar()
+	return w.Err()
+}
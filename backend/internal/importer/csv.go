@@ -0,0 +1,246 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvAliases 把常见的列名映射为标准列名
+var csvAliases = map[string]string{
+	"title":        "title",
+	"name":         "title",
+	"task":         "title",
+	"content":      "content",
+	"description":  "content",
+	"notes":        "content",
+	"completed":    "completed",
+	"done":         "completed",
+	"status":       "completed",
+	"created_at":   "created_at",
+	"created":      "created_at",
+	"completed_at": "completed_at",
+	"due_at":       "due_at",
+	"due":          "due_at",
+	"due_date":     "due_at",
+	"labels":       "labels",
+	"tags":         "labels",
+}
+
+type csvTable struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVTable(r io.Reader, aliases map[string]string) (*csvTable, error) {
+	reader := csv.NewReader(skipBOM(r))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if aliases != nil {
+			name = aliases[name]
+		}
+		if _, exists := columns[name]; name != "" && !exists {
+			columns[name] = i
+		}
+	}
+	return &csvTable{reader: reader, columns: columns}, nil
+}
+
+// next 返回下一行的字段值和行号, 结束时返回 io.EOF
+func (t *csvTable) next() (func(column string) string, int, error) {
+	record, err := t.reader.Read()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	line, _ := t.reader.FieldPos(0)
+	get := func(column string) string {
+		if i, ok := t.columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	return get, line, nil
+}
+
+func parseCSV(r io.Reader) ([]Record, error) {
+	table, err := newCSVTable(r, csvAliases)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := table.columns["title"]; !ok {
+		return nil, errors.New("csv file must have a title column")
+	}
+
+	var records []Record
+	for {
+		get, line, err := table.next()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			records = append(records, Record{Row: parseErr.Line, Err: err})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record := Record{
+			Row:     line,
+			Title:   get("title"),
+			Content: get("content"),
+			Labels:  splitLabels(get("labels")),
+		}
+		record.Err = firstError(
+			assignBool(&record.Completed, get("completed")),
+			assignTime(&record.CreatedAt, get("created_at")),
+			assignTime(&record.CompletedAt, get("completed_at")),
+			assignTime(&record.DueAt, get("due_at")),
+		)
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseTodoist 解析 Todoist 导出的 CSV 模板: INDENT 大于 1 的任务作为上一个任务的检查项,
+// note 追加到上一个任务的内容, section 名称作为标签
+func parseTodoist(r io.Reader) ([]Record, error) {
+	table, err := newCSVTable(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := table.columns["content"]; !ok {
+		return nil, errors.New("todoist export must have a CONTENT column")
+	}
+
+	var (
+		records []Record
+		section string
+	)
+	for {
+		get, line, err := table.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		content := get("content")
+		switch strings.ToLower(get("type")) {
+		case "section":
+			section = content
+			continue
+		case "note":
+			if len(records) > 0 && content != "" {
+				last := &records[len(records)-1]
+				last.Content = strings.TrimSpace(last.Content + "\n\n" + content)
+			}
+			continue
+		case "task":
+		default:
+			continue
+		}
+
+		title, labels := splitTodoistLabels(content)
+		indent, _ := strconv.Atoi(get("indent"))
+		if indent > 1 && len(records) > 0 {
+			last := &records[len(records)-1]
+			last.Checklist = append(last.Checklist, ChecklistItem{Title: title})
+			continue
+		}
+
+		record := Record{
+			Row:     line,
+			Title:   title,
+			Content: get("description"),
+			Labels:  labels,
+		}
+		if section != "" {
+			record.Labels = append([]string{section}, record.Labels...)
+		}
+		// Todoist 的优先级 4 对应界面上的 p1
+		if priority, _ := strconv.Atoi(get("priority")); priority > 1 && priority <= 4 {
+			record.Labels = append(record.Labels, "p"+strconv.Itoa(5-priority))
+		}
+
+		// 重复任务等自然语言日期无法转换, 保留在内容中
+		if date := get("date"); date != "" {
+			if due, err := parseTime(date); err == nil {
+				record.DueAt = due
+			} else {
+				record.Content = strings.TrimSpace(record.Content + "\n\nDue: " + date)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// splitTodoistLabels 把标题中的 @label 拆分出来
+func splitTodoistLabels(content string) (string, []string) {
+	var (
+		words  []string
+		labels []string
+	)
+	for _, word := range strings.Fields(content) {
+		if len(word) > 1 && strings.HasPrefix(word, "@") {
+			labels = append(labels, word[1:])
+			continue
+		}
+		words = append(words, word)
+	}
+	return strings.Join(words, " "), labels
+}
+
+func skipBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if head, err := br.Peek(3); err == nil && string(head) == "\xef\xbb\xbf" {
+		br.Discard(3)
+	}
+	return br
+}
+
+func assignBool(dst *bool, value string) error {
+	b, err := parseBool(value)
+	if err != nil {
+		return err
+	}
+	*dst = b
+	return nil
+}
+
+func assignTime(dst **time.Time, value string) error {
+	t, err := parseTime(value)
+	if err != nil {
+		return err
+	}
+	*dst = t
+	return nil
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"todo-backend/internal/model"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatJSON    = "json"
+	FormatTodoTxt = "todotxt"
+	FormatTodoist = "todoist"
+	FormatTrello  = "trello"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown import format")
+	ErrTooManyRows   = errors.New("too many rows")
+)
+
+// ChecklistItem 是来源数据中的子任务或检查项
+type ChecklistItem struct {
+	Title     string `json:"title"`
+	Completed bool   `json:"completed"`
+}
+
+// Record 是从来源文件解析出的一行数据, Err 不为空表示该行无法解析
+type Record struct {
+	Row         int
+	Title       string
+	Content     string
+	Completed   bool
+	CreatedAt   *time.Time
+	CompletedAt *time.Time
+	DueAt       *time.Time
+	Labels      []string
+	Checklist   []ChecklistItem
+	Skip        string
+	Err         error
+}
+
+// Todo 把 Record 映射为 Todo: 检查项和标签没有对应的字段, 追加到内容末尾
+func (r *Record) Todo() *model.Todo {
+	parts := []string{}
+	if content := strings.TrimSpace(r.Content); content != "" {
+		parts = append(parts, content)
+	}
+
+	if len(r.Checklist) > 0 {
+		lines := make([]string, 0, len(r.Checklist))
+		for _, item := range r.Checklist {
+			mark := " "
+			if item.Completed {
+				mark = "x"
+			}
+			lines = append(lines, "- ["+mark+"] "+item.Title)
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+
+	if len(r.Labels) > 0 {
+		parts = append(parts, "Labels: "+strings.Join(r.Labels, ", "))
+	}
+
+	todo := &model.Todo{
+		Title:       r.Title,
+		Content:     strings.Join(parts, "\n\n"),
+		Completed:   r.Completed,
+		CompletedAt: r.CompletedAt,
+		DueAt:       r.DueAt,
+	}
+	if r.CreatedAt != nil {
+		todo.CreatedAt = *r.CreatedAt
+	}
+	if !todo.Completed {
+		todo.CompletedAt = nil
+	}
+	return todo
+}
+
+// Parse 按 format 解析来源数据, 最多返回 maxRows 行
+func Parse(format string, r io.Reader, maxRows int) ([]Record, error) {
+	var (
+		records []Record
+		err     error
+	)
+
+	switch format {
+	case FormatCSV:
+		records, err = parseCSV(r)
+	case FormatJSON:
+		records, err = parseJSON(r)
+	case FormatTodoTxt:
+		records, err = parseTodoTxt(r)
+	case FormatTodoist:
+		records, err = parseTodoist(r)
+	case FormatTrello:
+		records, err = parseTrello(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if maxRows > 0 && len(records) > maxRows {
+		return nil, ErrTooManyRows
+	}
+	return records, nil
+}
+
+// Detect 根据文件名和文件开头的内容猜测格式
+func Detect(filename string, head []byte) string {
+	head = bytes.TrimLeft(head, "\ufeff \t\r\n")
+
+	switch {
+	case bytes.HasPrefix(head, []byte("{")) && bytes.Contains(head, []byte(`"cards"`)):
+		return FormatTrello
+	case bytes.HasPrefix(head, []byte("{")) || bytes.HasPrefix(head, []byte("[")):
+		return FormatJSON
+	case bytes.HasPrefix(bytes.ToUpper(head), []byte("TYPE,CONTENT")):
+		return FormatTodoist
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	case ".txt":
+		return FormatTodoTxt
+	}
+	return ""
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func parseTime(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, errors.New("invalid date: " + value)
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "false", "0", "no", "n":
+		return false, nil
+	case "true", "1", "yes", "y", "x", "done", "completed":
+		return true, nil
+	}
+	return false, errors.New("invalid boolean: " + value)
+}
+
+func splitLabels(value string) []string {
+	var labels []string
+	for _, label := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,175 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type jsonTodo struct {
+	Title       string          `json:"title"`
+	Content     string          `json:"content"`
+	Completed   bool            `json:"completed"`
+	CreatedAt   string          `json:"created_at"`
+	CompletedAt string          `json:"completed_at"`
+	DueAt       string          `json:"due_at"`
+	Labels      []string        `json:"labels"`
+	Checklist   []ChecklistItem `json:"checklist"`
+}
+
+// parseJSON 接受 Todo 数组, 或者 {"data": [...]} 形式的接口响应
+func parseJSON(r io.Reader) ([]Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		var envelope struct {
+			Data []json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &envelope); err != nil || envelope.Data == nil {
+			return nil, errors.New("json import must be an array of todos")
+		}
+		items = envelope.Data
+	}
+
+	records := make([]Record, 0, len(items))
+	for i, item := range items {
+		record := Record{Row: i + 1}
+
+		var todo jsonTodo
+		if err := json.Unmarshal(item, &todo); err != nil {
+			record.Err = err
+			records = append(records, record)
+			continue
+		}
+
+		record.Title = strings.TrimSpace(todo.Title)
+		record.Content = todo.Content
+		record.Completed = todo.Completed
+		record.Labels = todo.Labels
+		record.Checklist = todo.Checklist
+		record.Err = firstError(
+			assignTime(&record.CreatedAt, todo.CreatedAt),
+			assignTime(&record.CompletedAt, todo.CompletedAt),
+			assignTime(&record.DueAt, todo.DueAt),
+		)
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+type trelloBoard struct {
+	Cards []struct {
+		ID           string   `json:"id"`
+		Name         string   `json:"name"`
+		Desc         string   `json:"desc"`
+		Closed       bool     `json:"closed"`
+		Due          string   `json:"due"`
+		DueComplete  bool     `json:"dueComplete"`
+		IDList       string   `json:"idList"`
+		IDLabels     []string `json:"idLabels"`
+		IDChecklists []string `json:"idChecklists"`
+	} `json:"cards"`
+	Labels []struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	} `json:"labels"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Checklists []struct {
+		ID         string `json:"id"`
+		Name       string `json:"name"`
+		CheckItems []struct {
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+}
+
+// parseTrello 解析 Trello 看板的 JSON 导出: 卡片标签和所在列表作为标签,
+// 检查项作为检查清单, 已归档的卡片和列表会被跳过
+func parseTrello(r io.Reader) ([]Record, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, errors.New("invalid trello export: " + err.Error())
+	}
+
+	labels := make(map[string]string)
+	for _, label := range board.Labels {
+		name := label.Name
+		if name == "" {
+			name = label.Color
+		}
+		labels[label.ID] = name
+	}
+
+	lists := make(map[string]string)
+	closedLists := make(map[string]bool)
+	for _, list := range board.Lists {
+		lists[list.ID] = list.Name
+		closedLists[list.ID] = list.Closed
+	}
+
+	checklists := make(map[string][]ChecklistItem)
+	for _, checklist := range board.Checklists {
+		items := make([]ChecklistItem, 0, len(checklist.CheckItems))
+		for _, item := range checklist.CheckItems {
+			items = append(items, ChecklistItem{Title: item.Name, Completed: item.State == "complete"})
+		}
+		checklists[checklist.ID] = items
+	}
+
+	records := make([]Record, 0, len(board.Cards))
+	for i, card := range board.Cards {
+		record := Record{
+			Row:       i + 1,
+			Title:     strings.TrimSpace(card.Name),
+			Content:   card.Desc,
+			Completed: card.DueComplete,
+			CreatedAt: trelloCreatedAt(card.ID),
+		}
+
+		if card.Closed || closedLists[card.IDList] {
+			record.Skip = "archived"
+		}
+		if list := lists[card.IDList]; list != "" {
+			record.Labels = append(record.Labels, list)
+		}
+		for _, id := range card.IDLabels {
+			if name := labels[id]; name != "" {
+				record.Labels = append(record.Labels, name)
+			}
+		}
+		for _, id := range card.IDChecklists {
+			record.Checklist = append(record.Checklist, checklists[id]...)
+		}
+		record.Err = assignTime(&record.DueAt, card.Due)
+
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// trelloCreatedAt 从 Trello ID 前 8 位十六进制的时间戳取出创建时间
+func trelloCreatedAt(id string) *time.Time {
+	if len(id) < 8 {
+		return nil
+	}
+	seconds, err := strconv.ParseInt(id[:8], 16, 64)
+	if err != nil {
+		return nil
+	}
+	t := time.Unix(seconds, 0)
+	return &t
+}
@@ -0,0 +1,87 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	todoTxtDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	todoTxtPriority = regexp.MustCompile(`^\([A-Z]\)$`)
+)
+
+// parseTodoTxt 解析 todo.txt: 支持完成标记、优先级、完成/创建日期、+project、@context 和 due:
+func parseTodoTxt(r io.Reader) ([]Record, error) {
+	var records []Record
+
+	scanner := bufio.NewScanner(skipBOM(r))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		records = append(records, parseTodoTxtLine(line, text))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func parseTodoTxtLine(line int, text string) Record {
+	record := Record{Row: line}
+	fields := strings.Fields(text)
+
+	if len(fields) > 0 && fields[0] == "x" {
+		record.Completed = true
+		fields = fields[1:]
+	}
+	if len(fields) > 0 && todoTxtPriority.MatchString(fields[0]) {
+		record.Labels = append(record.Labels, "priority:"+fields[0][1:2])
+		fields = fields[1:]
+	}
+
+	// 完成的任务可以有 "完成日期 创建日期", 未完成的任务只有创建日期
+	var dates []string
+	for len(fields) > 0 && len(dates) < 2 && todoTxtDate.MatchString(fields[0]) {
+		dates = append(dates, fields[0])
+		fields = fields[1:]
+	}
+	switch {
+	case record.Completed && len(dates) == 2:
+		record.Err = firstError(assignTime(&record.CompletedAt, dates[0]), assignTime(&record.CreatedAt, dates[1]))
+	case record.Completed && len(dates) == 1:
+		record.Err = assignTime(&record.CompletedAt, dates[0])
+	case len(dates) == 1:
+		record.Err = assignTime(&record.CreatedAt, dates[0])
+	case len(dates) == 2:
+		// 未完成的任务不应该有两个日期, 第二个日期属于标题
+		record.Err = assignTime(&record.CreatedAt, dates[0])
+		fields = append([]string{dates[1]}, fields...)
+	}
+
+	var words []string
+	for _, field := range fields {
+		switch {
+		case len(field) > 1 && (field[0] == '+' || field[0] == '@'):
+			record.Labels = append(record.Labels, field[1:])
+			words = append(words, field)
+		case strings.HasPrefix(field, "due:"):
+			if err := assignTime(&record.DueAt, strings.TrimPrefix(field, "due:")); err != nil && record.Err == nil {
+				record.Err = err
+			}
+		default:
+			words = append(words, field)
+		}
+	}
+
+	record.Title = strings.Join(words, " ")
+	if record.Title == "" && record.Err == nil {
+		record.Err = errors.New("task text is empty")
+	}
+	return record
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	corsMethods       = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsHeaders       = "Content-Type, Authorization, Accept-Language, If-Match, If-None-Match, Idempotency-Key"
+	corsExposeHeaders = "ETag"
+)
+
+// CORSOptions 配置跨域策略. AllowedOrigins 中的每一项可以是
+//   - "*": 允许所有来源, 此时不会发送 Access-Control-Allow-Credentials
+//   - 完整的来源, 例如 "https://todo.example.com"
+//   - 通配子域名, 例如 "https://*.example.com", 匹配任意层级的子域名但不包括 example.com 本身
+type CORSOptions struct {
+	AllowedOrigins   []string
+	AllowCredentials bool
+	// 预检结果的缓存时间, 为 0 时不发送 Access-Control-Max-Age
+	MaxAge time.Duration
+}
+
+type originMatcher struct {
+	any      bool
+	exact    map[string]bool
+	wildcard [][2]string
+}
+
+func newOriginMatcher(origins []string) *originMatcher {
+	m := &originMatcher{exact: make(map[string]bool)}
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))
+		switch {
+		case origin == "":
+		case origin == "*":
+			m.any = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "*")
+			m.wildcard = append(m.wildcard, [2]string{scheme, host})
+		default:
+			m.exact[origin] = true
+		}
+	}
+	return m
+}
+
+func (m *originMatcher) allows(origin string) bool {
+	origin = strings.ToLower(origin)
+	if m.any || m.exact[origin] {
+		return true
+	}
+	for _, w := range m.wildcard {
+		sub, ok := strings.CutPrefix(origin, w[0])
+		if !ok {
+			continue
+		}
+		sub, ok = strings.CutSuffix(sub, w[1])
+		// 子域名部分不能为空, 也不能包含路径或端口
+		if ok && sub != "" && !strings.ContainsAny(sub, "/:@") {
+			return true
+		}
+	}
+	return false
+}
+
+// WebSocketOrigin 返回 websocket.Upgrader 的 CheckOrigin. 浏览器发起 WebSocket 连接时总会带上 Cookie,
+// 且不受 CORS 限制, 因此只接受没有 Origin 头的非浏览器客户端、与请求同源的页面, 以及 origins 中明确列出的来源;
+// "*" 对 WebSocket 不生效, 避免任意网站以用户身份建立连接
+func WebSocketOrigin(origins []string) func(r *http.Request) bool {
+	matcher := newOriginMatcher(origins)
+	matcher.any = false
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		if strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		return matcher.allows(origin)
+	}
+}
+
+// CORS 按配置的来源返回跨域响应头. 只拦截浏览器的预检请求, 其余 OPTIONS 请求 (例如 CalDAV) 交给路由处理
+func CORS(opts CORSOptions) gin.HandlerFunc {
+	matcher := newOriginMatcher(opts.AllowedOrigins)
+	// 允许所有来源时不能携带凭据, 否则任何网站都能以用户身份调用接口
+	credentials := opts.AllowCredentials && !matcher.any
+	maxAge := ""
+	if opts.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		// 响应内容取决于 Origin, 共享缓存需要按 Origin 区分
+		if !matcher.any {
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		if !matcher.allows(origin) {
+			if preflight {
+				c.AbortWithStatusJSON(http.StatusForbidden, model.Response{
+					Code:    403,
+					Data:    nil,
+					Message: "origin not allowed",
+				})
+				return
+			}
+			// 不带跨域头, 浏览器会拒绝脚本读取响应
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		if matcher.any {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if credentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		header.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+
+		if preflight {
+			header.Set("Access-Control-Allow-Methods", corsMethods)
+			header.Set("Access-Control-Allow-Headers", corsHeaders)
+			if maxAge != "" {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
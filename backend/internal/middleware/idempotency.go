@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxIdempotencyKeyLength = 255
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyOptions 配置幂等键的保存时长和计算请求摘要时的请求体上限
+type IdempotencyOptions struct {
+	TTL time.Duration
+	// MaxBodyBytes 是读取请求体的默认上限, 应与处理器的校验规则一致
+	MaxBodyBytes int64
+	// RouteMaxBytes 按路由 (c.FullPath()) 设置请求体上限, 例如导入接口允许更大的文件.
+	// 没有设置的路由使用 MaxBodyBytes
+	RouteMaxBytes map[string]int64
+}
+
+// Idempotency 为带 Idempotency-Key 头的 POST 请求保存首次响应, 在 TTL 内重放给相同请求.
+// 过期的键由 CleanupIdempotencyKeys 定期删除
+func Idempotency(repo *repository.IdempotencyRepository, opts IdempotencyOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			abort(c, http.StatusBadRequest, "Idempotency-Key must not exceed 255 characters")
+			return
+		}
+
+		limit, ok := opts.RouteMaxBytes[c.FullPath()]
+		if !ok {
+			limit = opts.MaxBodyBytes
+		}
+		body, err := validation.ReadBodyLimit(c.Writer, c.Request, limit)
+		if err != nil && err != validation.ErrEmptyBody {
+			locale := validation.Locale(c.GetHeader("Accept-Language"))
+			abort(c, validation.StatusCode(err), validation.BodyMessage(err, locale, limit))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.Sum256(body)
+		record := &model.IdempotencyKey{
+			Key:         key,
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			RequestHash: hex.EncodeToString(sum[:]),
+			ExpiresAt:   time.Now().Add(opts.TTL),
+		}
+
+		ctx := c.Request.Context()
+		reserved, err := repo.Reserve(ctx, record)
+		if err != nil {
+			abort(c, queryErrorStatus(err), err.Error())
+			return
+		}
+
+		if !reserved {
+			existing, err := repo.Find(ctx, record.Key, record.Method, record.Path)
+			switch {
+			case err != nil || existing.StatusCode == 0:
+				abort(c, http.StatusConflict, "a request with this Idempotency-Key is still being processed")
+			case existing.RequestHash != record.RequestHash:
+				abort(c, http.StatusUnprocessableEntity, "Idempotency-Key has already been used with a different request payload")
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.StatusCode, existing.ContentType, existing.Response)
+				c.Abort()
+			}
+			return
+		}
+
+		// 处理器 panic 时同样释放幂等键, 然后继续向上抛出交给 Recovery
+		defer func() {
+			if p := recover(); p != nil {
+				releaseIdempotencyKey(repo, record.ID)
+				panic(p)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// 服务端错误不缓存, 允许客户端用同一个键重试
+		if recorder.Status() >= http.StatusInternalServerError {
+			releaseIdempotencyKey(repo, record.ID)
+			return
+		}
+
+		// 请求取消或超时后仍要保存结果, 否则重试会一直得到 409
+		if err := repo.Complete(context.Background(), record.ID, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			log.Printf("Failed to store idempotent response: %v", err)
+		}
+	}
+}
+
+// releaseIdempotencyKey 删除未完成的幂等键, 使用不会被请求取消的 context
+func releaseIdempotencyKey(repo *repository.IdempotencyRepository, id uint) {
+	if err := repo.Delete(context.Background(), id); err != nil {
+		log.Printf("Failed to release idempotency key: %v", err)
+	}
+}
+
+// CleanupIdempotencyKeys 在后台每隔 interval 删除过期的幂等键, ctx 取消时停止
+func CleanupIdempotencyKeys(ctx context.Context, repo *repository.IdempotencyRepository, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := repo.DeleteExpired(ctx, time.Now()); err != nil {
+					log.Printf("Failed to delete expired idempotency keys: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func abort(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, model.Response{
+		Code:    status,
+		Data:    nil,
+		Message: message,
+	})
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logger 向 out 输出与 gin.Logger 格式相同的访问日志, 但把查询参数中 redact 列出的值替换为 REDACTED,
+// 避免日历订阅源的 token 等凭据写入日志
+func Logger(out io.Writer, redact ...string) gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Output: out,
+		Formatter: func(param gin.LogFormatterParams) string {
+			param.Path = redactQuery(param.Path, redact)
+
+			var statusColor, methodColor, resetColor string
+			if param.IsOutputColor() {
+				statusColor = param.StatusCodeColor()
+				methodColor = param.MethodColor()
+				resetColor = param.ResetColor()
+			}
+			if param.Latency > time.Minute {
+				param.Latency = param.Latency.Truncate(time.Second)
+			}
+			return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+				param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				statusColor, param.StatusCode, resetColor,
+				param.Latency,
+				param.ClientIP,
+				methodColor, param.Method, resetColor,
+				param.Path,
+				param.ErrorMessage,
+			)
+		},
+	})
+}
+
+// redactQuery 替换 path 的查询字符串中名为 keys 的参数值, 保留参数顺序和其余内容
+func redactQuery(path string, keys []string) string {
+	base, query, ok := strings.Cut(path, "?")
+	if !ok || len(keys) == 0 {
+		return path
+	}
+
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+		// 按解码后的名称比较, 与 c.Query 的解析方式一致
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		for _, key := range keys {
+			if name == key {
+				params[i] = key + "=REDACTED"
+				break
+			}
+		}
+	}
+	return base + "?" + strings.Join(params, "&")
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-backend/internal/model"
+	"todo-backend/internal/ratelimit"
+	"todo-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitOptions 配置请求频率限制. Routes 的 key 为 "方法 路由", 例如 "POST /api/todos",
+// 匹配的请求使用单独的令牌桶, 不再计入 Default; Default 为零值时其余请求不限制
+type RateLimitOptions struct {
+	Default ratelimit.Limit
+	Routes  map[string]ratelimit.Limit
+}
+
+// ParseRouteLimits 解析 "POST /api/todos=30/m" 形式的路由限制
+func ParseRouteLimits(items []string) (map[string]ratelimit.Limit, error) {
+	routes := make(map[string]ratelimit.Limit)
+	for _, item := range items {
+		route, value, ok := strings.Cut(item, "=")
+		method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !hasPath {
+			return nil, fmt.Errorf("invalid route limit %q, expected \"METHOD /path=<requests>/<period>\"", item)
+		}
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+		routes[strings.ToUpper(method)+" "+strings.TrimSpace(path)] = limit
+	}
+	return routes, nil
+}
+
+// RateLimit 按调用方限制请求频率, 响应中带有 RateLimit-* 头, 超出限制时返回 429 和 Retry-After
+func RateLimit(opts RateLimitOptions) gin.HandlerFunc {
+	var fallback *ratelimit.Limiter
+	if opts.Default.Requests > 0 {
+		fallback = ratelimit.NewLimiter(opts.Default)
+	}
+	routes := make(map[string]*ratelimit.Limiter, len(opts.Routes))
+	for route, limit := range opts.Routes {
+		routes[route] = ratelimit.NewLimiter(limit)
+	}
+
+	return func(c *gin.Context) {
+		limiter, ok := routes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			limiter = fallback
+		}
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		result := limiter.Allow(ClientKey(c))
+		header := c.Writer.Header()
+		limit := limiter.Limit()
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, seconds(limit.Per)))
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, model.Response{
+				Code:    429,
+				Data:    nil,
+				Message: "too many requests",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// TokenNameKey 是通过 token 认证的调用方在 gin.Context 中的名字, 由认证处理器在校验 token 后设置
+const TokenNameKey = "todo.token_name"
+
+// ClientKey 区分调用方: 已通过认证的用户 (例如 Basic 认证) 按用户名, 通过 token 认证的按 token 名,
+// 其余按客户端 IP. 未经校验的 Authorization 头不参与计数, 否则每次换一个随机 token 就能绕过限制.
+// 认证信息由认证中间件设置, 因此 RateLimit 和 QuotaOwner 要放在认证之后
+func ClientKey(c *gin.Context) string {
+	if user := c.GetString(gin.AuthUserKey); user != "" {
+		return "user:" + user
+	}
+	if token := c.GetString(TokenNameKey); token != "" {
+		return "token:" + token
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// QuotaOwner 把 ClientKey 记录为请求中新建 Todo 的 owner, 配额按调用方分别计算
+func QuotaOwner() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(repository.WithOwner(c.Request.Context(), ClientKey(c)))
+		c.Next()
+	}
+}
+
+// seconds 向上取整到秒
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
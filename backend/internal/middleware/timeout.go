@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"todo-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueryTimeout 限制一个请求中所有数据库查询的总时间, 超时的查询返回 repository.ErrQueryTimeout.
+// 只有查询受截止时间限制, SSE 和 WebSocket 连接本身可以保持打开; timeout 为 0 时不限制
+func QueryTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout > 0 {
+			ctx := repository.WithQueryTimeout(c.Request.Context(), timeout)
+			c.Request = c.Request.WithContext(ctx)
+		}
+		c.Next()
+	}
+}
+
+// queryErrorStatus 返回查询失败时的状态码: 超时为 504, 请求被取消为 503, 其余为 500
+func queryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrQueryTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, repository.ErrCanceled):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
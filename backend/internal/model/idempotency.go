@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+type IdempotencyKey struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement"`
+	Key         string    `gorm:"type:text;not null;uniqueIndex:idx_idempotency_scope"`
+	Method      string    `gorm:"type:text;not null;uniqueIndex:idx_idempotency_scope"`
+	Path        string    `gorm:"type:text;not null;uniqueIndex:idx_idempotency_scope"`
+	RequestHash string    `gorm:"type:text;not null"`
+	StatusCode  int       `gorm:"not null;default:0"`
+	ContentType string    `gorm:"type:text"`
+	Response    []byte    `gorm:"type:blob"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	ExpiresAt   time.Time `gorm:"index;not null"`
+}
@@ -0,0 +1,27 @@
+package model
+
+const (
+	ImportCreated     = "created"
+	ImportWouldCreate = "would_create"
+	ImportDuplicate   = "duplicate"
+	ImportSkipped     = "skipped"
+	ImportError       = "error"
+)
+
+type ImportRow struct {
+	Row    int    `json:"row"`
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status"`
+	ID     uint   `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	Format     string      `json:"format"`
+	DryRun     bool        `json:"dry_run"`
+	Created    int         `json:"created"`
+	Duplicates int         `json:"duplicates"`
+	Skipped    int         `json:"skipped"`
+	Failed     int         `json:"failed"`
+	Rows       []ImportRow `json:"rows"`
+}
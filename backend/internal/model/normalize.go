@@ -0,0 +1,19 @@
+package model
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeTitle 统一为 NFC 形式并去掉首尾空白
+func NormalizeTitle(s string) string {
+	return strings.TrimSpace(norm.NFC.String(s))
+}
+
+// NormalizeContent 额外把 CRLF / CR 换行统一为 LF
+func NormalizeContent(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.TrimSpace(norm.NFC.String(s))
+}
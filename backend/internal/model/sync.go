@@ -0,0 +1,97 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	SyncCreated = "created"
+	SyncUpdated = "updated"
+	SyncDeleted = "deleted"
+
+	SyncApplied   = "applied"
+	SyncMerged    = "merged"
+	SyncConflict  = "conflict"
+	SyncUnchanged = "unchanged"
+	SyncError     = "error"
+)
+
+// SyncChange 是服务端返回的一条变更, 删除的 Todo 只返回 ID 和删除时间
+type SyncChange struct {
+	Type      string     `json:"type"`
+	ID        uint       `json:"id"`
+	Todo      *Todo      `json:"todo,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type SyncResponse struct {
+	Token   string       `json:"token"`
+	Reset   bool         `json:"reset"`
+	Changes []SyncChange `json:"changes"`
+}
+
+// SyncPushChange 是客户端离线期间的一条修改. ID 为 0 表示新建,
+// 只有非空字段会参与合并
+type SyncPushChange struct {
+	ClientRef   string    `json:"client_ref" binding:"max=64"`
+	ID          uint      `json:"id"`
+	Deleted     bool      `json:"deleted"`
+	BaseVersion uint      `json:"base_version"`
+	ModifiedAt  time.Time `json:"modified_at"`
+	Base        *SyncBase `json:"base"`
+	Title       *string   `json:"title" binding:"omitempty,maxlen=title,singleline"`
+	Content     *string   `json:"content" binding:"omitempty,maxlen=content,multiline"`
+	Completed   *bool     `json:"completed"`
+}
+
+// SyncBase 是客户端修改前看到的字段值, 用于逐个字段判断哪一方做了修改
+type SyncBase struct {
+	Title     *string `json:"title"`
+	Content   *string `json:"content"`
+	Completed *bool   `json:"completed"`
+}
+
+type SyncPushRequest struct {
+	Since   string           `json:"since"`
+	Changes []SyncPushChange `json:"changes" binding:"required,max=500,dive"`
+}
+
+func (r *SyncPushRequest) Normalize() {
+	for i := range r.Changes {
+		if title := r.Changes[i].Title; title != nil {
+			*title = NormalizeTitle(*title)
+		}
+		if content := r.Changes[i].Content; content != nil {
+			*content = NormalizeContent(*content)
+		}
+		if base := r.Changes[i].Base; base != nil {
+			if base.Title != nil {
+				*base.Title = NormalizeTitle(*base.Title)
+			}
+			if base.Content != nil {
+				*base.Content = NormalizeContent(*base.Content)
+			}
+		}
+	}
+}
+
+type SyncFieldConflict struct {
+	Field       string      `json:"field"`
+	ServerValue interface{} `json:"server_value"`
+	ClientValue interface{} `json:"client_value"`
+}
+
+type SyncResult struct {
+	Index     int                 `json:"index"`
+	ClientRef string              `json:"client_ref,omitempty"`
+	ID        uint                `json:"id,omitempty"`
+	Status    string              `json:"status"`
+	Todo      *Todo               `json:"todo,omitempty"`
+	Conflicts []SyncFieldConflict `json:"conflicts,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+type SyncPushResponse struct {
+	SyncResponse
+	Results []SyncResult `json:"results"`
+}
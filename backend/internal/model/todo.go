@@ -1,27 +1,57 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Todo struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Title     string    `gorm:"type:text;not null" json:"title"`
-	Content   string    `gorm:"type:text" json:"content"`
-	Completed bool      `gorm:"default:false" json:"completed"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Title       string     `gorm:"type:text;not null" json:"title"`
+	Content     string     `gorm:"type:text" json:"content"`
+	Completed   bool       `gorm:"default:false" json:"completed"`
+	Version     uint       `gorm:"not null;default:1" json:"version"`
+	CompletedAt *time.Time `json:"completed_at"`
+	DueAt       *time.Time `json:"due_at"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// 同步相关字段: 删除为软删除, 每次写入都会分配新的 ChangeSeq
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ChangeSeq  uint64         `gorm:"not null;default:0;index" json:"-"`
+	CreatedSeq uint64         `gorm:"not null;default:0" json:"-"`
+
+	// Owner 是创建 Todo 的调用方, 只用于按调用方计算配额, 不限制访问
+	Owner string `gorm:"type:text;not null;default:'';index" json:"-"`
+
+	// UID 只在 CalDAV 客户端创建 Todo 时保存, 其余 Todo 的 UID 由 ID 生成
+	UID *string `gorm:"uniqueIndex" json:"-"`
+	// ResourceName 是 CalDAV 客户端 PUT 时使用的资源名 (不含 .ics), 为空时资源名与 UID 相同
+	ResourceName *string `gorm:"uniqueIndex" json:"-"`
 }
 
 type CreateTodoRequest struct {
-	Title   string `json:"title" binding:"required"`
-	Content string `json:"content"`
+	Title   string `json:"title" binding:"required,maxlen=title,singleline"`
+	Content string `json:"content" binding:"maxlen=content,multiline"`
 }
 
+func (r *CreateTodoRequest) Normalize() {
+	r.Title = NormalizeTitle(r.Title)
+	r.Content = NormalizeContent(r.Content)
+}
+
+// UpdateTodoRequest 只修改请求中提供的字段: 标题和内容为空、completed 缺省时保持原值
 type UpdateTodoRequest struct {
-	Title     string `json:"title"`
-	Content   string `json:"content"`
-	Completed bool   `json:"completed"`
+	Title     string `json:"title" binding:"maxlen=title,singleline"`
+	Content   string `json:"content" binding:"maxlen=content,multiline"`
+	Completed *bool  `json:"completed"`
+}
+
+func (r *UpdateTodoRequest) Normalize() {
+	r.Title = NormalizeTitle(r.Title)
+	r.Content = NormalizeContent(r.Content)
 }
 
 type Response struct {
@@ -29,3 +59,30 @@ type Response struct {
 	Data    interface{} `json:"data"`
 	Message string      `json:"message"`
 }
+
+type BatchOperation struct {
+	Op   string          `json:"op" binding:"required,oneof=create update delete"`
+	ID   uint            `json:"id" binding:"required_unless=Op create"`
+	Data json.RawMessage `json:"data"`
+}
+
+type BatchRequest struct {
+	Mode       string           `json:"mode" binding:"omitempty,oneof=atomic partial"`
+	Operations []BatchOperation `json:"operations" binding:"required,min=1,max=500,dive"`
+}
+
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	ID     uint   `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Todo   *Todo  `json:"todo,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode      string        `json:"mode"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// User 是可以登录的账号, 目前只能通过服务端的 user 子命令管理
+type User struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username     string    `gorm:"type:text;not null;uniqueIndex" json:"username"`
+	PasswordHash string    `gorm:"type:text;not null" json:"-"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+type Webhook struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	URL       string     `gorm:"type:text;not null" json:"url"`
+	Secret    string     `gorm:"type:text;not null" json:"secret,omitempty"`
+	Events    StringList `gorm:"type:text" json:"events"`
+	Active    bool       `gorm:"not null;default:true" json:"active"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Accepts 判断 webhook 是否订阅了该事件, 没有配置过滤条件时接收全部事件
+func (w *Webhook) Accepts(eventType string) bool {
+	return len(w.Events) == 0 || w.Events.Contains("*") || w.Events.Contains(eventType)
+}
+
+type WebhookDelivery struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	WebhookID     uint       `gorm:"not null;index" json:"webhook_id"`
+	EventID       uint64     `json:"event_id"`
+	EventType     string     `gorm:"type:text;not null" json:"event_type"`
+	Payload       string     `gorm:"type:text;not null" json:"payload"`
+	Status        string     `gorm:"type:text;not null;index" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"index" json:"next_attempt_at"`
+	ResponseCode  int        `json:"response_code"`
+	Error         string     `gorm:"type:text" json:"error"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url,max=2048"`
+	Secret string   `json:"secret" binding:"omitempty,min=16,max=256"`
+	Events []string `json:"events" binding:"dive,oneof=* todo.created todo.updated todo.deleted"`
+	Active *bool    `json:"active"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    string    `json:"url" binding:"omitempty,url,max=2048"`
+	Secret string    `json:"secret" binding:"omitempty,min=16,max=256"`
+	Events *[]string `json:"events" binding:"omitempty,dive,oneof=* todo.created todo.updated todo.deleted"`
+	Active *bool     `json:"active"`
+}
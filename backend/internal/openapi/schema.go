@@ -0,0 +1,220 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-backend/internal/validation"
+)
+
+// Schema 是 JSON Schema (OpenAPI 3.1) 的子集
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+// registry 根据 Go 类型生成 schema, 具名结构体放入 components 并以 $ref 引用
+type registry struct {
+	schemas map[string]*Schema
+	rules   validation.Rules
+}
+
+func newRegistry(rules validation.Rules) *registry {
+	return &registry{schemas: make(map[string]*Schema), rules: rules}
+}
+
+// ref 返回 v 的类型对应的 schema
+func (r *registry) ref(v interface{}) *Schema {
+	return r.schema(reflect.TypeOf(v))
+}
+
+func (r *registry) schema(t reflect.Type) *Schema {
+	switch {
+	case t == nil:
+		return &Schema{}
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawJSONType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(r.schema(t.Elem()))
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.object(t)
+		}
+		if _, ok := r.schemas[t.Name()]; !ok {
+			// 先占位, 避免自引用的类型无限递归
+			r.schemas[t.Name()] = &Schema{}
+			*r.schemas[t.Name()] = *r.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	return &Schema{}
+}
+
+// object 按 encoding/json 的规则展开结构体字段, 匿名嵌入的结构体字段提升到外层.
+// 请求结构体 (带 binding 标签) 只有 required 的字段必填, 响应结构体中没有 omitempty 的字段总会输出
+func (r *registry) object(t reflect.Type) *Schema {
+	request := false
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("binding"); ok {
+			request = true
+		}
+	}
+
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitempty, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			embedded := r.object(field.Type)
+			for key, prop := range embedded.Properties {
+				s.Properties[key] = prop
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+
+		binding := field.Tag.Get("binding")
+		prop := r.schema(field.Type)
+		r.applyBinding(prop, binding)
+		s.Properties[name] = prop
+
+		if request && strings.HasPrefix(binding+",", "required,") || !request && !omitempty {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+func jsonName(field reflect.StructField) (string, bool, bool) {
+	if !field.IsExported() {
+		return "", false, false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	omitempty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, true
+}
+
+// applyBinding 把常用的校验规则转换为 schema 约束
+func (r *registry) applyBinding(s *Schema, binding string) {
+	target := s
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "dive":
+			if s.Items != nil {
+				target = s.Items
+			}
+		case "url":
+			target.Format = "uri"
+		case "oneof":
+			target.Enum = strings.Fields(value)
+		case "maxlen":
+			max := r.maxLength(value)
+			target.MaxLength = &max
+		case "min", "max":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			limit := &n
+			switch {
+			case isArray(target) && key == "min":
+				target.MinItems = limit
+			case isArray(target):
+				target.MaxItems = limit
+			case key == "min":
+				target.MinLength = limit
+			default:
+				target.MaxLength = limit
+			}
+		}
+	}
+}
+
+func isArray(s *Schema) bool {
+	switch t := s.Type.(type) {
+	case string:
+		return t == "array"
+	case []string:
+		return len(t) > 0 && t[0] == "array"
+	}
+	return false
+}
+
+func (r *registry) maxLength(name string) int {
+	if name == "title" {
+		return r.rules.TitleMaxLength
+	}
+	return r.rules.ContentMaxLength
+}
+
+// nullable 按 OpenAPI 3.1 的写法允许 null
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+	}
+	if t, ok := s.Type.(string); ok {
+		s.Type = []string{t, "null"}
+	}
+	return s
+}
@@ -0,0 +1,506 @@
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"todo-backend/internal/backup"
+	"todo-backend/internal/events"
+	"todo-backend/internal/model"
+	"todo-backend/internal/realtime"
+	"todo-backend/internal/validation"
+)
+
+const Version = "3.1.0"
+
+type builder struct {
+	doc *Document
+	reg *registry
+}
+
+// Build 生成与 main.go 中注册的路由一致的 OpenAPI 文档, 长度限制取自 rules
+func Build(rules validation.Rules) *Document {
+	b := &builder{
+		doc: &Document{
+			OpenAPI: Version,
+			Info: Info{
+				Title:       "Todo API",
+				Version:     "1.0.0",
+				Description: "除文件下载、事件流、GraphQL 和 CalDAV 外, 所有 JSON 接口都使用 {code, data, message} 响应格式",
+			},
+			Tags: []Tag{
+				{Name: "todos", Description: "Todo 增删改查与批量操作"},
+				{Name: "realtime", Description: "SSE 和 WebSocket 实时推送"},
+				{Name: "sync", Description: "离线增量同步"},
+				{Name: "files", Description: "导入、导出与日历订阅"},
+				{Name: "webhooks", Description: "Webhook 订阅与投递记录"},
+				{Name: "graphql", Description: "GraphQL 查询、修改与订阅"},
+				{Name: "admin", Description: "数据库备份等管理操作, 需要 TODO_ADMIN_TOKEN"},
+				{Name: "caldav", Description: "CalDAV 同步 (WebDAV 扩展方法记录在 x-webdav-methods 中)"},
+				{Name: "docs", Description: "API 文档"},
+			},
+			Paths: make(map[string]*PathItem),
+			Components: Components{
+				SecuritySchemes: map[string]*SecurityScheme{
+					"calendarToken": {Type: "apiKey", In: "query", Name: "token", Description: "TODO_CALENDAR_TOKEN"},
+					"caldavBasic":   {Type: "http", Scheme: "basic", Description: "TODO_CALDAV_USERNAME / TODO_CALDAV_PASSWORD"},
+					"adminToken":    {Type: "http", Scheme: "bearer", Description: "TODO_ADMIN_TOKEN"},
+				},
+			},
+		},
+		reg: newRegistry(rules),
+	}
+
+	b.todos()
+	b.realtime()
+	b.sync()
+	b.files()
+	b.webhooks()
+	b.graphql()
+	b.admin()
+	b.caldav()
+	b.docs()
+
+	b.doc.Components.Schemas = b.reg.schemas
+	return b.doc
+}
+
+func (b *builder) todos() {
+	todo := b.reg.ref(model.Todo{})
+	todoList := &Schema{Type: "array", Items: todo}
+	completed := query("completed", "true 只处理已完成的 Todo, false 只处理未完成的", &Schema{Type: "boolean"})
+
+	b.add("GET", "/api/todos", &Operation{
+		Tags: []string{"todos"}, Summary: "获取所有 Todo", OperationID: "listTodos",
+		Parameters: []*Parameter{ifNoneMatch()},
+		Responses:  b.responses(http.StatusOK, todoList, etag(), http.StatusNotModified),
+	})
+	b.add("POST", "/api/todos", &Operation{
+		Tags: []string{"todos"}, Summary: "创建 Todo", OperationID: "createTodo",
+		Description: "超出 Todo 配额时返回 403, 请求过于频繁时返回 429 并带有 Retry-After",
+		Parameters:  []*Parameter{idempotencyKey(), acceptLanguage()},
+		RequestBody: b.jsonBody(model.CreateTodoRequest{}),
+		Responses:   b.responses(http.StatusCreated, todo, etag(), http.StatusBadRequest, http.StatusForbidden, http.StatusRequestEntityTooLarge, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusTooManyRequests),
+	})
+	b.add("DELETE", "/api/todos", &Operation{
+		Tags: []string{"todos"}, Summary: "按完成状态批量删除", OperationID: "deleteTodos",
+		Parameters: []*Parameter{required(completed)},
+		Responses:  b.responses(http.StatusOK, counter("deleted"), nil, http.StatusBadRequest),
+	})
+	b.add("GET", "/api/todos/:id", &Operation{
+		Tags: []string{"todos"}, Summary: "获取单个 Todo", OperationID: "getTodo",
+		Parameters: []*Parameter{idParam(), ifNoneMatch()},
+		Responses:  b.responses(http.StatusOK, todo, etag(), http.StatusNotModified, http.StatusBadRequest, http.StatusNotFound),
+	})
+	b.add("PUT", "/api/todos/:id", &Operation{
+		Tags: []string{"todos"}, Summary: "更新 Todo", OperationID: "updateTodo",
+		Parameters:  []*Parameter{idParam(), ifMatch(), acceptLanguage()},
+		RequestBody: b.jsonBody(model.UpdateTodoRequest{}),
+		Responses:   b.responses(http.StatusOK, todo, etag(), http.StatusBadRequest, http.StatusNotFound, http.StatusPreconditionFailed, http.StatusRequestEntityTooLarge),
+	})
+	b.add("DELETE", "/api/todos/:id", &Operation{
+		Tags: []string{"todos"}, Summary: "删除 Todo", OperationID: "deleteTodo",
+		Parameters: []*Parameter{idParam(), ifMatch()},
+		Responses:  b.responses(http.StatusOK, &Schema{Type: "null"}, nil, http.StatusBadRequest, http.StatusNotFound, http.StatusPreconditionFailed),
+	})
+	b.add("POST", "/api/todos/batch", &Operation{
+		Tags: []string{"todos"}, Summary: "批量创建、更新、删除",
+		Description: "atomic 模式下任意一项失败时整体回滚, 响应状态码为失败项的状态码, data 中仍包含逐项结果",
+		OperationID: "batchTodos",
+		Parameters:  []*Parameter{idempotencyKey(), acceptLanguage()},
+		RequestBody: b.jsonBody(model.BatchRequest{}),
+		Responses:   b.responses(http.StatusOK, b.reg.ref(model.BatchResponse{}), nil, http.StatusBadRequest, http.StatusNotFound, http.StatusPreconditionFailed),
+	})
+	b.add("POST", "/api/todos/complete-all", &Operation{
+		Tags: []string{"todos"}, Summary: "把所有 Todo 标记为已完成", OperationID: "completeAllTodos",
+		Parameters: []*Parameter{idempotencyKey()},
+		Responses:  b.responses(http.StatusOK, counter("updated"), nil),
+	})
+}
+
+func (b *builder) realtime() {
+	b.add("GET", "/api/todos/events", &Operation{
+		Tags: []string{"realtime"}, Summary: "Server-Sent Events 变更流", OperationID: "streamTodoEvents",
+		Description: "事件类型为 todo.created、todo.updated、todo.deleted, data 为 JSON 编码的 Event",
+		Parameters: []*Parameter{
+			header("Last-Event-ID", "从该事件之后继续推送", &Schema{Type: "string"}),
+			query("last_event_id", "与 Last-Event-ID 相同, 供无法设置请求头的客户端使用", &Schema{Type: "string"}),
+		},
+		Responses: map[string]*Response{
+			"200": {Description: "事件流", Content: map[string]*MediaType{"text/event-stream": {Schema: b.reg.ref(events.Event{})}}},
+		},
+	})
+	b.add("GET", "/api/ws", &Operation{
+		Tags: []string{"realtime"}, Summary: "WebSocket 实时协作", OperationID: "connectWebSocket",
+		Description: "升级为 WebSocket 后, 客户端发送 JSON 编码的 ClientMessage, 服务端推送 ServerMessage (见 components)",
+		Parameters:  []*Parameter{query("user", "在线状态中显示的用户名", &Schema{Type: "string"})},
+		Responses: map[string]*Response{
+			"101": {Description: "切换到 WebSocket 协议"},
+			"400": {Description: "不是 WebSocket 握手请求"},
+		},
+	})
+	b.reg.ref(realtime.ClientMessage{})
+	b.reg.ref(realtime.ServerMessage{})
+}
+
+func (b *builder) sync() {
+	b.add("GET", "/api/sync", &Operation{
+		Tags: []string{"sync"}, Summary: "拉取令牌之后的增量变更", OperationID: "pullChanges",
+		Parameters: []*Parameter{query("since", "上次同步返回的令牌, 为空时返回全量数据", &Schema{Type: "string"})},
+		Responses:  b.responses(http.StatusOK, b.reg.ref(model.SyncResponse{}), nil, http.StatusBadRequest),
+	})
+	b.add("POST", "/api/sync", &Operation{
+		Tags: []string{"sync"}, Summary: "提交离线修改并拉取增量变更", OperationID: "pushChanges",
+		Parameters:  []*Parameter{idempotencyKey(), acceptLanguage()},
+		RequestBody: b.jsonBody(model.SyncPushRequest{}),
+		Responses:   b.responses(http.StatusOK, b.reg.ref(model.SyncPushResponse{}), nil, http.StatusBadRequest, http.StatusRequestEntityTooLarge),
+	})
+}
+
+func (b *builder) files() {
+	filters := []*Parameter{
+		query("completed", "按完成状态过滤", &Schema{Type: "boolean"}),
+		query("q", "标题或内容包含的文本", &Schema{Type: "string"}),
+	}
+
+	b.add("GET", "/api/export", &Operation{
+		Tags: []string{"files"}, Summary: "导出 Todo", OperationID: "exportTodos",
+		Parameters: append([]*Parameter{
+			query("format", "导出格式", &Schema{Type: "string", Enum: []string{"json", "csv", "markdown", "todotxt"}, Default: "json"}),
+		}, filters...),
+		Responses: map[string]*Response{
+			"200": {Description: "以附件形式下载", Content: map[string]*MediaType{
+				"application/json": {Schema: &Schema{Type: "array", Items: b.reg.ref(model.Todo{})}},
+				"text/csv":         {Schema: &Schema{Type: "string"}},
+				"text/markdown":    {Schema: &Schema{Type: "string"}},
+				"text/plain":       {Schema: &Schema{Type: "string"}},
+			}},
+			"400": b.errorResponse(http.StatusBadRequest),
+		},
+	})
+
+	b.add("POST", "/api/import", &Operation{
+		Tags: []string{"files"}, Summary: "从文件导入 Todo", OperationID: "importTodos",
+		Parameters: []*Parameter{
+			idempotencyKey(), acceptLanguage(),
+			query("dry_run", "只返回将要创建的条目", &Schema{Type: "boolean"}),
+			query("format", "文件格式, 为空时根据文件名和内容识别", importFormat()),
+		},
+		RequestBody: &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{"multipart/form-data": {Schema: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"file":    {Type: "string", Format: "binary"},
+					"format":  importFormat(),
+					"dry_run": {Type: "boolean"},
+				},
+				Required: []string{"file"},
+			}}},
+		},
+		Responses: b.responses(http.StatusOK, b.reg.ref(model.ImportReport{}), nil, http.StatusBadRequest, http.StatusRequestEntityTooLarge),
+	})
+
+	b.add("GET", "/api/calendar.ics", &Operation{
+		Tags: []string{"files"}, Summary: "iCalendar 订阅源", OperationID: "getCalendar",
+		Parameters: append([]*Parameter{
+			required(query("token", "订阅源 token", &Schema{Type: "string"})),
+			query("events", "是否为有截止日期的 Todo 输出 VEVENT", &Schema{Type: "boolean", Default: true}),
+			ifNoneMatch(),
+		}, filters...),
+		Security: []map[string][]string{{"calendarToken": {}}},
+		Responses: map[string]*Response{
+			"200": {Description: "VCALENDAR", Headers: etag(), Content: map[string]*MediaType{"text/calendar": {Schema: &Schema{Type: "string"}}}},
+			"304": {Description: "内容未变化"},
+			"400": {Description: "过滤参数无效"},
+			"404": {Description: "未配置 token 或 token 不匹配"},
+		},
+	})
+}
+
+func (b *builder) webhooks() {
+	hook := b.reg.ref(model.Webhook{})
+
+	b.add("GET", "/api/webhooks", &Operation{
+		Tags: []string{"webhooks"}, Summary: "获取所有 Webhook", OperationID: "listWebhooks",
+		Responses: b.responses(http.StatusOK, &Schema{Type: "array", Items: hook}, nil),
+	})
+	b.add("POST", "/api/webhooks", &Operation{
+		Tags: []string{"webhooks"}, Summary: "创建 Webhook", OperationID: "createWebhook",
+		Description: "secret 只在创建时返回; 未提供时由服务端生成",
+		Parameters:  []*Parameter{idempotencyKey(), acceptLanguage()},
+		RequestBody: b.jsonBody(model.CreateWebhookRequest{}),
+		Responses:   b.responses(http.StatusCreated, hook, nil, http.StatusBadRequest),
+	})
+	b.add("GET", "/api/webhooks/:id", &Operation{
+		Tags: []string{"webhooks"}, Summary: "获取单个 Webhook", OperationID: "getWebhook",
+		Parameters: []*Parameter{idParam()},
+		Responses:  b.responses(http.StatusOK, hook, nil, http.StatusBadRequest, http.StatusNotFound),
+	})
+	b.add("PUT", "/api/webhooks/:id", &Operation{
+		Tags: []string{"webhooks"}, Summary: "更新 Webhook", OperationID: "updateWebhook",
+		Parameters:  []*Parameter{idParam(), acceptLanguage()},
+		RequestBody: b.jsonBody(model.UpdateWebhookRequest{}),
+		Responses:   b.responses(http.StatusOK, hook, nil, http.StatusBadRequest, http.StatusNotFound),
+	})
+	b.add("DELETE", "/api/webhooks/:id", &Operation{
+		Tags: []string{"webhooks"}, Summary: "删除 Webhook 及其投递记录", OperationID: "deleteWebhook",
+		Parameters: []*Parameter{idParam()},
+		Responses:  b.responses(http.StatusOK, &Schema{Type: "null"}, nil, http.StatusBadRequest, http.StatusNotFound),
+	})
+	b.add("GET", "/api/webhooks/:id/deliveries", &Operation{
+		Tags: []string{"webhooks"}, Summary: "最近 100 条投递记录", OperationID: "listWebhookDeliveries",
+		Parameters: []*Parameter{idParam()},
+		Responses:  b.responses(http.StatusOK, &Schema{Type: "array", Items: b.reg.ref(model.WebhookDelivery{})}, nil, http.StatusBadRequest, http.StatusNotFound),
+	})
+	b.add("POST", "/api/webhooks/:id/test", &Operation{
+		Tags: []string{"webhooks"}, Summary: "发送测试事件", OperationID: "sendWebhookTest",
+		Parameters: []*Parameter{idParam(), idempotencyKey()},
+		Responses:  b.responses(http.StatusAccepted, b.reg.ref(model.WebhookDelivery{}), nil, http.StatusBadRequest, http.StatusNotFound),
+	})
+}
+
+func (b *builder) graphql() {
+	request := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"query":         {Type: "string"},
+			"variables":     {Type: "object"},
+			"operationName": {Type: "string"},
+		},
+		Required: []string{"query"},
+	}
+	result := map[string]*MediaType{"application/json": {Schema: &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"data":   {Type: "object"},
+			"errors": {Type: "array", Items: &Schema{Type: "object"}},
+		},
+	}}}
+	responses := map[string]*Response{
+		"200": {Description: "执行结果, 字段级错误在 errors 中返回", Content: result},
+		"400": {Description: "语法错误、校验失败或超出深度/复杂度限制", Content: result},
+	}
+
+	b.add("GET", "/graphql", &Operation{
+		Tags: []string{"graphql"}, Summary: "执行 GraphQL 查询或建立订阅连接", OperationID: "graphqlGet",
+		Description: "GET 请求只能执行查询. 带 Upgrade 头并使用 graphql-transport-ws 子协议时切换为 WebSocket, 用于订阅 todoChanged",
+		Parameters: []*Parameter{
+			required(query("query", "GraphQL 文档", &Schema{Type: "string"})),
+			query("variables", "JSON 编码的变量", &Schema{Type: "string"}),
+			query("operationName", "要执行的操作名", &Schema{Type: "string"}),
+		},
+		Responses: map[string]*Response{
+			"101": {Description: "切换到 WebSocket 协议"},
+			"200": responses["200"],
+			"400": responses["400"],
+			"405": {Description: "修改操作必须使用 POST", Content: result},
+		},
+	})
+	b.add("POST", "/graphql", &Operation{
+		Tags: []string{"graphql"}, Summary: "执行 GraphQL 查询或修改", OperationID: "graphqlPost",
+		Parameters:  []*Parameter{acceptLanguage()},
+		RequestBody: &RequestBody{Required: true, Content: map[string]*MediaType{"application/json": {Schema: request}}},
+		Responses:   responses,
+	})
+}
+
+func (b *builder) admin() {
+	security := []map[string][]string{{"adminToken": {}}}
+	backupSchema := b.reg.ref(backup.Backup{})
+
+	b.add("GET", "/api/admin/backups", &Operation{
+		Tags: []string{"admin"}, Summary: "备份列表", OperationID: "listBackups",
+		Description: "最新的备份在前",
+		Security:    security,
+		Responses:   b.responses(http.StatusOK, &Schema{Type: "array", Items: backupSchema}, nil, http.StatusUnauthorized, http.StatusNotFound),
+	})
+	b.add("POST", "/api/admin/backups", &Operation{
+		Tags: []string{"admin"}, Summary: "立即备份数据库", OperationID: "createBackup",
+		Description: "使用 VACUUM INTO 在线备份并生成 SHA-256 校验文件, 超出保留数量的旧备份会被删除",
+		Security:    security,
+		Responses:   b.responses(http.StatusCreated, backupSchema, nil, http.StatusUnauthorized, http.StatusNotFound),
+	})
+}
+
+func (b *builder) caldav() {
+	security := []map[string][]string{{"caldavBasic": {}}}
+	path := &Parameter{Name: "path", In: "path", Required: true, Description: "/ 为主目录, /todos/ 为日历集合, /todos/<uid>.ics 为单个 VTODO", Schema: &Schema{Type: "string"}}
+	ics := map[string]*MediaType{"text/calendar": {Schema: &Schema{Type: "string"}}}
+
+	b.add("GET", "/.well-known/caldav", &Operation{
+		Tags: []string{"caldav"}, Summary: "CalDAV 服务发现", OperationID: "caldavWellKnown",
+		Responses: map[string]*Response{"301": {Description: "重定向到 /caldav/"}},
+	})
+	b.add("PROPFIND", "/.well-known/caldav", nil)
+
+	b.add("OPTIONS", "/caldav/*path", &Operation{
+		Tags: []string{"caldav"}, Summary: "查询支持的 DAV 能力", OperationID: "caldavOptions",
+		Description: "PROPFIND (Depth 0/1) 和 REPORT (calendar-query、calendar-multiget、sync-collection) 不是 OpenAPI 支持的方法, " +
+			"记录在 x-webdav-methods 中, 返回 207 multistatus",
+		Parameters: []*Parameter{path}, Security: security,
+		Responses: map[string]*Response{"200": {Description: "DAV 和 Allow 响应头"}},
+	})
+	b.add("GET", "/caldav/*path", &Operation{
+		Tags: []string{"caldav"}, Summary: "下载 VTODO 资源", OperationID: "caldavGet",
+		Parameters: []*Parameter{path, ifNoneMatch()}, Security: security,
+		Responses: map[string]*Response{
+			"200": {Description: "VCALENDAR", Headers: etag(), Content: ics},
+			"304": {Description: "内容未变化"},
+			"401": {Description: "需要认证"},
+			"404": {Description: "资源不存在"},
+		},
+	})
+	b.add("HEAD", "/caldav/*path", &Operation{
+		Tags: []string{"caldav"}, Summary: "获取 VTODO 资源的 ETag", OperationID: "caldavHead",
+		Parameters: []*Parameter{path, ifNoneMatch()}, Security: security,
+		Responses: map[string]*Response{"200": {Description: "资源存在", Headers: etag()}, "404": {Description: "资源不存在"}},
+	})
+	b.add("PUT", "/caldav/*path", &Operation{
+		Tags: []string{"caldav"}, Summary: "创建或替换 VTODO 资源", OperationID: "caldavPut",
+		Parameters:  []*Parameter{path, ifMatch(), header("If-None-Match", "为 * 时只允许新建", &Schema{Type: "string"})},
+		Security:    security,
+		RequestBody: &RequestBody{Required: true, Content: ics},
+		Responses: map[string]*Response{
+			"201": {Description: "已创建", Headers: etag()},
+			"204": {Description: "已更新", Headers: etag()},
+			"400": {Description: "不是有效的 VTODO"},
+			"409": {Description: "UID 已被其他资源使用"},
+			"412": {Description: "ETag 不匹配"},
+		},
+	})
+	b.add("DELETE", "/caldav/*path", &Operation{
+		Tags: []string{"caldav"}, Summary: "删除 VTODO 资源", OperationID: "caldavDelete",
+		Parameters: []*Parameter{path, ifMatch()}, Security: security,
+		Responses: map[string]*Response{
+			"204": {Description: "已删除"},
+			"404": {Description: "资源不存在"},
+			"412": {Description: "ETag 不匹配"},
+		},
+	})
+	b.add("PROPFIND", "/caldav/*path", nil)
+	b.add("REPORT", "/caldav/*path", nil)
+}
+
+func (b *builder) docs() {
+	b.add("GET", "/api/openapi.json", &Operation{
+		Tags: []string{"docs"}, Summary: "OpenAPI 文档", OperationID: "getOpenAPI",
+		Responses: map[string]*Response{"200": {Description: "本文档", Content: map[string]*MediaType{"application/json": {Schema: &Schema{Type: "object"}}}}},
+	})
+	b.add("GET", "/api/docs", &Operation{
+		Tags: []string{"docs"}, Summary: "API 文档页面", OperationID: "getDocs",
+		Responses: map[string]*Response{"200": {Description: "HTML 页面", Content: map[string]*MediaType{"text/html": {Schema: &Schema{Type: "string"}}}}},
+	})
+}
+
+// add 注册一个操作, path 使用 Gin 的写法; WebDAV 方法的 op 为 nil
+func (b *builder) add(method, path string, op *Operation) {
+	key := PathFromGin(path)
+	item, ok := b.doc.Paths[key]
+	if !ok {
+		item = &PathItem{}
+		b.doc.Paths[key] = item
+	}
+	item.set(method, op)
+}
+
+func (b *builder) jsonBody(v interface{}) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]*MediaType{"application/json": {Schema: b.reg.ref(v)}},
+	}
+}
+
+// responses 生成成功响应和错误响应, 错误响应都使用统一的响应格式
+func (b *builder) responses(status int, data *Schema, headers map[string]*Header, errors ...int) map[string]*Response {
+	responses := map[string]*Response{
+		strconv.Itoa(status): {
+			Description: http.StatusText(status),
+			Headers:     headers,
+			Content:     map[string]*MediaType{"application/json": {Schema: envelope(data)}},
+		},
+		"500": b.errorResponse(http.StatusInternalServerError),
+		// 数据库查询超过 TODO_QUERY_TIMEOUT
+		"504": b.errorResponse(http.StatusGatewayTimeout),
+	}
+	for _, code := range errors {
+		if code == http.StatusNotModified {
+			responses[strconv.Itoa(code)] = &Response{Description: "内容未变化"}
+			continue
+		}
+		responses[strconv.Itoa(code)] = b.errorResponse(code)
+	}
+	return responses
+}
+
+func (b *builder) errorResponse(status int) *Response {
+	if _, ok := b.reg.schemas["ErrorResponse"]; !ok {
+		b.reg.schemas["ErrorResponse"] = envelope(&Schema{Type: "null"})
+	}
+	return &Response{
+		Description: http.StatusText(status),
+		Content:     map[string]*MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/ErrorResponse"}}},
+	}
+}
+
+// envelope 对应 model.Response
+func envelope(data *Schema) *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":    {Type: "integer", Description: "成功时为 0, 失败时为 HTTP 状态码"},
+			"data":    data,
+			"message": {Type: "string"},
+		},
+		Required: []string{"code", "data", "message"},
+	}
+}
+
+func counter(name string) *Schema {
+	return &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{name: {Type: "integer"}},
+		Required:   []string{name},
+	}
+}
+
+func importFormat() *Schema {
+	return &Schema{Type: "string", Enum: []string{"csv", "json", "todotxt", "todoist", "trello"}}
+}
+
+func etag() map[string]*Header {
+	return map[string]*Header{"ETag": {Description: "资源版本, 可用于 If-Match 和 If-None-Match", Schema: &Schema{Type: "string"}}}
+}
+
+func idParam() *Parameter {
+	return &Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer"}}
+}
+
+func query(name, description string, schema *Schema) *Parameter {
+	return &Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func header(name, description string, schema *Schema) *Parameter {
+	return &Parameter{Name: name, In: "header", Description: description, Schema: schema}
+}
+
+func required(p *Parameter) *Parameter {
+	p.Required = true
+	return p
+}
+
+func ifMatch() *Parameter {
+	return header("If-Match", "只有 ETag 匹配时才执行修改, 否则返回 412", &Schema{Type: "string"})
+}
+
+func ifNoneMatch() *Parameter {
+	return header("If-None-Match", "ETag 匹配时返回 304", &Schema{Type: "string"})
+}
+
+func idempotencyKey() *Parameter {
+	return header("Idempotency-Key", "相同的 key 和请求体重试时返回第一次的响应", &Schema{Type: "string"})
+}
+
+func acceptLanguage() *Parameter {
+	return header("Accept-Language", "校验错误信息的语言 (en, zh)", &Schema{Type: "string"})
+}
@@ -0,0 +1,10 @@
+package openapi
+
+import (
+	_ "embed"
+)
+
+// DocsHTML 是随二进制发布的文档页面, 在浏览器中读取同目录下的 openapi.json 渲染接口列表
+//
+//go:embed ui/index.html
+var DocsHTML []byte
@@ -0,0 +1,34 @@
+package realtime
+
+import (
+	"encoding/json"
+
+	"todo-backend/internal/events"
+)
+
+type ClientMessage struct {
+	Type        string          `json:"type"`
+	RequestID   string          `json:"request_id,omitempty"`
+	Topics      []string        `json:"topics,omitempty"`
+	LastEventID *uint64         `json:"last_event_id,omitempty"`
+	Op          string          `json:"op,omitempty"`
+	ID          uint            `json:"id,omitempty"`
+	IfMatch     string          `json:"if_match,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"`
+	TodoID      uint            `json:"todo_id,omitempty"`
+	State       string          `json:"state,omitempty"`
+}
+
+type ServerMessage struct {
+	Type        string        `json:"type"`
+	RequestID   string        `json:"request_id,omitempty"`
+	ClientID    string        `json:"client_id,omitempty"`
+	User        string        `json:"user,omitempty"`
+	Status      int           `json:"status,omitempty"`
+	Message     string        `json:"message,omitempty"`
+	Data        interface{}   `json:"data,omitempty"`
+	Event       *events.Event `json:"event,omitempty"`
+	Topics      []string      `json:"topics,omitempty"`
+	TodoID      uint          `json:"todo_id,omitempty"`
+	LastEventID uint64        `json:"last_event_id,omitempty"`
+}
@@ -0,0 +1,251 @@
+package realtime
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-backend/internal/events"
+	"todo-backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	TopicAllTodos   = "todos"
+	todoTopicPrefix = "todo:"
+
+	StateViewing = "viewing"
+	StateEditing = "editing"
+	StateIdle    = "idle"
+)
+
+type Member struct {
+	ClientID string    `json:"client_id"`
+	User     string    `json:"user"`
+	State    string    `json:"state"`
+	Since    time.Time `json:"since"`
+}
+
+type Client struct {
+	ID   string
+	User string
+
+	send   chan ServerMessage
+	mu     sync.Mutex
+	topics map[string]struct{}
+}
+
+// Registry 记录在线连接和每个 Todo 上的在线状态
+type Registry struct {
+	mu       sync.Mutex
+	nextID   uint64
+	clients  map[string]*Client
+	presence map[uint]map[string]Member
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		clients:  make(map[string]*Client),
+		presence: make(map[uint]map[string]Member),
+	}
+}
+
+func (r *Registry) Register(user string, buffer int) *Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	client := &Client{
+		ID:     fmt.Sprintf("c%d", r.nextID),
+		User:   user,
+		send:   make(chan ServerMessage, buffer),
+		topics: make(map[string]struct{}),
+	}
+	r.clients[client.ID] = client
+	return client
+}
+
+// Unregister 移除连接并清理它在所有 Todo 上的在线状态
+func (r *Registry) Unregister(client *Client) {
+	r.mu.Lock()
+	delete(r.clients, client.ID)
+
+	var affected []uint
+	for todoID, members := range r.presence {
+		if _, ok := members[client.ID]; ok {
+			delete(members, client.ID)
+			affected = append(affected, todoID)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, todoID := range affected {
+		r.broadcastPresence(todoID)
+	}
+}
+
+func (r *Registry) SetPresence(client *Client, todoID uint, state string) error {
+	switch state {
+	case StateViewing, StateEditing, StateIdle:
+	default:
+		return fmt.Errorf("unknown presence state %q", state)
+	}
+
+	r.mu.Lock()
+	members, ok := r.presence[todoID]
+	if !ok {
+		members = make(map[string]Member)
+		r.presence[todoID] = members
+	}
+
+	if state == StateIdle {
+		delete(members, client.ID)
+	} else if current, ok := members[client.ID]; !ok || current.State != state {
+		members[client.ID] = Member{
+			ClientID: client.ID,
+			User:     client.User,
+			State:    state,
+			Since:    time.Now(),
+		}
+	}
+	if len(members) == 0 {
+		delete(r.presence, todoID)
+	}
+	r.mu.Unlock()
+
+	r.broadcastPresence(todoID)
+	return nil
+}
+
+func (r *Registry) Members(todoID uint) []Member {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members := make([]Member, 0, len(r.presence[todoID]))
+	for _, member := range r.presence[todoID] {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ClientID < members[j].ClientID
+	})
+	return members
+}
+
+func (r *Registry) PresenceMessage(todoID uint) ServerMessage {
+	return ServerMessage{
+		Type:   "presence",
+		TodoID: todoID,
+		Data:   r.Members(todoID),
+	}
+}
+
+func (r *Registry) broadcastPresence(todoID uint) {
+	msg := r.PresenceMessage(todoID)
+
+	r.mu.Lock()
+	clients := make([]*Client, 0, len(r.clients))
+	for _, client := range r.clients {
+		clients = append(clients, client)
+	}
+	r.mu.Unlock()
+
+	for _, client := range clients {
+		if client.Interested(todoID) {
+			client.Send(msg)
+		}
+	}
+}
+
+// Send 非阻塞地投递消息, 缓冲区已满时返回 false
+func (c *Client) Send(msg ServerMessage) bool {
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Client) Outbox() <-chan ServerMessage {
+	return c.send
+}
+
+func (c *Client) Subscribe(topics []string) error {
+	for _, topic := range topics {
+		if _, err := ParseTopic(topic); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, topic := range topics {
+		c.topics[topic] = struct{}{}
+	}
+	return nil
+}
+
+func (c *Client) Unsubscribe(topics []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, topic := range topics {
+		delete(c.topics, topic)
+	}
+}
+
+func (c *Client) Topics() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+func (c *Client) Interested(todoID uint) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.topics[TopicAllTodos]; ok {
+		return true
+	}
+	_, ok := c.topics[TodoTopic(todoID)]
+	return ok
+}
+
+func TodoTopic(todoID uint) string {
+	return todoTopicPrefix + strconv.FormatUint(uint64(todoID), 10)
+}
+
+// ParseTopic 解析订阅主题, 返回对应的 Todo ID (订阅全部时为 0)
+func ParseTopic(topic string) (uint, error) {
+	if topic == TopicAllTodos {
+		return 0, nil
+	}
+	if idStr, ok := strings.CutPrefix(topic, todoTopicPrefix); ok {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err == nil && id > 0 {
+			return uint(id), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown topic %q", topic)
+}
+
+// EventTodoID 取出事件关联的 Todo ID
+func EventTodoID(event events.Event) uint {
+	switch data := event.Data.(type) {
+	case *model.Todo:
+		return data.ID
+	case gin.H:
+		id, _ := data["id"].(uint)
+		return id
+	}
+	return 0
+}
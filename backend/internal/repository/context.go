@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrQueryTimeout 表示查询超过了请求的查询截止时间
+	ErrQueryTimeout = errors.New("database query timed out")
+	// ErrCanceled 表示请求在查询完成前被取消, 通常是客户端断开了连接
+	ErrCanceled = errors.New("request canceled")
+)
+
+type queryTimeoutKey struct{}
+
+// queryTimeout 记录请求的查询超时时间和由它算出的截止时间
+type queryTimeout struct {
+	timeout  time.Duration
+	deadline time.Time
+}
+
+// WithQueryTimeout 为 ctx 上的所有查询设置从现在起 timeout 后的共同截止时间.
+// 截止时间只作用于查询, 不会结束 ctx 本身, SSE、WebSocket 等长连接不受影响
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutKey{}, queryTimeout{timeout: timeout, deadline: time.Now().Add(timeout)})
+}
+
+// RenewQueryTimeout 从现在起重新计算 ctx 上的查询截止时间, 用于长连接中的每条消息; ctx 没有设置超时时原样返回
+func RenewQueryTimeout(ctx context.Context) context.Context {
+	if qt, ok := ctx.Value(queryTimeoutKey{}).(queryTimeout); ok {
+		return WithQueryTimeout(ctx, qt.timeout)
+	}
+	return ctx
+}
+
+type ownerKey struct{}
+
+// WithOwner 记录发起写入的调用方, 通过 ctx 新建的 Todo 属于 owner, 配额按 owner 分别计算.
+// 没有记录调用方的写入 (例如命令行工具) 属于空 owner
+func WithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFromContext 返回 WithOwner 记录的调用方
+func OwnerFromContext(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+	return owner
+}
+
+// WithoutQueryTimeout 去掉 ctx 上的查询截止时间. 用于跨越多批查询的事务: 事务本身不受单个请求的
+// 时间预算限制, 每批查询使用 RenewQueryTimeout 得到的截止时间
+func WithoutQueryTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryTimeoutKey{}, nil)
+}
+
+// withContext 返回绑定 ctx 的连接, 调用方必须用 done 处理查询结果: done 把因 ctx 结束导致的错误
+// 转换为 ErrQueryTimeout 或 ErrCanceled, 并释放截止时间的定时器
+func withContext(ctx context.Context, db *gorm.DB) (*gorm.DB, func(error) error) {
+	cancel := func() {}
+	if qt, ok := ctx.Value(queryTimeoutKey{}).(queryTimeout); ok {
+		ctx, cancel = context.WithDeadline(ctx, qt.deadline)
+	}
+
+	return db.WithContext(ctx), func(err error) error {
+		err = contextError(ctx, err)
+		cancel()
+		return err
+	}
+}
+
+func contextError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	if errors.Is(err, ErrQueryTimeout) || errors.Is(err, ErrCanceled) {
+		return err
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrQueryTimeout, err)
+	}
+	return fmt.Errorf("%w: %v", ErrCanceled, err)
+}
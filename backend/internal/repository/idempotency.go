@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"todo-backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+func (r *IdempotencyRepository) Find(ctx context.Context, key, method, path string) (*model.IdempotencyKey, error) {
+	db, done := withContext(ctx, r.db)
+	var record model.IdempotencyKey
+	if err := done(db.Where("key = ? AND method = ? AND path = ?", key, method, path).First(&record).Error); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Reserve 占用一个幂等键, 键已存在且未过期时返回 false. 同一范围内已过期但还没被清理的键会先删除
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyKey) (bool, error) {
+	db, done := withContext(ctx, r.db)
+	if err := db.Where("key = ? AND method = ? AND path = ? AND expires_at <= ?", record.Key, record.Method, record.Path, time.Now()).
+		Delete(&model.IdempotencyKey{}).Error; err != nil {
+		return false, done(err)
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	return result.RowsAffected > 0, done(result.Error)
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, id uint, statusCode int, contentType string, response []byte) error {
+	db, done := withContext(ctx, r.db)
+	return done(db.Model(&model.IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status_code":  statusCode,
+		"content_type": contentType,
+		"response":     response,
+	}).Error)
+}
+
+func (r *IdempotencyRepository) Delete(ctx context.Context, id uint) error {
+	db, done := withContext(ctx, r.db)
+	return done(db.Delete(&model.IdempotencyKey{}, id).Error)
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	db, done := withContext(ctx, r.db)
+	return done(db.Where("expires_at <= ?", now).Delete(&model.IdempotencyKey{}).Error)
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"todo-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota 限制每个 owner 的 Todo 数量和标题加内容的总字节数, 0 表示不限制.
+// 软删除的 Todo 不计入. owner 见 WithOwner
+type Quota struct {
+	MaxTodos        int64
+	MaxStorageBytes int64
+}
+
+// WithQuota 返回使用配额 q 的仓库, 之后通过它写入 Todo 的操作都会检查配额
+func (r *TodoRepository) WithQuota(q Quota) *TodoRepository {
+	return &TodoRepository{db: r.db, quota: q}
+}
+
+// Usage 是当前的 Todo 数量和存储用量
+type Usage struct {
+	Todos        int64 `json:"todos"`
+	StorageBytes int64 `json:"storage_bytes"`
+}
+
+// Usage 返回整个数据库的用量
+func (r *TodoRepository) Usage(ctx context.Context) (*Usage, error) {
+	return r.usage(ctx, r.db.Model(&model.Todo{}))
+}
+
+// OwnerUsage 返回属于 owner 的 Todo 的用量, 配额按它检查
+func (r *TodoRepository) OwnerUsage(ctx context.Context, owner string) (*Usage, error) {
+	return r.usage(ctx, r.db.Model(&model.Todo{}).Where("owner = ?", owner))
+}
+
+func (r *TodoRepository) usage(ctx context.Context, query *gorm.DB) (*Usage, error) {
+	db, done := withContext(ctx, query)
+	var usage Usage
+	err := db.Select("COUNT(*) AS todos, COALESCE(SUM(LENGTH(CAST(title AS BLOB)) + LENGTH(CAST(content AS BLOB))), 0) AS storage_bytes").
+		Scan(&usage).Error
+	return &usage, done(err)
+}
+
+// checkQuota 在写入前检查 owner 新增 added 个 Todo、增加 growth 字节后是否超出配额
+func (r *TodoRepository) checkQuota(ctx context.Context, owner string, added, growth int64) error {
+	quota := r.quota
+	if quota.MaxTodos <= 0 && quota.MaxStorageBytes <= 0 {
+		return nil
+	}
+	if added <= 0 && growth <= 0 {
+		return nil
+	}
+
+	usage, err := r.OwnerUsage(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if quota.MaxTodos > 0 && added > 0 && usage.Todos+added > quota.MaxTodos {
+		return fmt.Errorf("%w: at most %d todos", ErrQuotaExceeded, quota.MaxTodos)
+	}
+	if quota.MaxStorageBytes > 0 && growth > 0 && usage.StorageBytes+growth > quota.MaxStorageBytes {
+		return fmt.Errorf("%w: at most %d bytes of todo text", ErrQuotaExceeded, quota.MaxStorageBytes)
+	}
+	return nil
+}
+
+// storageGrowth 计算更新 values 中的标题和内容后增加的字节数, 增加的用量计入 Todo 所属的 owner
+func (r *TodoRepository) storageGrowth(ctx context.Context, id uint, values map[string]interface{}) (string, int64, error) {
+	title, hasTitle := values["title"].(string)
+	content, hasContent := values["content"].(string)
+	if r.quota.MaxStorageBytes <= 0 || (!hasTitle && !hasContent) {
+		return "", 0, nil
+	}
+
+	current, err := r.GetByID(ctx, id)
+	if err != nil {
+		if r.IsNotFound(err) {
+			return "", 0, nil
+		}
+		return "", 0, err
+	}
+
+	var growth int64
+	if hasTitle {
+		growth += int64(len(title) - len(current.Title))
+	}
+	if hasContent {
+		growth += int64(len(content) - len(current.Content))
+	}
+	return current.Owner, growth, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"todo-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// Stats 是数据库内容的概况, 由 server stats 子命令输出
+type Stats struct {
+	Todos             int64  `json:"todos"`
+	Completed         int64  `json:"completed"`
+	Pending           int64  `json:"pending"`
+	Overdue           int64  `json:"overdue"`
+	Deleted           int64  `json:"deleted"`
+	Webhooks          int64  `json:"webhooks"`
+	PendingDeliveries int64  `json:"pending_deliveries"`
+	IdempotencyKeys   int64  `json:"idempotency_keys"`
+	StorageBytes      int64  `json:"storage_bytes"`
+	Users             int64  `json:"users"`
+	LastChangeSeq     uint64 `json:"last_change_seq"`
+}
+
+func CollectStats(ctx context.Context, conn *gorm.DB) (*Stats, error) {
+	db, done := withContext(ctx, conn)
+	stats := &Stats{}
+
+	queries := []func() error{
+		func() error { return db.Model(&model.Todo{}).Count(&stats.Todos).Error },
+		func() error {
+			return db.Model(&model.Todo{}).Where("completed = ?", true).Count(&stats.Completed).Error
+		},
+		func() error {
+			return db.Model(&model.Todo{}).Where("completed = ? AND due_at < ?", false, time.Now()).Count(&stats.Overdue).Error
+		},
+		func() error {
+			return db.Unscoped().Model(&model.Todo{}).Where("deleted_at IS NOT NULL").Count(&stats.Deleted).Error
+		},
+		func() error { return db.Model(&model.Webhook{}).Count(&stats.Webhooks).Error },
+		func() error {
+			return db.Model(&model.WebhookDelivery{}).Where("status = ?", model.DeliveryPending).Count(&stats.PendingDeliveries).Error
+		},
+		func() error { return db.Model(&model.IdempotencyKey{}).Count(&stats.IdempotencyKeys).Error },
+		func() error { return db.Model(&model.User{}).Count(&stats.Users).Error },
+	}
+	for _, query := range queries {
+		if err := query(); err != nil {
+			return nil, done(err)
+		}
+	}
+	done(nil)
+	stats.Pending = stats.Todos - stats.Completed
+
+	usage, err := NewTodoRepository(conn).Usage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.StorageBytes = usage.StorageBytes
+
+	seq, err := NewTodoRepository(conn).LastChangeSeq(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.LastChangeSeq = seq
+	return stats, nil
+}
@@ -1,44 +1,311 @@
 package repository
 
 import (
+	"context"
 	"errors"
-	"todo-backend/internal/database"
+	"strings"
+	"time"
+
 	"todo-backend/internal/model"
 
 	"gorm.io/gorm"
 )
 
-type TodoRepository struct{}
+var ErrVersionConflict = errors.New("todo has been modified")
+
+// nextChangeSeq 在写锁内计算下一个变更序号, 同一条语句修改的记录共享同一个序号
+var nextChangeSeq = gorm.Expr("(SELECT COALESCE(MAX(change_seq), 0) + 1 FROM todos)")
+
+type TodoRepository struct {
+	db    *gorm.DB
+	quota Quota
+}
+
+// NewTodoRepository 创建不限制配额的仓库, 需要配额时使用 WithQuota
+func NewTodoRepository(db *gorm.DB) *TodoRepository {
+	return &TodoRepository{db: db}
+}
 
-func NewTodoRepository() *TodoRepository {
-	return &TodoRepository{}
+// Transaction 在事务中执行 fn, 嵌套调用时使用 SAVEPOINT, 规则同 UnitOfWork.Do
+func (r *TodoRepository) Transaction(ctx context.Context, fn func(repo *TodoRepository) error) error {
+	return NewUnitOfWork(r.db).WithQuota(r.quota).Do(ctx, func(tx *UnitOfWork) error {
+		return fn(tx.Todos())
+	})
 }
 
-func (r *TodoRepository) GetAll() ([]model.Todo, error) {
+func (r *TodoRepository) GetAll(ctx context.Context) ([]model.Todo, error) {
+	db, done := withContext(ctx, r.db)
 	var todos []model.Todo
-	err := database.DB.Order("created_at DESC").Find(&todos).Error
-	return todos, err
+	err := db.Order("created_at DESC").Find(&todos).Error
+	return todos, done(err)
 }
 
-func (r *TodoRepository) GetByID(id uint) (*model.Todo, error) {
+func (r *TodoRepository) GetByID(ctx context.Context, id uint) (*model.Todo, error) {
+	db, done := withContext(ctx, r.db)
 	var todo model.Todo
-	err := database.DB.First(&todo, id).Error
+	if err := done(db.First(&todo, id).Error); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+func (r *TodoRepository) Create(ctx context.Context, todo *model.Todo) error {
+	if todo.Completed && todo.CompletedAt == nil {
+		now := time.Now()
+		todo.CompletedAt = &now
+	}
+
+	todo.Owner = OwnerFromContext(ctx)
+	return r.Transaction(ctx, func(repo *TodoRepository) error {
+		if err := repo.checkQuota(ctx, todo.Owner, 1, int64(len(todo.Title)+len(todo.Content))); err != nil {
+			return err
+		}
+
+		db, done := withContext(ctx, repo.db)
+		if err := db.Create(todo).Error; err != nil {
+			return done(err)
+		}
+		err := db.Model(&model.Todo{}).Where("id = ?", todo.ID).UpdateColumns(map[string]interface{}{
+			"change_seq":  nextChangeSeq,
+			"created_seq": nextChangeSeq,
+		}).Error
+		if err != nil {
+			return done(err)
+		}
+		return done(db.Select("change_seq", "created_seq").First(todo, todo.ID).Error)
+	})
+}
+
+func (r *TodoRepository) Update(ctx context.Context, id uint, updates *model.UpdateTodoRequest) error {
+	return r.UpdateIfVersion(ctx, id, updates, 0)
+}
+
+// UpdateIfVersion 只写入 updates 中提供的字段, 仅当当前版本等于 version 时更新, version 为 0 表示不检查
+func (r *TodoRepository) UpdateIfVersion(ctx context.Context, id uint, updates *model.UpdateTodoRequest, version uint) error {
+	values := make(map[string]interface{})
+	if updates.Completed != nil {
+		values["completed"] = *updates.Completed
+	}
+	if updates.Title != "" {
+		values["title"] = updates.Title
+	}
+	if updates.Content != "" {
+		values["content"] = updates.Content
+	}
+
+	return r.UpdateFields(ctx, id, values, version)
+}
+
+// UpdateFields 只更新 values 中的列, 同时递增版本号和变更序号.
+// 没有匹配的记录时返回 ErrVersionConflict (指定了 version) 或 gorm.ErrRecordNotFound
+func (r *TodoRepository) UpdateFields(ctx context.Context, id uint, values map[string]interface{}, version uint) error {
+	values["version"] = gorm.Expr("version + 1")
+	values["change_seq"] = nextChangeSeq
+	// 调用方没有指定完成时间时, 根据完成状态维护 completed_at
+	if _, set := values["completed_at"]; !set {
+		if completed, ok := values["completed"].(bool); ok && completed {
+			values["completed_at"] = gorm.Expr("COALESCE(completed_at, ?)", time.Now())
+		} else if ok {
+			values["completed_at"] = nil
+		}
+	}
+
+	owner, growth, err := r.storageGrowth(ctx, id, values)
 	if err != nil {
+		return err
+	}
+	if err := r.checkQuota(ctx, owner, 0, growth); err != nil {
+		return err
+	}
+
+	db, done := withContext(ctx, r.db)
+	query := db.Model(&model.Todo{}).Where("id = ?", id)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	result := query.Updates(values)
+	if err := done(result.Error); err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		if version > 0 {
+			return ErrVersionConflict
+		}
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *TodoRepository) Delete(ctx context.Context, id uint) error {
+	return r.DeleteIfVersion(ctx, id, 0)
+}
+
+// DeleteIfVersion 软删除 Todo, 保留的记录作为同步接口的删除标记
+func (r *TodoRepository) DeleteIfVersion(ctx context.Context, id uint, version uint) error {
+	return r.UpdateFields(ctx, id, map[string]interface{}{"deleted_at": time.Now()}, version)
+}
+
+// DeleteByCompleted 删除指定完成状态的 Todo, 返回被删除的 ID
+func (r *TodoRepository) DeleteByCompleted(ctx context.Context, completed bool) ([]uint, error) {
+	var ids []uint
+	err := r.Transaction(ctx, func(repo *TodoRepository) error {
+		if err := repo.db.Model(&model.Todo{}).Where("completed = ?", completed).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return repo.db.Model(&model.Todo{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"deleted_at": time.Now(),
+			"version":    gorm.Expr("version + 1"),
+			"change_seq": nextChangeSeq,
+		}).Error
+	})
+	return ids, err
+}
+
+// CompleteAll 把未完成的 Todo 标记为已完成, 返回被修改的记录
+func (r *TodoRepository) CompleteAll(ctx context.Context) ([]model.Todo, error) {
+	var todos []model.Todo
+	err := r.Transaction(ctx, func(repo *TodoRepository) error {
+		var ids []uint
+		if err := repo.db.Model(&model.Todo{}).Where("completed = ?", false).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		err := repo.db.Model(&model.Todo{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"completed":    true,
+			"completed_at": time.Now(),
+			"version":      gorm.Expr("version + 1"),
+			"change_seq":   nextChangeSeq,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return repo.db.Where("id IN ?", ids).Order("id").Find(&todos).Error
+	})
+	return todos, err
+}
+
+func (r *TodoRepository) GetByUID(ctx context.Context, uid string) (*model.Todo, error) {
+	db, done := withContext(ctx, r.db)
+	var todo model.Todo
+	if err := done(db.Where("uid = ?", uid).First(&todo).Error); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+func (r *TodoRepository) GetByResourceName(ctx context.Context, name string) (*model.Todo, error) {
+	db, done := withContext(ctx, r.db)
+	var todo model.Todo
+	if err := done(db.Where("resource_name = ?", name).First(&todo).Error); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// ReleaseResource 清除已删除记录上的资源名和 UID, 使客户端可以用同一个资源名或 UID 重新创建
+func (r *TodoRepository) ReleaseResource(ctx context.Context, name, uid string) error {
+	db, done := withContext(ctx, r.db)
+	return done(db.Unscoped().Model(&model.Todo{}).
+		Where("(resource_name = ? OR uid = ?) AND deleted_at IS NOT NULL", name, uid).
+		UpdateColumns(map[string]interface{}{"resource_name": nil, "uid": nil}).Error)
+}
+
+func (r *TodoRepository) FindByTitle(ctx context.Context, title string) ([]model.Todo, error) {
+	db, done := withContext(ctx, r.db)
+	var todos []model.Todo
+	err := db.Where("title = ?", title).Find(&todos).Error
+	return todos, done(err)
+}
+
+// GetByIDUnscoped 查询 Todo, 包括已删除的记录
+func (r *TodoRepository) GetByIDUnscoped(ctx context.Context, id uint) (*model.Todo, error) {
+	db, done := withContext(ctx, r.db)
+	var todo model.Todo
+	if err := done(db.Unscoped().First(&todo, id).Error); err != nil {
 		return nil, err
 	}
 	return &todo, nil
 }
 
-func (r *TodoRepository) Create(todo *model.Todo) error {
-	return database.DB.Create(todo).Error
+// ChangesSince 返回变更序号大于 seq 的记录, 包括已删除的记录
+func (r *TodoRepository) ChangesSince(ctx context.Context, seq uint64) ([]model.Todo, error) {
+	db, done := withContext(ctx, r.db)
+	var todos []model.Todo
+	err := db.Unscoped().Where("change_seq > ?", seq).Order("change_seq, id").Find(&todos).Error
+	return todos, done(err)
+}
+
+func (r *TodoRepository) LastChangeSeq(ctx context.Context) (uint64, error) {
+	db, done := withContext(ctx, r.db)
+	var seq uint64
+	err := db.Unscoped().Model(&model.Todo{}).Select("COALESCE(MAX(change_seq), 0)").Scan(&seq).Error
+	return seq, done(err)
+}
+
+// TodoFilter 是列表类查询的过滤条件, 零值表示不过滤
+type TodoFilter struct {
+	Completed *bool
+	Query     string
+}
+
+func (f TodoFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.Completed != nil {
+		db = db.Where("completed = ?", *f.Completed)
+	}
+	if f.Query != "" {
+		pattern := "%" + escapeLike(f.Query) + "%"
+		db = db.Where("title LIKE ? ESCAPE '\\' OR content LIKE ? ESCAPE '\\'", pattern, pattern)
+	}
+	return db
+}
+
+const eachBatchSize = 200
+
+// Each 按 ID 顺序分批读取符合条件的 Todo, 避免一次性加载全部数据.
+// 每批查询重新计算查询截止时间, 数据量大或 fn 较慢 (例如向读取缓慢的客户端流式输出) 时不会因总时间超时
+func (r *TodoRepository) Each(ctx context.Context, filter TodoFilter, fn func(todo *model.Todo) error) error {
+	var afterID uint
+	for {
+		batch, err := r.List(RenewQueryTimeout(ctx), filter, afterID, eachBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if len(batch) < eachBatchSize {
+			return nil
+		}
+		afterID = batch[len(batch)-1].ID
+	}
+}
+
+// List 按 ID 顺序返回 afterID 之后的最多 limit 条 Todo, 用于游标分页
+func (r *TodoRepository) List(ctx context.Context, filter TodoFilter, afterID uint, limit int) ([]model.Todo, error) {
+	db, done := withContext(ctx, r.db)
+	var todos []model.Todo
+	err := filter.apply(db).Where("id > ?", afterID).Order("id").Limit(limit).Find(&todos).Error
+	return todos, done(err)
 }
 
-func (r *TodoRepository) Update(id uint, updates *model.Todo) error {
-	return database.DB.Model(&model.Todo{}).Where("id = ?", id).Updates(updates).Error
+func (r *TodoRepository) Count(ctx context.Context, filter TodoFilter) (int64, error) {
+	db, done := withContext(ctx, r.db)
+	var count int64
+	err := filter.apply(db.Model(&model.Todo{})).Count(&count).Error
+	return count, done(err)
 }
 
-func (r *TodoRepository) Delete(id uint) error {
-	return database.DB.Delete(&model.Todo{}, id).Error
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
 }
 
 func (r *TodoRepository) IsNotFound(err error) bool {
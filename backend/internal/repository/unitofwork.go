@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+const (
+	// busyRetries 是最外层事务遇到 SQLITE_BUSY 时的最多执行次数
+	busyRetries = 5
+	// busyBackoff 是第一次重试前的等待时间, 之后每次加倍
+	busyBackoff = 10 * time.Millisecond
+)
+
+// UnitOfWork 把多个仓库的操作放在同一个事务中执行, 仓库通过 Todos、Webhooks 等方法获取
+type UnitOfWork struct {
+	db    *gorm.DB
+	quota Quota
+}
+
+func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// WithQuota 返回使用配额 q 的 UnitOfWork, Todos 返回的仓库在写入前检查配额
+func (u *UnitOfWork) WithQuota(q Quota) *UnitOfWork {
+	return &UnitOfWork{db: u.db, quota: q}
+}
+
+func (u *UnitOfWork) Todos() *TodoRepository {
+	return &TodoRepository{db: u.db, quota: u.quota}
+}
+
+func (u *UnitOfWork) Webhooks() *WebhookRepository {
+	return &WebhookRepository{db: u.db}
+}
+
+func (u *UnitOfWork) Idempotency() *IdempotencyRepository {
+	return &IdempotencyRepository{db: u.db}
+}
+
+func (u *UnitOfWork) Users() *UserRepository {
+	return &UserRepository{db: u.db}
+}
+
+// Do 在事务中执行 fn, fn 返回错误时回滚. 在事务内调用时使用 SAVEPOINT, 只回滚 fn 中的修改.
+// 最外层事务遇到 SQLITE_BUSY 时按指数退避重新执行整个 fn, 因此 fn 不能有数据库之外的副作用,
+// 发布事件等操作应放在 Do 返回之后
+func (u *UnitOfWork) Do(ctx context.Context, fn func(tx *UnitOfWork) error) error {
+	db, done := withContext(ctx, u.db)
+	run := func() error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			return fn(&UnitOfWork{db: tx, quota: u.quota})
+		})
+	}
+
+	// 嵌套事务中的 SQLITE_BUSY 交给最外层重试
+	if inTransaction(u.db) {
+		return done(u.savepoint(db, fn))
+	}
+
+	wait := busyBackoff
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if attempt == busyRetries || !isBusy(err) {
+			return done(err)
+		}
+
+		timer := time.NewTimer(wait/2 + time.Duration(rand.Int63n(int64(wait))))
+		select {
+		case <-db.Statement.Context.Done():
+			timer.Stop()
+			return done(err)
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+}
+
+var savepointSeq uint64
+
+// savepoint 在 SAVEPOINT 中执行 fn. 不使用 gorm 的嵌套事务, 因为 SQLite 驱动会忽略
+// SAVEPOINT 和 ROLLBACK TO 的错误, 失败时 fn 的修改可能被当作成功提交
+func (u *UnitOfWork) savepoint(db *gorm.DB, fn func(tx *UnitOfWork) error) (err error) {
+	name := fmt.Sprintf("sp%d", atomic.AddUint64(&savepointSeq, 1))
+	if err := db.Exec("SAVEPOINT " + name).Error; err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	panicked := true
+	defer func() {
+		if !panicked && err == nil {
+			return
+		}
+		if rbErr := db.Exec("ROLLBACK TO SAVEPOINT " + name).Error; rbErr != nil && !panicked {
+			err = fmt.Errorf("failed to roll back savepoint: %w", rbErr)
+		}
+	}()
+
+	err = fn(&UnitOfWork{db: db.Session(&gorm.Session{}), quota: u.quota})
+	panicked = false
+	if err != nil {
+		return err
+	}
+	if err := db.Exec("RELEASE SAVEPOINT " + name).Error; err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+func inTransaction(db *gorm.DB) bool {
+	committer, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok && committer != nil
+}
+
+// isBusy 判断 err 是否是数据库被其他连接锁定导致的错误, 这类错误重试后可能成功
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
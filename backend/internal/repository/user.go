@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"todo-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var ErrUserExists = errors.New("user already exists")
+
+type UserRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	db, done := withContext(ctx, r.db)
+	var user model.User
+	if err := done(db.Where("username = ?", username).First(&user).Error); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	db, done := withContext(ctx, r.db)
+	err := done(db.Create(user).Error)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrUserExists
+	}
+	return err
+}
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, id uint, passwordHash string) error {
+	db, done := withContext(ctx, r.db)
+	return done(db.Model(&model.User{}).Where("id = ?", id).Update("password_hash", passwordHash).Error)
+}
+
+func (r *UserRepository) Count(ctx context.Context) (int64, error) {
+	db, done := withContext(ctx, r.db)
+	var count int64
+	err := db.Model(&model.User{}).Count(&count).Error
+	return count, done(err)
+}
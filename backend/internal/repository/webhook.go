@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"todo-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) GetAll(ctx context.Context) ([]model.Webhook, error) {
+	db, done := withContext(ctx, r.db)
+	var webhooks []model.Webhook
+	err := db.Order("id").Find(&webhooks).Error
+	return webhooks, done(err)
+}
+
+func (r *WebhookRepository) GetActive(ctx context.Context) ([]model.Webhook, error) {
+	db, done := withContext(ctx, r.db)
+	var webhooks []model.Webhook
+	err := db.Where("active = ?", true).Order("id").Find(&webhooks).Error
+	return webhooks, done(err)
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id uint) (*model.Webhook, error) {
+	db, done := withContext(ctx, r.db)
+	var webhook model.Webhook
+	if err := done(db.First(&webhook, id).Error); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, webhook *model.Webhook) error {
+	db, done := withContext(ctx, r.db)
+	return done(db.Create(webhook).Error)
+}
+
+func (r *WebhookRepository) Save(ctx context.Context, webhook *model.Webhook) error {
+	db, done := withContext(ctx, r.db)
+	return done(db.Save(webhook).Error)
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id uint) error {
+	return NewUnitOfWork(r.db).Do(ctx, func(tx *UnitOfWork) error {
+		if err := tx.db.Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.db.Delete(&model.Webhook{}, id).Error
+	})
+}
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	db, done := withContext(ctx, r.db)
+	return done(db.Create(delivery).Error)
+}
+
+func (r *WebhookRepository) SaveDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	db, done := withContext(ctx, r.db)
+	return done(db.Save(delivery).Error)
+}
+
+// DueDeliveries 返回已到重试时间的待投递记录
+func (r *WebhookRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	db, done := withContext(ctx, r.db)
+	var deliveries []model.WebhookDelivery
+	err := db.
+		Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, done(err)
+}
+
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, webhookID uint, limit int) ([]model.WebhookDelivery, error) {
+	db, done := withContext(ctx, r.db)
+	var deliveries []model.WebhookDelivery
+	err := db.Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, done(err)
+}
+
+func (r *WebhookRepository) GetDelivery(ctx context.Context, id uint) (*model.WebhookDelivery, error) {
+	db, done := withContext(ctx, r.db)
+	var delivery model.WebhookDelivery
+	if err := done(db.First(&delivery, id).Error); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
@@ -0,0 +1,17 @@
+package server
+
+import (
+	"todo-backend/internal/config"
+	"todo-backend/internal/database"
+)
+
+// DatabaseOptions 把配置中的 SQLite 设置转换为 database.Options
+func DatabaseOptions(cfg *config.Config) database.Options {
+	opts := database.DefaultOptions()
+	opts.JournalMode = cfg.DBJournalMode
+	opts.Synchronous = cfg.DBSynchronous
+	opts.BusyTimeout = cfg.DBBusyTimeout
+	opts.ForeignKeys = cfg.DBForeignKeys
+	opts.ReadConns = cfg.DBReadConns
+	return opts
+}
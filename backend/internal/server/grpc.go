@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"net"
+
+	"todo-backend/internal/config"
+	"todo-backend/internal/handler"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
+	todov1 "todo-backend/proto/todo/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewGRPCServer 创建注册了 TodoService 和反射服务的 gRPC 服务, 与 HTTP 路由共用数据库和事件中心
+func NewGRPCServer(deps Deps) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(queryTimeoutInterceptor(deps.Config), quotaOwnerInterceptor()))
+	todov1.RegisterTodoServiceServer(server, handler.NewTodoGRPCServer(deps.DB, deps.Hub, todoQuota(deps.Config), validation.New(ValidationRules(deps.Config))))
+	reflection.Register(server)
+	return server
+}
+
+// queryTimeoutInterceptor 与 HTTP 的 middleware.QueryTimeout 相同, 限制一次调用中数据库查询的总时间
+func queryTimeoutInterceptor(cfg *config.Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		if cfg.QueryTimeout > 0 {
+			ctx = repository.WithQueryTimeout(ctx, cfg.QueryTimeout)
+		}
+		return next(ctx, req)
+	}
+}
+
+// quotaOwnerInterceptor 与 HTTP 的 middleware.QuotaOwner 相同, gRPC 没有认证, 按客户端 IP 计算配额
+func quotaOwnerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		if p, ok := peer.FromContext(ctx); ok {
+			host, _, err := net.SplitHostPort(p.Addr.String())
+			if err != nil {
+				host = p.Addr.String()
+			}
+			ctx = repository.WithOwner(ctx, "ip:"+host)
+		}
+		return next(ctx, req)
+	}
+}
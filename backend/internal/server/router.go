@@ -0,0 +1,193 @@
+package server
+
+import (
+	"fmt"
+
+	"todo-backend/internal/backup"
+	"todo-backend/internal/config"
+	"todo-backend/internal/events"
+	"todo-backend/internal/handler"
+	"todo-backend/internal/middleware"
+	"todo-backend/internal/openapi"
+	"todo-backend/internal/ratelimit"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/validation"
+	"todo-backend/internal/web"
+	"todo-backend/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Deps 是路由依赖的外部状态, 由 serve 命令和测试各自创建.
+// 后台任务 (webhook 投递、定时备份) 的启动和停止由调用方负责
+type Deps struct {
+	Config     *config.Config
+	DB         *gorm.DB
+	Hub        *events.Hub
+	Dispatcher *webhook.Dispatcher
+	Backups    *backup.Manager
+}
+
+// NewRouter 创建包含全部中间件和路由的 Gin 引擎
+func NewRouter(deps Deps) (*gin.Engine, error) {
+	cfg := deps.Config
+
+	r := gin.New()
+	if gin.Mode() != gin.TestMode {
+		// 日历订阅源的 token 通过查询参数传递, 不能出现在访问日志中
+		r.Use(middleware.Logger(gin.DefaultWriter, "token"))
+	}
+	r.Use(middleware.Recovery())
+
+	// 添加 CORS 中间件
+	r.Use(middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins:   cfg.CORSOrigins,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	}))
+
+	rateLimit, err := rateLimitOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TODO_TRUSTED_PROXIES: %w", err)
+	}
+	r.Use(middleware.QueryTimeout(cfg.QueryTimeout))
+
+	// 频率限制和配额按调用方计算, 每个路由组都放在自己的认证之后, 已认证的请求才能按用户或 token 计数.
+	// 所有路由组共用同一组令牌桶; 在 CORS 之后, 429 响应也带有跨域头
+	client := []gin.HandlerFunc{middleware.RateLimit(rateLimit), middleware.QuotaOwner()}
+
+	// 初始化路由
+	checkOrigin := middleware.WebSocketOrigin(cfg.CORSOrigins)
+	quota := todoQuota(cfg)
+	rules := ValidationRules(cfg)
+	validator := validation.New(rules)
+	todoHandler := handler.NewTodoHandler(deps.DB, deps.Hub, quota, validator)
+	eventHandler := handler.NewEventHandler(deps.Hub)
+	realtimeHandler := handler.NewRealtimeHandler(deps.DB, deps.Hub, quota, validator, checkOrigin)
+	webhookHandler := handler.NewWebhookHandler(deps.DB, deps.Dispatcher, validator)
+	syncHandler := handler.NewSyncHandler(deps.DB, deps.Hub, quota, validator)
+	exportHandler := handler.NewExportHandler(deps.DB)
+	calendarHandler := handler.NewCalendarHandler(deps.DB, cfg.CalendarToken, cfg.CalendarDomain)
+	docsHandler := handler.NewDocsHandler(openapi.Build(rules))
+	graphqlHandler := handler.NewGraphQLHandler(deps.DB, deps.Hub, quota, validator, cfg.GraphQLMaxDepth, cfg.GraphQLMaxComplexity, checkOrigin)
+	importHandler := handler.NewImportHandler(deps.DB, deps.Hub, quota, validator, cfg.ImportMaxBytes, cfg.ImportMaxRows)
+	backupHandler := handler.NewBackupHandler(deps.Backups, cfg.AdminToken)
+	api := r.Group("/api", client...)
+	api.Use(middleware.Idempotency(repository.NewIdempotencyRepository(deps.DB), middleware.IdempotencyOptions{
+		TTL:           cfg.IdempotencyTTL,
+		MaxBodyBytes:  rules.MaxBodyBytes,
+		RouteMaxBytes: map[string]int64{"/api/import": cfg.ImportMaxBytes},
+	}))
+	{
+		api.GET("/todos", todoHandler.GetAllTodos)
+		api.GET("/todos/events", eventHandler.StreamTodoEvents)
+		api.GET("/todos/:id", todoHandler.GetTodoByID)
+		api.POST("/todos", todoHandler.CreateTodo)
+		api.PUT("/todos/:id", todoHandler.UpdateTodo)
+		api.DELETE("/todos/:id", todoHandler.DeleteTodo)
+		api.DELETE("/todos", todoHandler.DeleteTodos)
+		api.POST("/todos/batch", todoHandler.BatchTodos)
+		api.POST("/todos/complete-all", todoHandler.CompleteAllTodos)
+		api.GET("/ws", realtimeHandler.Connect)
+		api.GET("/sync", syncHandler.GetChanges)
+		api.POST("/sync", syncHandler.PushChanges)
+		api.GET("/export", exportHandler.ExportTodos)
+		api.POST("/import", importHandler.ImportTodos)
+		api.GET("/calendar.ics", calendarHandler.GetCalendar)
+		api.GET("/openapi.json", docsHandler.GetSpec)
+		api.GET("/docs", docsHandler.GetDocs)
+
+		api.GET("/webhooks", webhookHandler.GetAllWebhooks)
+		api.POST("/webhooks", webhookHandler.CreateWebhook)
+		api.GET("/webhooks/:id", webhookHandler.GetWebhookByID)
+		api.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
+		api.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+		api.GET("/webhooks/:id/deliveries", webhookHandler.GetDeliveries)
+		api.POST("/webhooks/:id/test", webhookHandler.SendTestEvent)
+	}
+
+	admin := r.Group("/api/admin", backupHandler.Authorize)
+	admin.Use(client...)
+	{
+		admin.GET("/backups", backupHandler.GetBackups)
+		admin.POST("/backups", backupHandler.CreateBackup)
+	}
+
+	graphql := r.Group("/graphql", client...)
+	{
+		graphql.GET("", graphqlHandler.Serve)
+		graphql.POST("", graphqlHandler.Serve)
+	}
+
+	// CalDAV 只在配置了账号时启用
+	if cfg.CalDAVUsername != "" && cfg.CalDAVPassword != "" {
+		caldavHandler := handler.NewCalDAVHandler(deps.DB, deps.Hub, quota, validator, cfg.CalendarDomain)
+		wellKnown := r.Group("/.well-known/caldav", client...)
+		wellKnown.GET("", caldavHandler.WellKnown)
+		wellKnown.Handle("PROPFIND", "", caldavHandler.WellKnown)
+		caldav := r.Group("/caldav", gin.BasicAuthForRealm(gin.Accounts{cfg.CalDAVUsername: cfg.CalDAVPassword}, "todo"))
+		caldav.Use(client...)
+		for _, method := range handler.CalDAVMethods {
+			caldav.Handle(method, "/*path", caldavHandler.ServeDAV)
+		}
+	}
+
+	// 前端: 开发时代理到 Vite, 否则使用 embedfrontend 标签编译进程序的构建产物
+	if cfg.FrontendDevURL != "" {
+		proxy, err := web.DevProxy(cfg.FrontendDevURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TODO_FRONTEND_DEV_URL: %w", err)
+		}
+		r.NoRoute(append(client, proxy)...)
+	} else if assets, ok := web.Assets(); ok {
+		frontend, err := web.NewHandler(assets)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load frontend: %w", err)
+		}
+		r.NoRoute(append(client, frontend.Serve)...)
+	} else {
+		r.NoRoute(client...)
+	}
+
+	return r, nil
+}
+
+// todoQuota 返回所有写入 Todo 的接口共用的配额
+func todoQuota(cfg *config.Config) repository.Quota {
+	return repository.Quota{
+		MaxTodos:        cfg.QuotaMaxTodos,
+		MaxStorageBytes: cfg.QuotaMaxStorageBytes,
+	}
+}
+
+// ValidationRules 返回 cfg 中的请求校验规则, 每个路由实例各自创建校验器
+func ValidationRules(cfg *config.Config) validation.Rules {
+	return validation.Rules{
+		TitleMaxLength:   cfg.TitleMaxLength,
+		ContentMaxLength: cfg.ContentMaxLength,
+		MaxBodyBytes:     cfg.MaxBodyBytes,
+	}
+}
+
+func rateLimitOptions(cfg *config.Config) (middleware.RateLimitOptions, error) {
+	var opts middleware.RateLimitOptions
+	if cfg.RateLimit == "off" {
+		return opts, nil
+	}
+
+	limit, err := ratelimit.ParseLimit(cfg.RateLimit)
+	if err != nil {
+		return opts, fmt.Errorf("invalid TODO_RATE_LIMIT: %w", err)
+	}
+	routes, err := middleware.ParseRouteLimits(cfg.RateLimitRoutes)
+	if err != nil {
+		return opts, fmt.Errorf("invalid TODO_RATE_LIMIT_ROUTES: %w", err)
+	}
+	opts.Default = limit
+	opts.Routes = routes
+	return opts, nil
+}
@@ -0,0 +1,50 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+)
+
+// TodoOption 修改工厂创建的 Todo
+type TodoOption func(todo *model.Todo)
+
+func WithContent(content string) TodoOption {
+	return func(todo *model.Todo) { todo.Content = content }
+}
+
+func Completed() TodoOption {
+	return func(todo *model.Todo) { todo.Completed = true }
+}
+
+func DueAt(due time.Time) TodoOption {
+	return func(todo *model.Todo) { todo.DueAt = &due }
+}
+
+// CreateTodo 直接通过仓库写入一条 Todo, 不经过 HTTP 接口, 也不发布事件
+func (s *Server) CreateTodo(t testing.TB, title string, opts ...TodoOption) *model.Todo {
+	t.Helper()
+
+	todo := &model.Todo{Title: title}
+	for _, opt := range opts {
+		opt(todo)
+	}
+	if err := repository.NewTodoRepository(s.DB).Create(context.Background(), todo); err != nil {
+		t.Fatalf("Failed to create todo %q: %v", title, err)
+	}
+	return todo
+}
+
+// LoadFixtures 写入一组常用的 Todo: 一条普通的未完成、一条已过期的未完成和一条已完成, 按此顺序返回
+func (s *Server) LoadFixtures(t testing.TB) []*model.Todo {
+	t.Helper()
+
+	return []*model.Todo{
+		s.CreateTodo(t, "Buy milk", WithContent("2 liters")),
+		s.CreateTodo(t, "File taxes", DueAt(time.Now().Add(-24*time.Hour))),
+		s.CreateTodo(t, "Write report", Completed()),
+	}
+}
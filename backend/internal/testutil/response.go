@@ -0,0 +1,127 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"todo-backend/internal/model"
+)
+
+// Response 是已读取完响应体的 HTTP 响应
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Request 发送请求, body 不为 nil 时编码为 JSON
+func (s *Server) Request(t testing.TB, method, path string, body interface{}) *Response {
+	t.Helper()
+
+	resp, err := s.TryRequest(method, path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// TryRequest 与 Request 相同, 但返回错误而不是调用 t.Fatal, 可以在测试以外的 goroutine 中使用
+func (s *Server) TryRequest(method, path string, body interface{}) (*Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return s.TryDo(req)
+}
+
+// Do 发送自定义的请求并读取整个响应体
+func (s *Server) Do(t testing.TB, req *http.Request) *Response {
+	t.Helper()
+
+	resp, err := s.TryDo(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// TryDo 与 Do 相同, 但返回错误而不是调用 t.Fatal
+func (s *Server) TryDo(req *http.Request) (*Response, error) {
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}, nil
+}
+
+// Envelope 把响应体解码为统一响应格式, data 不为 nil 时 Data 字段解码到 data 中
+func (r *Response) Envelope(t testing.TB, data interface{}) model.Response {
+	t.Helper()
+
+	envelope, err := r.DecodeEnvelope(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return envelope
+}
+
+// DecodeEnvelope 与 Envelope 相同, 但返回错误而不是调用 t.Fatal
+func (r *Response) DecodeEnvelope(data interface{}) (model.Response, error) {
+	envelope := model.Response{Data: data}
+	if err := json.Unmarshal(r.Body, &envelope); err != nil {
+		return envelope, fmt.Errorf("failed to decode response envelope: %w\n%s", err, r.Body)
+	}
+	return envelope, nil
+}
+
+// AssertSuccess 检查状态码和成功响应的 code、message, 并把 data 解码到 out 中
+func AssertSuccess(t testing.TB, resp *Response, status int, out interface{}) {
+	t.Helper()
+
+	if resp.StatusCode != status {
+		t.Fatalf("Expected status %d, got %d: %s", status, resp.StatusCode, resp.Body)
+	}
+	envelope := resp.Envelope(t, out)
+	if envelope.Code != 0 {
+		t.Errorf("Expected code 0, got %d", envelope.Code)
+	}
+	if envelope.Message != "success" {
+		t.Errorf("Expected message 'success', got %q", envelope.Message)
+	}
+}
+
+// AssertError 检查错误响应的状态码以及 code 与状态码一致, 返回错误信息
+func AssertError(t testing.TB, resp *Response, status int) string {
+	t.Helper()
+
+	if resp.StatusCode != status {
+		t.Fatalf("Expected status %d, got %d: %s", status, resp.StatusCode, resp.Body)
+	}
+	envelope := resp.Envelope(t, nil)
+	if envelope.Code != status {
+		t.Errorf("Expected code %d, got %d", status, envelope.Code)
+	}
+	return envelope.Message
+}
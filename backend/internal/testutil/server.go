@@ -0,0 +1,198 @@
+// Package testutil 为测试启动互相隔离的服务: 每个服务使用独立的内存数据库、事件中心和备份目录,
+// 测试之间不共享状态, 可以调用 t.Parallel().
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"todo-backend/internal/backup"
+	"todo-backend/internal/config"
+	"todo-backend/internal/database"
+	"todo-backend/internal/events"
+	"todo-backend/internal/middleware"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/server"
+	"todo-backend/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var dbSeq uint64
+
+// Option 在启动服务前修改测试配置
+type Option func(cfg *config.Config)
+
+// Config 返回测试使用的配置: 不读取环境变量, 关闭频率限制, 缩短 webhook 的轮询和重试间隔
+func Config() *config.Config {
+	return &config.Config{
+		DBJournalMode: "WAL",
+		DBSynchronous: "NORMAL",
+		DBBusyTimeout: 5 * time.Second,
+		DBForeignKeys: true,
+		DBReadConns:   4,
+
+		BackupKeep: 2,
+
+		CORSOrigins: []string{"*"},
+		CORSMaxAge:  10 * time.Minute,
+
+		RateLimit: "off",
+
+		TitleMaxLength:             200,
+		ContentMaxLength:           10000,
+		MaxBodyBytes:               1 << 20,
+		IdempotencyTTL:             time.Hour,
+		IdempotencyCleanupInterval: time.Minute,
+		EventLogSize:               1000,
+
+		QueryTimeout: 5 * time.Second,
+
+		WebhookMaxAttempts:  3,
+		WebhookTimeout:      time.Second,
+		WebhookPollInterval: 20 * time.Millisecond,
+		WebhookBackoff:      20 * time.Millisecond,
+		// 测试中的接收端运行在 127.0.0.1
+		WebhookAllowPrivate: true,
+
+		ImportMaxBytes: 1 << 20,
+		ImportMaxRows:  100,
+
+		CalendarDomain: "todo.test",
+
+		GraphQLMaxDepth:      8,
+		GraphQLMaxComplexity: 1000,
+
+		GRPCAddr: "off",
+	}
+}
+
+// OpenDB 按 cfg 的 SQLite 设置创建一个已迁移的数据库. DatabasePath 为空时使用 memdb VFS 的内存数据库,
+// 同一个 *gorm.DB 的多个连接看到同一份数据, 不同的调用之间互不可见
+func OpenDB(cfg *config.Config) (*gorm.DB, error) {
+	dsn := cfg.DatabasePath
+	if dsn == "" {
+		dsn = fmt.Sprintf("file:/testutil-%d?vfs=memdb", atomic.AddUint64(&dbSeq, 1))
+	}
+	db, err := database.Connect(dsn, server.DatabaseOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	if err := database.Migrate(db); err != nil {
+		database.CloseDB(db)
+		return nil, err
+	}
+	return db, nil
+}
+
+// Server 是运行中的测试服务, 嵌入的 httptest.Server 提供 URL 和 Client
+type Server struct {
+	*httptest.Server
+
+	Router  *gin.Engine
+	Deps    server.Deps
+	DB      *gorm.DB
+	Hub     *events.Hub
+	Backups *backup.Manager
+
+	backupDir string
+	cancel    context.CancelFunc
+}
+
+// NewServer 为当前测试启动服务, 测试结束时自动关闭
+func NewServer(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	s, err := Start(opts...)
+	if err != nil {
+		t.Fatalf("Failed to start test server: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Start 启动服务, 调用方负责 Close. 测试中使用 NewServer, 由测试结束时自动关闭
+func Start(opts ...Option) (*Server, error) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := Config()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	// 没有指定备份目录时使用临时目录, 关闭服务时删除
+	var backupDir string
+	if cfg.BackupDir == "" {
+		dir, err := os.MkdirTemp("", "todo-test-backups")
+		if err != nil {
+			return nil, err
+		}
+		backupDir = dir
+		cfg.BackupDir = dir
+	}
+
+	db, err := OpenDB(cfg)
+	if err != nil {
+		os.RemoveAll(backupDir)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hub := events.NewHub(cfg.EventLogSize)
+	dispatcher := webhook.NewDispatcher(repository.NewWebhookRepository(db), hub, webhook.Options{
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		Timeout:      cfg.WebhookTimeout,
+		PollInterval: cfg.WebhookPollInterval,
+		BaseBackoff:  cfg.WebhookBackoff,
+		MaxBackoff:   5 * cfg.WebhookBackoff,
+
+		AllowPrivateNetworks: cfg.WebhookAllowPrivate,
+	})
+	dispatcher.Start(ctx)
+	if cfg.IdempotencyCleanupInterval > 0 {
+		middleware.CleanupIdempotencyKeys(ctx, repository.NewIdempotencyRepository(db), cfg.IdempotencyCleanupInterval)
+	}
+
+	deps := server.Deps{
+		Config:     cfg,
+		DB:         db,
+		Hub:        hub,
+		Dispatcher: dispatcher,
+		Backups:    backup.NewManager(db, cfg.BackupDir, cfg.BackupKeep),
+	}
+	r, err := server.NewRouter(deps)
+	if err != nil {
+		cancel()
+		database.CloseDB(db)
+		os.RemoveAll(backupDir)
+		return nil, err
+	}
+
+	return &Server{
+		Server:    httptest.NewServer(r),
+		Router:    r,
+		Deps:      deps,
+		DB:        db,
+		Hub:       hub,
+		Backups:   deps.Backups,
+		backupDir: backupDir,
+		cancel:    cancel,
+	}, nil
+}
+
+// Close 断开仍在进行的长连接 (SSE、WebSocket), 停止 webhook 投递, 释放内存数据库并删除备份目录
+func (s *Server) Close() {
+	s.CloseClientConnections()
+	s.Server.Close()
+	s.cancel()
+	database.CloseDB(s.DB)
+	if s.backupDir != "" {
+		os.RemoveAll(s.backupDir)
+	}
+}
@@ -0,0 +1,163 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrEmptyBody    = errors.New("request body is empty")
+	ErrBodyTooLarge = errors.New("request body too large")
+	ErrInvalidUTF8  = errors.New("request body is not valid UTF-8")
+	ErrTrailingData = errors.New("request body must contain a single JSON value")
+)
+
+type UnknownFieldError struct {
+	Field string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", e.Field)
+}
+
+type SyntaxError struct {
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	return "invalid JSON: " + e.Err.Error()
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// BindJSON 限制请求体大小, 拒绝非法 UTF-8 和未知字段, 然后规范化并校验
+func (v *Validator) BindJSON(c *gin.Context, obj interface{}) error {
+	if err := v.DecodeJSON(c.Writer, c.Request, obj); err != nil {
+		return err
+	}
+	return v.Validate(obj)
+}
+
+func (v *Validator) DecodeJSON(w http.ResponseWriter, r *http.Request, obj interface{}) error {
+	body, err := v.ReadBody(w, r)
+	if err != nil {
+		return err
+	}
+	return Unmarshal(body, obj)
+}
+
+func (v *Validator) ReadBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	return ReadBodyLimit(w, r, v.rules.MaxBodyBytes)
+}
+
+// ReadBodyLimit 与 ReadBody 相同, 但使用 limit 代替规则中的 MaxBodyBytes, 用于有单独上限的路由
+func ReadBodyLimit(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	if r.Body == nil {
+		return nil, ErrEmptyBody
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, ErrBodyTooLarge
+		}
+		return nil, err
+	}
+	return body, nil
+}
+
+func Unmarshal(data []byte, obj interface{}) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return ErrEmptyBody
+	}
+	if !utf8.Valid(data) {
+		return ErrInvalidUTF8
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(obj); err != nil {
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return &UnknownFieldError{Field: strings.Trim(field, `"`)}
+		}
+		return &SyntaxError{Err: err}
+	}
+	if dec.More() {
+		return ErrTrailingData
+	}
+	return nil
+}
+
+// StatusCode 返回绑定错误对应的 HTTP 状态码
+func StatusCode(err error) int {
+	if errors.Is(err, ErrBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+var bodyMessages = map[string]map[error]string{
+	"en": {
+		ErrEmptyBody:    "request body is empty",
+		ErrBodyTooLarge: "request body must not exceed %d bytes",
+		ErrInvalidUTF8:  "request body is not valid UTF-8",
+		ErrTrailingData: "request body must contain a single JSON value",
+	},
+	"zh": {
+		ErrEmptyBody:    "请求体不能为空",
+		ErrBodyTooLarge: "请求体不能超过 %d 字节",
+		ErrInvalidUTF8:  "请求体不是合法的 UTF-8 编码",
+		ErrTrailingData: "请求体只能包含一个 JSON 值",
+	},
+}
+
+// BodyTooLargeMessage 返回请求体超过 limit 字节时的提示
+func BodyTooLargeMessage(limit int64, locale string) string {
+	messages, ok := bodyMessages[locale]
+	if !ok {
+		messages = bodyMessages["en"]
+	}
+	return fmt.Sprintf(messages[ErrBodyTooLarge], limit)
+}
+
+// BodyMessage 把读取或解析请求体的错误转换为指定语言的提示, limit 是请求体的上限
+func BodyMessage(err error, locale string, limit int64) string {
+	messages, ok := bodyMessages[locale]
+	if !ok {
+		messages = bodyMessages["en"]
+	}
+
+	for target, msg := range messages {
+		if errors.Is(err, target) {
+			if target == ErrBodyTooLarge {
+				return BodyTooLargeMessage(limit, locale)
+			}
+			return msg
+		}
+	}
+
+	var unknown *UnknownFieldError
+	if errors.As(err, &unknown) {
+		if locale == "zh" {
+			return fmt.Sprintf("未知字段 %q", unknown.Field)
+		}
+		return unknown.Error()
+	}
+
+	var syntax *SyntaxError
+	if errors.As(err, &syntax) && locale == "zh" {
+		return "JSON 格式错误: " + syntax.Err.Error()
+	}
+
+	return err.Error()
+}
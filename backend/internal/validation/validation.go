@@ -0,0 +1,159 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	zh_translations "github.com/go-playground/validator/v10/translations/zh"
+)
+
+type Rules struct {
+	TitleMaxLength   int
+	ContentMaxLength int
+	MaxBodyBytes     int64
+}
+
+func DefaultRules() Rules {
+	return Rules{
+		TitleMaxLength:   200,
+		ContentMaxLength: 10000,
+		MaxBodyBytes:     1 << 20,
+	}
+}
+
+// Validator 按一组 Rules 规范化并校验请求, 每个服务实例各自创建, 互不影响
+type Validator struct {
+	rules    Rules
+	validate *validator.Validate
+	uni      *ut.UniversalTranslator
+}
+
+// New 创建使用 rules 的校验器, 注册自定义规则和中英文翻译
+func New(rules Rules) *Validator {
+	v := &Validator{rules: rules, validate: validator.New()}
+	v.validate.SetTagName("binding")
+	v.validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+
+	v.validate.RegisterValidation("maxlen", func(fl validator.FieldLevel) bool {
+		limit := v.maxLength(fl.Param())
+		return limit <= 0 || utf8.RuneCountInString(fl.Field().String()) <= limit
+	})
+	v.validate.RegisterValidation("singleline", func(fl validator.FieldLevel) bool {
+		return !strings.ContainsFunc(fl.Field().String(), unicode.IsControl)
+	})
+	v.validate.RegisterValidation("multiline", func(fl validator.FieldLevel) bool {
+		return !strings.ContainsFunc(fl.Field().String(), func(r rune) bool {
+			return unicode.IsControl(r) && r != '\n' && r != '\t'
+		})
+	})
+
+	enLocale := en.New()
+	v.uni = ut.New(enLocale, enLocale, zh.New())
+
+	enTrans, _ := v.uni.GetTranslator("en")
+	en_translations.RegisterDefaultTranslations(v.validate, enTrans)
+	v.registerTranslations(enTrans, map[string]string{
+		"maxlen":     "{0} must be at most {1} characters long",
+		"singleline": "{0} must not contain control characters or line breaks",
+		"multiline":  "{0} must not contain control characters",
+	})
+
+	zhTrans, _ := v.uni.GetTranslator("zh")
+	zh_translations.RegisterDefaultTranslations(v.validate, zhTrans)
+	v.registerTranslations(zhTrans, map[string]string{
+		"maxlen":     "{0}长度不能超过{1}个字符",
+		"singleline": "{0}不能包含控制字符或换行",
+		"multiline":  "{0}不能包含控制字符",
+	})
+
+	return v
+}
+
+func (v *Validator) Rules() Rules {
+	return v.rules
+}
+
+func (v *Validator) maxLength(name string) int {
+	switch name {
+	case "title":
+		return v.rules.TitleMaxLength
+	case "content":
+		return v.rules.ContentMaxLength
+	}
+	return 0
+}
+
+type normalizer interface {
+	Normalize()
+}
+
+// Validate 先规范化请求再按 binding 标签校验
+func (v *Validator) Validate(obj interface{}) error {
+	if n, ok := obj.(normalizer); ok {
+		n.Normalize()
+	}
+	return v.validate.Struct(obj)
+}
+
+func (v *Validator) registerTranslations(trans ut.Translator, messages map[string]string) {
+	for tag, text := range messages {
+		tag, text := tag, text
+		v.validate.RegisterTranslation(tag, trans, func(t ut.Translator) error {
+			return t.Add(tag, text, true)
+		}, func(t ut.Translator, fe validator.FieldError) string {
+			param := fe.Param()
+			if tag == "maxlen" {
+				param = strconv.Itoa(v.maxLength(param))
+			}
+			msg, err := t.T(tag, fe.Field(), param)
+			if err != nil {
+				return fe.Error()
+			}
+			return msg
+		})
+	}
+}
+
+// Message 把绑定或校验错误转换为指定语言的提示
+func (v *Validator) Message(err error, locale string) string {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		trans, _ := v.uni.GetTranslator(locale)
+		msgs := make([]string, 0, len(verrs))
+		for _, fe := range verrs {
+			msgs = append(msgs, fe.Translate(trans))
+		}
+		return strings.Join(msgs, "; ")
+	}
+
+	return BodyMessage(err, locale, v.rules.MaxBodyBytes)
+}
+
+// Locale 根据 Accept-Language 选择 en 或 zh, 默认 en
+func Locale(acceptLanguage string) string {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		switch {
+		case strings.HasPrefix(tag, "zh"):
+			return "zh"
+		case strings.HasPrefix(tag, "en"):
+			return "en"
+		}
+	}
+	return "en"
+}
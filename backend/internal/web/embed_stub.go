@@ -0,0 +1,10 @@
+//go:build !embedfrontend
+
+package web
+
+import "io/fs"
+
+// Assets 在未使用 embedfrontend 标签编译时不提供前端
+func Assets() (fs.FS, bool) {
+	return nil, false
+}
@@ -0,0 +1,4 @@
+package web
+
+// 把 frontend 的构建产物复制到 dist, 之后使用 -tags embedfrontend 编译
+//go:generate sh -c "rm -rf dist && cp -r ../../../frontend/dist dist"
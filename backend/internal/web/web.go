@@ -0,0 +1,200 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"todo-backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	indexFile = "index.html"
+
+	// Vite 把带内容哈希的文件放在 assets 目录, 内容变化时文件名也会变化, 可以永久缓存
+	hashedDir       = "assets/"
+	immutableCache  = "public, max-age=31536000, immutable"
+	revalidateCache = "no-cache"
+)
+
+// encodings 按优先级排列, 与构建时生成的预压缩文件后缀对应
+var encodings = []struct {
+	name   string
+	suffix string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// apiPrefixes 下的路径不属于前端, 找不到路由时返回 JSON 而不是 index.html
+var apiPrefixes = []string{"/api/", "/graphql", "/caldav", "/.well-known/"}
+
+type asset struct {
+	data    []byte
+	hash    string
+	ctype   string
+	variant map[string][]byte
+}
+
+// Handler 提供前端静态文件, 找不到的页面路径返回 index.html, 由前端路由处理
+type Handler struct {
+	assets map[string]*asset
+}
+
+// NewHandler 在启动时读取 fsys 中的全部文件并计算 ETag
+func NewHandler(fsys fs.FS) (*Handler, error) {
+	h := &Handler{assets: make(map[string]*asset)}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		for _, enc := range encodings {
+			if strings.HasSuffix(name, enc.suffix) {
+				return nil
+			}
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		a := &asset{
+			data:    data,
+			hash:    hex.EncodeToString(sum[:8]),
+			ctype:   contentType(name, data),
+			variant: make(map[string][]byte),
+		}
+		for _, enc := range encodings {
+			if compressed, err := fs.ReadFile(fsys, name+enc.suffix); err == nil {
+				a.variant[enc.name] = compressed
+			}
+		}
+		h.assets[name] = a
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Serve 作为 NoRoute 处理器使用
+func (h *Handler) Serve(c *gin.Context) {
+	if !acceptsFrontend(c) {
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean(c.Request.URL.Path), "/")
+	if name == "" {
+		name = indexFile
+	}
+
+	a, ok := h.assets[name]
+	if !ok {
+		// 带扩展名的路径是缺失的静态文件, 其余交给前端路由
+		if path.Ext(name) != "" {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		name = indexFile
+		if a, ok = h.assets[name]; !ok {
+			c.Status(http.StatusNotFound)
+			return
+		}
+	}
+
+	header := c.Writer.Header()
+	if strings.HasPrefix(name, hashedDir) {
+		header.Set("Cache-Control", immutableCache)
+	} else {
+		header.Set("Cache-Control", revalidateCache)
+	}
+	header.Set("Content-Type", a.ctype)
+
+	// 压缩后的内容不同, ETag 中带上编码
+	data, etag := a.data, a.hash
+	if len(a.variant) > 0 {
+		header.Add("Vary", "Accept-Encoding")
+		accepted := acceptedEncodings(c.GetHeader("Accept-Encoding"))
+		for _, enc := range encodings {
+			if compressed, ok := a.variant[enc.name]; ok && accepted[enc.name] {
+				header.Set("Content-Encoding", enc.name)
+				data, etag = compressed, a.hash+"-"+enc.name
+				break
+			}
+		}
+	}
+	header.Set("ETag", `"`+etag+`"`)
+
+	// ServeContent 处理 If-None-Match、Range 和 HEAD
+	http.ServeContent(c.Writer, c.Request, name, time.Time{}, bytes.NewReader(data))
+}
+
+// DevProxy 把前端请求转发到 Vite 开发服务器, 包括热更新使用的 WebSocket
+func DevProxy(target string) (gin.HandlerFunc, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	proxy := httputil.NewSingleHostReverseProxy(u)
+
+	return func(c *gin.Context) {
+		if !acceptsFrontend(c) {
+			return
+		}
+		proxy.ServeHTTP(c.Writer, c.Request)
+	}, nil
+}
+
+// acceptsFrontend 对接口路径和非 GET 请求返回统一格式的 404, 并返回 false
+func acceptsFrontend(c *gin.Context) bool {
+	p := c.Request.URL.Path
+	isAPI := false
+	for _, prefix := range apiPrefixes {
+		if strings.HasPrefix(p, prefix) || p == strings.TrimSuffix(prefix, "/") {
+			isAPI = true
+			break
+		}
+	}
+	if isAPI || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+		c.JSON(http.StatusNotFound, model.Response{
+			Code:    404,
+			Data:    nil,
+			Message: "not found",
+		})
+		return false
+	}
+	return true
+}
+
+// acceptedEncodings 解析 Accept-Encoding, 忽略 q=0 的编码
+func acceptedEncodings(header string) map[string]bool {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok && strings.Trim(q, "0.") == "" {
+			continue
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	return accepted
+}
+
+func contentType(name string, data []byte) string {
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		return ctype
+	}
+	return http.DetectContentType(data)
+}
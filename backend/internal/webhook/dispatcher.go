@@ -0,0 +1,295 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"todo-backend/internal/events"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+)
+
+const (
+	EventTest = "webhook.test"
+
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+
+	maxResponseBody = 4096
+	batchSize       = 50
+)
+
+type Options struct {
+	MaxAttempts  int
+	Timeout      time.Duration
+	PollInterval time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// AllowPrivateNetworks 允许投递到回环、内网和链路本地地址, 只应在测试或可信的内网部署中开启
+	AllowPrivateNetworks bool
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MaxAttempts:  8,
+		Timeout:      10 * time.Second,
+		PollInterval: 5 * time.Second,
+		BaseBackoff:  10 * time.Second,
+		MaxBackoff:   time.Hour,
+	}
+}
+
+type Payload struct {
+	ID        uint64      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher 把事件写入持久化的投递队列, 并按指数退避重试失败的投递
+type Dispatcher struct {
+	hub    *events.Hub
+	repo   *repository.WebhookRepository
+	client *http.Client
+	opts   Options
+	wake   chan struct{}
+}
+
+func NewDispatcher(repo *repository.WebhookRepository, hub *events.Hub, opts Options) *Dispatcher {
+	return &Dispatcher{
+		hub:    hub,
+		repo:   repo,
+		client: newClient(opts),
+		opts:   opts,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+func (d *Dispatcher) Start(ctx context.Context) {
+	go d.consume(ctx)
+	go d.work(ctx)
+}
+
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) consume(ctx context.Context) {
+	var lastID uint64
+	resume := false
+
+	for {
+		sub, missed, ok := d.hub.SubscribeSince(lastID, resume, 1024)
+		if !ok {
+			log.Printf("Webhook dispatcher missed events after %d", lastID)
+		}
+		for _, event := range missed {
+			d.enqueueEvent(ctx, event)
+			lastID = event.ID
+		}
+
+		for open := true; open; {
+			select {
+			case <-ctx.Done():
+				sub.Close()
+				return
+			case event, ok := <-sub.C:
+				if !ok {
+					// 订阅因积压被断开, 从最后处理的事件继续
+					open = false
+					break
+				}
+				d.enqueueEvent(ctx, event)
+				lastID = event.ID
+			}
+		}
+		resume = true
+	}
+}
+
+func (d *Dispatcher) enqueueEvent(ctx context.Context, event events.Event) {
+	webhooks, err := d.repo.GetActive(ctx)
+	if err != nil {
+		log.Printf("Failed to load webhooks: %v", err)
+		return
+	}
+
+	for i := range webhooks {
+		if webhooks[i].Accepts(event.Type) {
+			if _, err := d.Enqueue(ctx, &webhooks[i], event); err != nil {
+				log.Printf("Failed to enqueue webhook delivery: %v", err)
+			}
+		}
+	}
+}
+
+// Enqueue 为 webhook 创建一条待投递记录
+func (d *Dispatcher) Enqueue(ctx context.Context, webhook *model.Webhook, event events.Event) (*model.WebhookDelivery, error) {
+	body, err := json.Marshal(Payload{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.Time,
+		Data:      event.Data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &model.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventID:       event.ID,
+		EventType:     event.Type,
+		Payload:       string(body),
+		Status:        model.DeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := d.repo.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	d.notify()
+	return delivery, nil
+}
+
+// SendTest 投递一个测试事件
+func (d *Dispatcher) SendTest(ctx context.Context, webhook *model.Webhook) (*model.WebhookDelivery, error) {
+	return d.Enqueue(ctx, webhook, events.Event{
+		Type: EventTest,
+		Data: map[string]interface{}{"webhook_id": webhook.ID, "message": "This is a test event"},
+		Time: time.Now(),
+	})
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.ProcessDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// ProcessDue 投递所有到期的记录
+func (d *Dispatcher) ProcessDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := d.repo.DueDeliveries(ctx, time.Now(), batchSize)
+		if err != nil {
+			log.Printf("Failed to load webhook deliveries: %v", err)
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+
+		for i := range deliveries {
+			d.attempt(ctx, &deliveries[i])
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *model.WebhookDelivery) {
+	webhook, err := d.repo.GetByID(ctx, delivery.WebhookID)
+	if err != nil {
+		delivery.Status = model.DeliveryFailed
+		delivery.Error = "webhook not found"
+		d.save(delivery)
+		return
+	}
+
+	delivery.Attempts++
+	code, err := d.post(ctx, webhook, delivery)
+	delivery.ResponseCode = code
+	delivery.Error = ""
+
+	switch {
+	case err == nil && code >= 200 && code < 300:
+		now := time.Now()
+		delivery.Status = model.DeliverySucceeded
+		delivery.DeliveredAt = &now
+	default:
+		if err != nil {
+			delivery.Error = err.Error()
+		} else {
+			delivery.Error = fmt.Sprintf("unexpected status %d", code)
+		}
+
+		if delivery.Attempts >= d.opts.MaxAttempts {
+			delivery.Status = model.DeliveryFailed
+		} else {
+			delivery.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempts))
+		}
+	}
+
+	d.save(delivery)
+}
+
+// post 发送一次投递, 只返回状态码. 响应体不保存, 避免通过投递记录读取目标地址的内容
+func (d *Dispatcher) post(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-backend-webhook/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// 读完少量响应体以便复用连接
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, nil
+}
+
+// backoff 第 n 次失败后的等待时间: BaseBackoff * 2^(n-1), 不超过 MaxBackoff
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.opts.BaseBackoff
+	for i := 1; i < attempts && wait < d.opts.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.opts.MaxBackoff {
+		wait = d.opts.MaxBackoff
+	}
+	return wait
+}
+
+// save 不使用投递的 ctx, 停止服务时已经完成的投递结果也要保存, 避免重启后重复投递
+func (d *Dispatcher) save(delivery *model.WebhookDelivery) {
+	if err := d.repo.SaveDelivery(context.Background(), delivery); err != nil {
+		log.Printf("Failed to save webhook delivery %d: %v", delivery.ID, err)
+	}
+}
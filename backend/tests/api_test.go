@@ -3,66 +3,32 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
-	"net/http/httptest"
-	"os"
 	"testing"
 
-	"todo-backend/internal/database"
-	"todo-backend/internal/handler"
+	"todo-backend/internal/config"
+	"todo-backend/internal/events"
 	"todo-backend/internal/model"
-
-	"github.com/gin-gonic/gin"
+	"todo-backend/internal/testutil"
 )
 
-const baseURL = "http://localhost:8080/api"
-
-var testServer *httptest.Server
-
-func setupTestServer() *httptest.Server {
-	gin.SetMode(gin.TestMode)
-
-	r := gin.New()
-
-	r.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	})
-
-	todoHandler := handler.NewTodoHandler()
-	api := r.Group("/api")
-	{
-		api.GET("/todos", todoHandler.GetAllTodos)
-		api.GET("/todos/:id", todoHandler.GetTodoByID)
-		api.POST("/todos", todoHandler.CreateTodo)
-		api.PUT("/todos/:id", todoHandler.UpdateTodo)
-		api.DELETE("/todos/:id", todoHandler.DeleteTodo)
-	}
-
-	return httptest.NewServer(r)
+// testConfig 开启 CalDAV、订阅日历和管理接口
+func testConfig(cfg *config.Config) {
+	cfg.CORSOrigins = []string{testCORSOrigin, "https://*.todo.test"}
+	cfg.CORSAllowCredentials = true
+	cfg.AdminToken = testAdminToken
+	cfg.CalendarToken = testCalendarToken
+	cfg.CalDAVUsername = testCalDAVUser
+	cfg.CalDAVPassword = testCalDAVPassword
+	cfg.GraphQLMaxDepth = 3
 }
 
-func setupTestDB() {
-	os.Remove("todo_test.db")
-	database.DB = nil
-	database.InitTestDatabase("todo_test.db")
-}
-
-func TestMain(m *testing.M) {
-	setupTestDB()
-	testServer = setupTestServer()
-	defer testServer.Close()
-	defer os.Remove("todo_test.db")
+// newTestServer 为当前测试启动使用 testConfig 的服务, opts 在 testConfig 之后应用
+func newTestServer(t *testing.T, opts ...testutil.Option) *testutil.Server {
+	t.Helper()
 
-	m.Run()
+	return testutil.NewServer(t, append([]testutil.Option{testConfig}, opts...)...)
 }
 
 func makeRequest(method, url string, body interface{}) (*http.Response, error) {
@@ -89,315 +55,162 @@ func parseResponse(resp *http.Response) (model.Response, error) {
 	return response, err
 }
 
+func boolPtr(v bool) *bool {
+	return &v
+}
+
 func TestCreateTodo(t *testing.T) {
-	body := model.CreateTodoRequest{
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	resp := server.Request(t, "POST", "/api/todos", model.CreateTodoRequest{
 		Title:   "Test Todo",
 		Content: "Test Content",
-	}
-
-	resp, err := makeRequest("POST", testServer.URL+"/api/todos", body)
-	if err != nil {
-		t.Fatalf("Failed to make request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		t.Errorf("Expected status 201, got %d", resp.StatusCode)
-	}
-
-	response, err := parseResponse(resp)
-	if err != nil {
-		t.Fatalf("Failed to parse response: %v", err)
-	}
-
-	if response.Code != 0 {
-		t.Errorf("Expected code 0, got %d", response.Code)
-	}
-
-	if response.Message != "success" {
-		t.Errorf("Expected message 'success', got %s", response.Message)
-	}
+	})
 
-	todo, ok := response.Data.(*model.Todo)
-	if !ok {
-		t.Fatal("Failed to cast data to Todo")
+	var todo model.Todo
+	testutil.AssertSuccess(t, resp, http.StatusCreated, &todo)
+	if todo.ID == 0 {
+		t.Error("Expected an ID to be assigned")
 	}
-
 	if todo.Title != "Test Todo" {
 		t.Errorf("Expected title 'Test Todo', got %s", todo.Title)
 	}
-
 	if todo.Content != "Test Content" {
 		t.Errorf("Expected content 'Test Content', got %s", todo.Content)
 	}
-
-	if !todo.Completed {
+	if todo.Completed {
 		t.Error("Expected completed to be false by default")
 	}
-
-	t.Logf("Created todo with ID: %d", todo.ID)
 }
 
 func TestGetAllTodos(t *testing.T) {
-	// Create a todo first
-	body := model.CreateTodoRequest{
-		Title:   "Get All Test",
-		Content: "Content for get all",
-	}
-	resp, err := makeRequest("POST", testServer.URL+"/api/todos", body)
-	if err != nil {
-		t.Fatalf("Failed to create todo: %v", err)
-	}
-	resp.Body.Close()
-
-	// Get all todos
-	resp, err = makeRequest("GET", testServer.URL+"/api/todos", nil)
-	if err != nil {
-		t.Fatalf("Failed to make request: %v", err)
-	}
-	defer resp.Body.Close()
+	t.Parallel()
+	server := testutil.NewServer(t)
+	fixtures := server.LoadFixtures(t)
 
-	if resp.StatusCode != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", resp.StatusCode)
-	}
-
-	response, err := parseResponse(resp)
-	if err != nil {
-		t.Fatalf("Failed to parse response: %v", err)
+	var todos []model.Todo
+	testutil.AssertSuccess(t, server.Request(t, "GET", "/api/todos", nil), http.StatusOK, &todos)
+	if len(todos) != len(fixtures) {
+		t.Fatalf("Expected %d todos, got %d", len(fixtures), len(todos))
 	}
-
-	if response.Code != 0 {
-		t.Errorf("Expected code 0, got %d", response.Code)
-	}
-
-	todos, ok := response.Data.([]interface{})
-	if !ok {
-		t.Fatal("Failed to cast data to []interface{}")
-	}
-
-	if len(todos) == 0 {
-		t.Error("Expected at least one todo")
-	}
-
-	t.Logf("Found %d todos", len(todos))
 }
 
 func TestGetTodoByID(t *testing.T) {
-	// Create a todo first
-	body := model.CreateTodoRequest{
-		Title:   "Get By ID Test",
-		Content: "Content for get by id",
-	}
-	resp, err := makeRequest("POST", testServer.URL+"/api/todos", body)
-	if err != nil {
-		t.Fatalf("Failed to create todo: %v", err)
-	}
-	defer resp.Body.Close()
-
-	response, err := parseResponse(resp)
-	if err != nil {
-		t.Fatalf("Failed to parse response: %v", err)
-	}
-
-	todo, ok := response.Data.(*model.Todo)
-	if !ok {
-		t.Fatal("Failed to cast data to Todo")
-	}
-
-	todoID := todo.ID
-
-	// Get todo by ID
-	resp, err = makeRequest("GET", testServer.URL+"/api/todos/"+string(rune(todoID))+"", nil)
-	if err != nil {
-		t.Fatalf("Failed to make request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", resp.StatusCode)
-	}
-
-	response, err = parseResponse(resp)
-	if err != nil {
-		t.Fatalf("Failed to parse response: %v", err)
-	}
+	t.Parallel()
+	server := testutil.NewServer(t)
+	created := server.CreateTodo(t, "Get By ID Test", testutil.WithContent("Content for get by id"))
 
-	if response.Code != 0 {
-		t.Errorf("Expected code 0, got %d", response.Code)
+	var todo model.Todo
+	testutil.AssertSuccess(t, server.Request(t, "GET", fmt.Sprintf("/api/todos/%d", created.ID), nil), http.StatusOK, &todo)
+	if todo.ID != created.ID {
+		t.Errorf("Expected ID %d, got %d", created.ID, todo.ID)
 	}
-
-	retrievedTodo, ok := response.Data.(*model.Todo)
-	if !ok {
-		t.Fatal("Failed to cast data to Todo")
-	}
-
-	if retrievedTodo.ID != todoID {
-		t.Errorf("Expected ID %d, got %d", todoID, retrievedTodo.ID)
-	}
-
-	if retrievedTodo.Title != "Get By ID Test" {
-		t.Errorf("Expected title 'Get By ID Test', got %s", retrievedTodo.Title)
+	if todo.Title != "Get By ID Test" {
+		t.Errorf("Expected title 'Get By ID Test', got %s", todo.Title)
 	}
 }
 
 func TestUpdateTodo(t *testing.T) {
-	// Create a todo first
-	body := model.CreateTodoRequest{
-		Title:   "Update Test",
-		Content: "Original Content",
-	}
-	resp, err := makeRequest("POST", testServer.URL+"/api/todos", body)
-	if err != nil {
-		t.Fatalf("Failed to create todo: %v", err)
-	}
-	defer resp.Body.Close()
-
-	response, err := parseResponse(resp)
-	if err != nil {
-		t.Fatalf("Failed to parse response: %v", err)
-	}
-
-	todo, ok := response.Data.(*model.Todo)
-	if !ok {
-		t.Fatal("Failed to cast data to Todo")
-	}
+	t.Parallel()
+	server := testutil.NewServer(t)
+	created := server.CreateTodo(t, "Update Test", testutil.WithContent("Original Content"))
+	path := fmt.Sprintf("/api/todos/%d", created.ID)
 
-	todoID := todo.ID
-
-	// Update todo
-	updateBody := model.UpdateTodoRequest{
+	var todo model.Todo
+	resp := server.Request(t, "PUT", path, model.UpdateTodoRequest{
 		Title:     "Updated Title",
 		Content:   "Updated Content",
-		Completed: true,
-	}
-
-	resp, err = makeRequest("PUT", testServer.URL+"/api/todos/"+string(rune(todoID))+"", updateBody)
-	if err != nil {
-		t.Fatalf("Failed to make request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", resp.StatusCode)
-	}
-
-	response, err = parseResponse(resp)
-	if err != nil {
-		t.Fatalf("Failed to parse response: %v", err)
-	}
-
-	if response.Code != 0 {
-		t.Errorf("Expected code 0, got %d", response.Code)
-	}
-
-	updatedTodo, ok := response.Data.(*model.Todo)
-	if !ok {
-		t.Fatal("Failed to cast data to Todo")
+		Completed: boolPtr(true),
+	})
+	testutil.AssertSuccess(t, resp, http.StatusOK, &todo)
+	if todo.Title != "Updated Title" {
+		t.Errorf("Expected title 'Updated Title', got %s", todo.Title)
 	}
-
-	if updatedTodo.Title != "Updated Title" {
-		t.Errorf("Expected title 'Updated Title', got %s", updatedTodo.Title)
+	if todo.Content != "Updated Content" {
+		t.Errorf("Expected content 'Updated Content', got %s", todo.Content)
 	}
-
-	if updatedTodo.Content != "Updated Content" {
-		t.Errorf("Expected content 'Updated Content', got %s", updatedTodo.Content)
-	}
-
-	if !updatedTodo.Completed {
+	if !todo.Completed {
 		t.Error("Expected completed to be true")
 	}
 
-	// Test marking as incomplete
-	updateBody = model.UpdateTodoRequest{
+	// 标记为未完成
+	resp = server.Request(t, "PUT", path, model.UpdateTodoRequest{
 		Title:     "Updated Title",
 		Content:   "Updated Content",
-		Completed: false,
-	}
-
-	resp, err = makeRequest("PUT", testServer.URL+"/api/todos/"+string(rune(todoID))+"", updateBody)
-	if err != nil {
-		t.Fatalf("Failed to make request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	response, err = parseResponse(resp)
-	if err != nil {
-		t.Fatalf("Failed to parse response: %v", err)
-	}
-
-	updatedTodo, ok = response.Data.(*model.Todo)
-	if !ok {
-		t.Fatal("Failed to cast data to Todo")
-	}
-
-	if updatedTodo.Completed {
+		Completed: boolPtr(false),
+	})
+	testutil.AssertSuccess(t, resp, http.StatusOK, &todo)
+	if todo.Completed {
 		t.Error("Expected completed to be false")
 	}
 }
 
-func TestDeleteTodo(t *testing.T) {
-	// Create a todo first
-	body := model.CreateTodoRequest{
-		Title:   "Delete Test",
-		Content: "Content to be deleted",
-	}
-	resp, err := makeRequest("POST", testServer.URL+"/api/todos", body)
-	if err != nil {
-		t.Fatalf("Failed to create todo: %v", err)
-	}
-	defer resp.Body.Close()
+func TestUpdateTodoKeepsUnsentFields(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+	created := server.CreateTodo(t, "Partial", testutil.WithContent("Keep me"), testutil.Completed())
+	path := fmt.Sprintf("/api/todos/%d", created.ID)
 
-	response, err := parseResponse(resp)
-	if err != nil {
-		t.Fatalf("Failed to parse response: %v", err)
+	var todo model.Todo
+	testutil.AssertSuccess(t, server.Request(t, "PUT", path, map[string]string{"title": "Renamed"}), http.StatusOK, &todo)
+	if todo.Title != "Renamed" || todo.Content != "Keep me" || !todo.Completed {
+		t.Errorf("Expected only the title to change, got %+v", todo)
 	}
 
-	todo, ok := response.Data.(*model.Todo)
-	if !ok {
-		t.Fatal("Failed to cast data to Todo")
+	testutil.AssertSuccess(t, server.Request(t, "POST", "/api/todos/batch", map[string]interface{}{
+		"operations": []map[string]interface{}{{"op": "update", "id": created.ID, "data": map[string]string{"content": "Batch"}}},
+	}), http.StatusOK, nil)
+	testutil.AssertSuccess(t, server.Request(t, "GET", path, nil), http.StatusOK, &todo)
+	if todo.Title != "Renamed" || todo.Content != "Batch" || !todo.Completed {
+		t.Errorf("Expected only the content to change, got %+v", todo)
 	}
 
-	todoID := todo.ID
-
-	// Delete todo
-	resp, err = makeRequest("DELETE", testServer.URL+"/api/todos/"+string(rune(todoID))+"", nil)
-	if err != nil {
-		t.Fatalf("Failed to make request: %v", err)
+	testutil.AssertSuccess(t, server.Request(t, "PUT", path, map[string]bool{"completed": false}), http.StatusOK, &todo)
+	if todo.Title != "Renamed" || todo.Content != "Batch" || todo.Completed {
+		t.Errorf("Expected only completed to change, got %+v", todo)
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", resp.StatusCode)
-	}
+func TestDeleteTodo(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+	created := server.CreateTodo(t, "Delete Test")
+	path := fmt.Sprintf("/api/todos/%d", created.ID)
 
-	response, err = parseResponse(resp)
-	if err != nil {
-		t.Fatalf("Failed to parse response: %v", err)
-	}
+	testutil.AssertSuccess(t, server.Request(t, "DELETE", path, nil), http.StatusOK, nil)
+	testutil.AssertError(t, server.Request(t, "GET", path, nil), http.StatusNotFound)
+}
 
-	if response.Code != 0 {
-		t.Errorf("Expected code 0, got %d", response.Code)
-	}
+func TestDeleteTodoTwice(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+	created := server.CreateTodo(t, "Delete Twice")
+	path := fmt.Sprintf("/api/todos/%d", created.ID)
+	start := server.Hub.LastID()
 
-	// Try to get the deleted todo
-	resp, err = makeRequest("GET", testServer.URL+"/api/todos/"+string(rune(todoID))+"", nil)
-	if err != nil {
-		t.Fatalf("Failed to make request: %v", err)
-	}
-	defer resp.Body.Close()
+	testutil.AssertSuccess(t, server.Request(t, "DELETE", path, nil), http.StatusOK, nil)
+	testutil.AssertError(t, server.Request(t, "DELETE", path, nil), http.StatusNotFound)
+	testutil.AssertError(t, server.Request(t, "DELETE", "/api/todos/999999", nil), http.StatusNotFound)
 
-	if resp.StatusCode != http.StatusNotFound {
-		t.Errorf("Expected status 404, got %d", resp.StatusCode)
+	// 只有真正删除了记录的请求发布事件
+	sub, published, _ := server.Hub.SubscribeSince(start, true, 1)
+	sub.Close()
+	if len(published) != 1 || published[0].Type != events.TodoDeleted {
+		t.Errorf("Expected exactly one todo.deleted event, got %+v", published)
 	}
 }
 
 func TestCreateTodoMissingTitle(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
 	body := model.CreateTodoRequest{
 		Title:   "",
 		Content: "Content without title",
 	}
 
-	resp, err := makeRequest("POST", testServer.URL+"/api/todos", body)
+	resp, err := makeRequest("POST", server.URL+"/api/todos", body)
 	if err != nil {
 		t.Fatalf("Failed to make request: %v", err)
 	}
@@ -418,7 +231,10 @@ func TestCreateTodoMissingTitle(t *testing.T) {
 }
 
 func TestGetTodoByInvalidID(t *testing.T) {
-	resp, err := makeRequest("GET", testServer.URL+"/api/todos/abc", nil)
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp, err := makeRequest("GET", server.URL+"/api/todos/abc", nil)
 	if err != nil {
 		t.Fatalf("Failed to make request: %v", err)
 	}
@@ -439,7 +255,10 @@ func TestGetTodoByInvalidID(t *testing.T) {
 }
 
 func TestGetTodoByNonExistentID(t *testing.T) {
-	resp, err := makeRequest("GET", testServer.URL+"/api/todos/99999", nil)
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp, err := makeRequest("GET", server.URL+"/api/todos/99999", nil)
 	if err != nil {
 		t.Fatalf("Failed to make request: %v", err)
 	}
@@ -460,13 +279,16 @@ func TestGetTodoByNonExistentID(t *testing.T) {
 }
 
 func TestUpdateTodoInvalidID(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
 	body := model.UpdateTodoRequest{
 		Title:     "Test",
 		Content:   "Content",
-		Completed: false,
+		Completed: boolPtr(false),
 	}
 
-	resp, err := makeRequest("PUT", testServer.URL+"/api/todos/abc", body)
+	resp, err := makeRequest("PUT", server.URL+"/api/todos/abc", body)
 	if err != nil {
 		t.Fatalf("Failed to make request: %v", err)
 	}
@@ -478,7 +300,10 @@ func TestUpdateTodoInvalidID(t *testing.T) {
 }
 
 func TestDeleteTodoInvalidID(t *testing.T) {
-	resp, err := makeRequest("DELETE", testServer.URL+"/api/todos/abc", nil)
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp, err := makeRequest("DELETE", server.URL+"/api/todos/abc", nil)
 	if err != nil {
 		t.Fatalf("Failed to make request: %v", err)
 	}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"todo-backend/internal/backup"
+	"todo-backend/internal/model"
+	"todo-backend/internal/testutil"
+)
+
+const testAdminToken = "admin-secret"
+
+func adminRequest(t *testing.T, server *testutil.Server, method, token string) (*http.Response, model.Response) {
+	t.Helper()
+
+	headers := map[string]string{}
+	if token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	resp, err := makeRawRequest(method, server.URL+"/api/admin/backups", nil, headers)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := parseResponse(resp)
+	return resp, body
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	for _, token := range []string{"", "wrong"} {
+		resp, _ := adminRequest(t, server, "GET", token)
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for %q, got %d", token, resp.StatusCode)
+		}
+		if resp.Header.Get("WWW-Authenticate") == "" {
+			t.Error("Expected a WWW-Authenticate header")
+		}
+	}
+}
+
+func TestCreateAndListBackups(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	var names []string
+	for i := 0; i < 3; i++ {
+		resp, body := adminRequest(t, server, "POST", testAdminToken)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", resp.StatusCode, body.Message)
+		}
+		data := body.Data.(map[string]interface{})
+		names = append(names, data["name"].(string))
+
+		path := filepath.Join(server.Backups.Dir(), data["name"].(string))
+		if err := backup.Verify(path); err != nil {
+			t.Errorf("Expected a valid checksum: %v", err)
+		}
+	}
+
+	// 测试服务器只保留 2 个备份, 最新的在前
+	resp, body := adminRequest(t, server, "GET", testAdminToken)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	list := body.Data.([]interface{})
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 backups, got %d", len(list))
+	}
+	if list[0].(map[string]interface{})["name"] != names[2] || list[1].(map[string]interface{})["name"] != names[1] {
+		t.Errorf("Expected the newest backups first, got %v", list)
+	}
+	if _, ok := list[0].(map[string]interface{})["sha256"].(string); !ok {
+		t.Error("Expected a sha256 field")
+	}
+}
@@ -0,0 +1,305 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"todo-backend/internal/model"
+	"todo-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+func createTestTodo(t *testing.T, server *testutil.Server, title string) uint {
+	t.Helper()
+
+	resp, err := makeRequest("POST", server.URL+"/api/todos", model.CreateTodoRequest{Title: title})
+	if err != nil {
+		t.Fatalf("Failed to create todo: %v", err)
+	}
+	defer resp.Body.Close()
+
+	response, err := parseResponse(resp)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	todo, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Failed to create todo: %s", response.Message)
+	}
+
+	return uint(todo["id"].(float64))
+}
+
+func getTestTodo(t *testing.T, server *testutil.Server, id uint) (map[string]interface{}, int) {
+	t.Helper()
+
+	resp, err := makeRequest("GET", fmt.Sprintf("%s/api/todos/%d", server.URL, id), nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	response, err := parseResponse(resp)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	todo, _ := response.Data.(map[string]interface{})
+	return todo, resp.StatusCode
+}
+
+func postBatch(t *testing.T, server *testutil.Server, body interface{}) (model.Response, int) {
+	t.Helper()
+
+	resp, err := makeRequest("POST", server.URL+"/api/todos/batch", body)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	response, err := parseResponse(resp)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	return response, resp.StatusCode
+}
+
+func batchResults(t *testing.T, response model.Response) []interface{} {
+	t.Helper()
+
+	data, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected batch data, got %v", response.Data)
+	}
+
+	results, _ := data["results"].([]interface{})
+	return results
+}
+
+func TestBatchAtomic(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	updateID := createTestTodo(t, server, "Batch Update")
+	deleteID := createTestTodo(t, server, "Batch Delete")
+
+	response, status := postBatch(t, server, map[string]interface{}{
+		"operations": []map[string]interface{}{
+			{"op": "create", "data": map[string]interface{}{"title": "Batch Created"}},
+			{"op": "update", "id": updateID, "data": map[string]interface{}{"title": "Batch Updated", "completed": true}},
+			{"op": "delete", "id": deleteID},
+		},
+	})
+
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d - %s", status, response.Message)
+	}
+
+	results := batchResults(t, response)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	if todo, _ := getTestTodo(t, server, updateID); todo["title"] != "Batch Updated" || todo["completed"] != true {
+		t.Errorf("Expected todo to be updated, got %v", todo)
+	}
+
+	if _, status := getTestTodo(t, server, deleteID); status != http.StatusNotFound {
+		t.Errorf("Expected deleted todo to return 404, got %d", status)
+	}
+}
+
+func TestBatchAtomicRollback(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	keepID := createTestTodo(t, server, "Batch Keep")
+
+	response, status := postBatch(t, server, map[string]interface{}{
+		"mode": "atomic",
+		"operations": []map[string]interface{}{
+			{"op": "delete", "id": keepID},
+			{"op": "update", "id": 999999, "data": map[string]interface{}{"title": "Missing"}},
+		},
+	})
+
+	if status != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", status)
+	}
+
+	results := batchResults(t, response)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	if first := results[0].(map[string]interface{}); first["status"] != float64(http.StatusFailedDependency) {
+		t.Errorf("Expected first operation to be rolled back, got %v", first)
+	}
+
+	if _, status := getTestTodo(t, server, keepID); status != http.StatusOK {
+		t.Errorf("Expected delete to be rolled back, got %d", status)
+	}
+}
+
+func TestBatchPartial(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	response, status := postBatch(t, server, map[string]interface{}{
+		"mode": "partial",
+		"operations": []map[string]interface{}{
+			{"op": "create", "data": map[string]interface{}{"title": "Batch Partial"}},
+			{"op": "create", "data": map[string]interface{}{"title": "  "}},
+			{"op": "delete", "id": 999999},
+		},
+	})
+
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d - %s", status, response.Message)
+	}
+
+	data := response.Data.(map[string]interface{})
+	if data["succeeded"] != float64(1) || data["failed"] != float64(2) {
+		t.Errorf("Expected 1 succeeded and 2 failed, got %v", data)
+	}
+
+	results := batchResults(t, response)
+	created := results[0].(map[string]interface{})
+	if _, status := getTestTodo(t, server, uint(created["id"].(float64))); status != http.StatusOK {
+		t.Errorf("Expected created todo to exist, got %d", status)
+	}
+
+	if invalid := results[1].(map[string]interface{}); invalid["status"] != float64(http.StatusBadRequest) {
+		t.Errorf("Expected status 400 for invalid create, got %v", invalid["status"])
+	}
+
+	if missing := results[2].(map[string]interface{}); missing["status"] != float64(http.StatusNotFound) {
+		t.Errorf("Expected status 404 for missing delete, got %v", missing["status"])
+	}
+}
+
+func TestBatchInvalidOperation(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	_, status := postBatch(t, server, map[string]interface{}{
+		"operations": []map[string]interface{}{{"op": "update"}},
+	})
+
+	if status != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", status)
+	}
+}
+
+func TestCompleteAllAndDeleteCompleted(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	id := createTestTodo(t, server, "Complete All")
+
+	resp, err := makeRequest("POST", server.URL+"/api/todos/complete-all", nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if todo, _ := getTestTodo(t, server, id); todo["completed"] != true {
+		t.Errorf("Expected todo to be completed, got %v", todo["completed"])
+	}
+
+	pendingID := createTestTodo(t, server, "Still Pending")
+
+	resp, err = makeRequest("DELETE", server.URL+"/api/todos?completed=true", nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	response, err := parseResponse(resp)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if data := response.Data.(map[string]interface{}); data["deleted"].(float64) < 1 {
+		t.Errorf("Expected at least one deleted todo, got %v", data["deleted"])
+	}
+
+	if _, status := getTestTodo(t, server, id); status != http.StatusNotFound {
+		t.Errorf("Expected completed todo to be deleted, got %d", status)
+	}
+
+	if _, status := getTestTodo(t, server, pendingID); status != http.StatusOK {
+		t.Errorf("Expected pending todo to remain, got %d", status)
+	}
+}
+
+func TestDeleteTodosRequiresFilter(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp, err := makeRequest("DELETE", server.URL+"/api/todos", nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestBatchPartialSavepointFailure(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	// 每个创建操作有两层 SAVEPOINT (批量项和 Create), 第三个是第二个操作的批量项
+	var savepoints int32
+	err := server.DB.Callback().Raw().Before("gorm:raw").Register("test:fail_savepoint", func(tx *gorm.DB) {
+		if strings.HasPrefix(tx.Statement.SQL.String(), "SAVEPOINT ") && atomic.AddInt32(&savepoints, 1) == 3 {
+			tx.AddError(errors.New("savepoint failed"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resp model.BatchResponse
+	testutil.AssertSuccess(t, server.Request(t, "POST", "/api/todos/batch", map[string]interface{}{
+		"mode": "partial",
+		"operations": []map[string]interface{}{
+			{"op": "create", "data": map[string]string{"title": "First"}},
+			{"op": "create", "data": map[string]string{"title": "Second"}},
+			{"op": "create", "data": map[string]string{"title": "Third"}},
+		},
+	}), http.StatusOK, &resp)
+
+	if resp.Succeeded != 2 || resp.Failed != 1 {
+		t.Fatalf("Expected 2 succeeded and 1 failed, got %d and %d", resp.Succeeded, resp.Failed)
+	}
+	failed := resp.Results[1]
+	if failed.Index != 1 || failed.Op != "create" || failed.Status != http.StatusInternalServerError || failed.Todo != nil {
+		t.Errorf("Expected the second operation to fail with 500, got %+v", failed)
+	}
+
+	var todos []model.Todo
+	testutil.AssertSuccess(t, server.Request(t, "GET", "/api/todos", nil), http.StatusOK, &todos)
+	if len(todos) != 2 {
+		t.Fatalf("Expected 2 todos to be created, got %d", len(todos))
+	}
+	for _, todo := range todos {
+		if todo.Title == "Second" {
+			t.Errorf("Expected the failed operation to be rolled back")
+		}
+	}
+}
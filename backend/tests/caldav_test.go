@@ -0,0 +1,371 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"todo-backend/internal/model"
+	"todo-backend/internal/testutil"
+)
+
+const (
+	testCalDAVUser     = "caldav"
+	testCalDAVPassword = "caldav-secret"
+)
+
+// davClient 是测试用的最小 CalDAV 客户端, 使用 Basic 认证发起原始请求
+type davClient struct {
+	t      *testing.T
+	server *testutil.Server
+}
+
+func (d davClient) do(method, path, body string, headers map[string]string) (*http.Response, string) {
+	d.t.Helper()
+
+	req, err := http.NewRequest(method, d.server.URL+path, strings.NewReader(body))
+	if err != nil {
+		d.t.Fatalf("Failed to build request: %v", err)
+	}
+	req.SetBasicAuth(testCalDAVUser, testCalDAVPassword)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		d.t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+type davResult struct {
+	Responses []struct {
+		Href      string `xml:"href"`
+		Status    string `xml:"status"`
+		Propstats []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				ETag         string `xml:"getetag"`
+				CTag         string `xml:"http://calendarserver.org/ns/ getctag"`
+				CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+				ResourceType struct {
+					Inner string `xml:",innerxml"`
+				} `xml:"resourcetype"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+	SyncToken string `xml:"sync-token"`
+}
+
+func (d davClient) multistatus(method, path, depth, body string) davResult {
+	d.t.Helper()
+
+	resp, data := d.do(method, path, body, map[string]string{"Depth": depth})
+	if resp.StatusCode != http.StatusMultiStatus {
+		d.t.Fatalf("%s %s: expected 207, got %d: %s", method, path, resp.StatusCode, data)
+	}
+
+	var result davResult
+	if err := xml.NewDecoder(bytes.NewReader([]byte(data))).Decode(&result); err != nil {
+		d.t.Fatalf("Failed to decode multistatus: %v\n%s", err, data)
+	}
+	return result
+}
+
+func (d davClient) put(path, uid, summary, status string, headers map[string]string) *http.Response {
+	d.t.Helper()
+
+	body := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\nBEGIN:VTODO\r\n" +
+		"UID:" + uid + "\r\nSUMMARY:" + summary + "\r\nDESCRIPTION:first\\nsecond\r\n" +
+		"STATUS:" + status + "\r\nDUE;VALUE=DATE:20240301\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers["Content-Type"] = "text/calendar; charset=utf-8"
+
+	resp, _ := d.do("PUT", path, body, headers)
+	return resp
+}
+
+func findDAVResponse(result davResult, href string) int {
+	for i, resp := range result.Responses {
+		if resp.Href == href {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestCalDAVRequiresAuth(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp, err := makeRawRequest("PROPFIND", server.URL+"/caldav/", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+		t.Errorf("Expected 401 with a challenge, got %d", resp.StatusCode)
+	}
+}
+
+func TestCalDAVDiscovery(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	dav := davClient{t, server}
+
+	resp, _ := dav.do("OPTIONS", "/caldav/", "", nil)
+	if !strings.Contains(resp.Header.Get("DAV"), "calendar-access") {
+		t.Errorf("Expected calendar-access in DAV header, got %q", resp.Header.Get("DAV"))
+	}
+
+	result := dav.multistatus("PROPFIND", "/caldav/", "1", `<?xml version="1.0"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">
+  <d:prop><d:resourcetype/><d:current-user-principal/><c:calendar-home-set/><cs:getctag/></d:prop>
+</d:propfind>`)
+
+	i := findDAVResponse(result, "/caldav/todos/")
+	if i < 0 {
+		t.Fatalf("Expected the todo collection in the calendar home, got %+v", result)
+	}
+	props := result.Responses[i].Propstats[0].Prop
+	if !strings.Contains(props.ResourceType.Inner, "calendar") || props.CTag == "" {
+		t.Errorf("Unexpected collection props %+v", props)
+	}
+
+	// 根目录没有 getctag, 应在 404 propstat 中返回
+	root := result.Responses[findDAVResponse(result, "/caldav/")]
+	if len(root.Propstats) != 2 || !strings.Contains(root.Propstats[1].Status, "404") {
+		t.Errorf("Expected missing props to be reported as 404, got %+v", root.Propstats)
+	}
+}
+
+func TestCalDAVLifecycle(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	dav := davClient{t, server}
+	uid := "caldav-lifecycle@client.test"
+	href := "/caldav/todos/" + uid + ".ics"
+
+	if resp := dav.put(href, uid, "CalDAV todo", "NEEDS-ACTION", map[string]string{"If-None-Match": "*"}); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	if resp := dav.put(href, uid, "CalDAV todo", "NEEDS-ACTION", map[string]string{"If-None-Match": "*"}); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 when creating an existing resource, got %d", resp.StatusCode)
+	}
+
+	resp, body := dav.do("GET", href, "", nil)
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with ETag, got %d", resp.StatusCode)
+	}
+	for _, want := range []string{"UID:" + uid, "SUMMARY:CalDAV todo", "DESCRIPTION:first\\nsecond", "DUE;VALUE=DATE:20240301"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in calendar object:\n%s", want, body)
+		}
+	}
+
+	if resp, _ := dav.do("GET", href, "", map[string]string{"If-None-Match": etag}); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", resp.StatusCode)
+	}
+
+	multiget := dav.multistatus("REPORT", "/caldav/todos/", "1", `<?xml version="1.0"?>
+<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <d:href>`+href+`</d:href>
+  <d:href>/caldav/todos/missing.ics</d:href>
+</c:calendar-multiget>`)
+	if len(multiget.Responses) != 2 || multiget.Responses[0].Propstats[0].Prop.ETag != etag {
+		t.Fatalf("Unexpected multiget result %+v", multiget)
+	}
+	if !strings.Contains(multiget.Responses[0].Propstats[0].Prop.CalendarData, "UID:"+uid) {
+		t.Errorf("Expected calendar-data in multiget, got %+v", multiget.Responses[0])
+	}
+	if !strings.Contains(multiget.Responses[1].Status, "404") {
+		t.Errorf("Expected 404 for missing href, got %+v", multiget.Responses[1])
+	}
+
+	// 通过 REST API 可以看到 CalDAV 创建的 Todo
+	var created model.Todo
+	list, _ := makeRequest("GET", server.URL+"/api/todos", nil)
+	todos, _ := parseResponse(list)
+	list.Body.Close()
+	for _, item := range todos.Data.([]interface{}) {
+		if todo := item.(map[string]interface{}); todo["title"] == "CalDAV todo" {
+			created.ID = uint(todo["id"].(float64))
+		}
+	}
+	if created.ID == 0 {
+		t.Fatalf("Expected the todo to be visible through the REST API")
+	}
+
+	// 带旧 ETag 的修改被拒绝, 带当前 ETag 的修改成功
+	if resp := dav.put(href, uid, "CalDAV done", "COMPLETED", map[string]string{"If-Match": `"stale"`}); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for stale If-Match, got %d", resp.StatusCode)
+	}
+	resp = dav.put(href, uid, "CalDAV done", "COMPLETED", map[string]string{"If-Match": etag})
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("ETag") == etag {
+		t.Fatalf("Expected 204 with a new ETag, got %d %s", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+	etag = resp.Header.Get("ETag")
+
+	get, _ := makeRequest("GET", fmt.Sprintf("%s/api/todos/%d", server.URL, created.ID), nil)
+	updated, _ := parseResponse(get)
+	get.Body.Close()
+	if todo := updated.Data.(map[string]interface{}); todo["title"] != "CalDAV done" || todo["completed"] != true || todo["completed_at"] == nil {
+		t.Errorf("Expected the update to be applied, got %+v", todo)
+	}
+
+	if resp, _ := dav.do("DELETE", href, "", map[string]string{"If-Match": `"stale"`}); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for stale delete, got %d", resp.StatusCode)
+	}
+	if resp, _ := dav.do("DELETE", href, "", map[string]string{"If-Match": etag}); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", resp.StatusCode)
+	}
+	if resp, _ := dav.do("GET", href, "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", resp.StatusCode)
+	}
+
+	// 删除后可以用相同 UID 重新创建
+	if resp := dav.put(href, uid, "CalDAV again", "NEEDS-ACTION", nil); resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected 201 when reusing a deleted UID, got %d", resp.StatusCode)
+	}
+}
+
+func TestCalDAVExistingTodos(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	dav := davClient{t, server}
+
+	resp, _ := makeRequest("POST", server.URL+"/api/todos", map[string]string{"title": "Created over REST"})
+	created, _ := parseResponse(resp)
+	resp.Body.Close()
+	id := uint(created.Data.(map[string]interface{})["id"].(float64))
+	href := fmt.Sprintf("/caldav/todos/todo-%d@todo.test.ics", id)
+
+	query := dav.multistatus("REPORT", "/caldav/todos/", "1", `<?xml version="1.0"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VTODO">
+        <c:prop-filter name="COMPLETED"><c:is-not-defined/></c:prop-filter>
+      </c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`)
+	if findDAVResponse(query, href) < 0 {
+		t.Fatalf("Expected %s in calendar-query result", href)
+	}
+
+	events := dav.multistatus("REPORT", "/caldav/todos/", "1", `<?xml version="1.0"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT"/></c:comp-filter></c:filter>
+</c:calendar-query>`)
+	if len(events.Responses) != 0 {
+		t.Errorf("Expected no VEVENT resources, got %d", len(events.Responses))
+	}
+
+	resp, body := dav.do("GET", href, "", nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "SUMMARY:Created over REST") {
+		t.Errorf("Expected existing todo to be served, got %d:\n%s", resp.StatusCode, body)
+	}
+}
+
+func TestCalDAVSyncCollection(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	dav := davClient{t, server}
+	report := func(token string) davResult {
+		return dav.multistatus("REPORT", "/caldav/todos/", "1", `<?xml version="1.0"?>
+<d:sync-collection xmlns:d="DAV:">
+  <d:sync-token>`+token+`</d:sync-token>
+  <d:sync-level>1</d:sync-level>
+  <d:prop><d:getetag/></d:prop>
+</d:sync-collection>`)
+	}
+
+	initial := report("")
+	if initial.SyncToken == "" {
+		t.Fatalf("Expected a sync token")
+	}
+
+	uid := "caldav-sync@client.test"
+	href := "/caldav/todos/" + uid + ".ics"
+	dav.put(href, uid, "Synced", "NEEDS-ACTION", nil)
+
+	changed := report(initial.SyncToken)
+	if len(changed.Responses) != 1 || changed.Responses[0].Href != href {
+		t.Fatalf("Expected only the new resource, got %+v", changed.Responses)
+	}
+
+	dav.do("DELETE", href, "", nil)
+	deleted := report(changed.SyncToken)
+	if len(deleted.Responses) != 1 || !strings.Contains(deleted.Responses[0].Status, "404") {
+		t.Errorf("Expected a 404 tombstone, got %+v", deleted.Responses)
+	}
+
+	resp, body := dav.do("REPORT", "/caldav/todos/", `<d:sync-collection xmlns:d="DAV:"><d:sync-token>bogus</d:sync-token><d:prop><d:getetag/></d:prop></d:sync-collection>`, nil)
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(body, "valid-sync-token") {
+		t.Errorf("Expected 403 valid-sync-token, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestCalDAVResourceNameFromURL(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	dav := davClient{t, server}
+	uid := "caldav-random-name@client.test"
+	href := "/caldav/todos/7f3a9c2e-random.ics"
+
+	if resp := dav.put(href, uid, "CalDAV random name", "NEEDS-ACTION", nil); resp.StatusCode != http.StatusCreated || resp.Header.Get("Location") != href {
+		t.Fatalf("Expected 201 at %s, got %d %s", href, resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	resp, body := dav.do("GET", href, "", nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "UID:"+uid) {
+		t.Fatalf("Expected the object at the request URL, got %d:\n%s", resp.StatusCode, body)
+	}
+	if resp, _ := dav.do("GET", "/caldav/todos/"+uid+".ics", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected no resource under the UID, got %d", resp.StatusCode)
+	}
+
+	listing := dav.multistatus("PROPFIND", "/caldav/todos/", "1", "")
+	if findDAVResponse(listing, href) < 0 {
+		t.Errorf("Expected %s in the collection listing", href)
+	}
+
+	// 已有资源的 UID 不能修改, 其他资源名也不能使用同一个 UID
+	if resp := dav.put(href, "other-uid@client.test", "CalDAV random name", "NEEDS-ACTION", nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 when changing the UID, got %d", resp.StatusCode)
+	}
+	if resp := dav.put("/caldav/todos/another-name.ics", uid, "CalDAV duplicate", "NEEDS-ACTION", nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 when reusing the UID under another name, got %d", resp.StatusCode)
+	}
+
+	if resp, _ := dav.do("DELETE", href, "", nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", resp.StatusCode)
+	}
+	if resp := dav.put(href, uid, "CalDAV random name again", "NEEDS-ACTION", nil); resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected 201 when reusing a deleted resource name, got %d", resp.StatusCode)
+	}
+}
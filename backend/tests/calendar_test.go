@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-backend/internal/ical"
+	"todo-backend/internal/middleware"
+	"todo-backend/internal/model"
+	"todo-backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testCalendarToken = "calendar-secret"
+
+func getCalendar(t *testing.T, server *testutil.Server, query string, headers map[string]string) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := makeRawRequest("GET", server.URL+"/api/calendar.ics?"+query, nil, headers)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+// unfold 还原折行后的内容行
+func unfold(body string) []string {
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(body, "\r\n ", ""), "\r\n"), "\r\n")
+}
+
+func TestCalendarRequiresToken(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	for _, query := range []string{"", "token=wrong"} {
+		if resp, _ := getCalendar(t, server, query, nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404 for %q, got %d", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestCalendarFeed(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	title := "Calendar; escaping, test \\ " + strings.Repeat("长标题", 20)
+	resp, _ := makeRequest("POST", server.URL+"/api/todos", map[string]string{"title": title, "content": "line one\nline two"})
+	created, _ := parseResponse(resp)
+	resp.Body.Close()
+	id := uint(created.Data.(map[string]interface{})["id"].(float64))
+
+	due := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	server.DB.Model(&model.Todo{}).Where("id = ?", id).Update("due_at", due)
+
+	query := "token=" + testCalendarToken + "&q=Calendar%3B"
+	resp, body := getCalendar(t, server, query, nil)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/calendar") {
+		t.Fatalf("Unexpected response %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	for _, line := range strings.Split(body, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Line longer than 75 octets: %q", line)
+		}
+	}
+
+	lines := strings.Join(unfold(body), "\n")
+	uid := ical.UID(id, "todo.test")
+	for _, want := range []string{
+		"BEGIN:VCALENDAR",
+		"UID:" + uid,
+		"SUMMARY:" + `Calendar\; escaping\, test \\ ` + strings.Repeat("长标题", 20),
+		`DESCRIPTION:line one\nline two`,
+		"DUE;VALUE=DATE:20240301",
+		"STATUS:NEEDS-ACTION",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20240301",
+		"END:VCALENDAR",
+	} {
+		if !strings.Contains(lines, want) {
+			t.Errorf("Expected calendar to contain %q, got:\n%s", want, lines)
+		}
+	}
+
+	// 更新后 UID 不变, SEQUENCE 递增, ETag 变化
+	etag := resp.Header.Get("ETag")
+	if resp, _ := getCalendar(t, server, query, map[string]string{"If-None-Match": etag}); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected status 304, got %d", resp.StatusCode)
+	}
+
+	resp, _ = makeRequest("PUT", fmt.Sprintf("%s/api/todos/%d", server.URL, id), model.UpdateTodoRequest{Completed: boolPtr(true)})
+	resp.Body.Close()
+
+	resp, body = getCalendar(t, server, query+"&events=false", map[string]string{"If-None-Match": etag})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected changed calendar, got %d", resp.StatusCode)
+	}
+	lines = strings.Join(unfold(body), "\n")
+	for _, want := range []string{"UID:" + uid, "SEQUENCE:1", "STATUS:COMPLETED", "COMPLETED:"} {
+		if !strings.Contains(lines, want) {
+			t.Errorf("Expected updated calendar to contain %q", want)
+		}
+	}
+	if strings.Contains(lines, "BEGIN:VEVENT") {
+		t.Error("Expected no VEVENT when events=false")
+	}
+}
+
+func TestICalFold(t *testing.T) {
+	t.Parallel()
+	line := "DESCRIPTION:" + strings.Repeat("é", 80)
+	folded := ical.Fold(line)
+
+	for _, part := range strings.Split(folded, "\r\n") {
+		if len(part) > 75 {
+			t.Errorf("Folded line too long: %d", len(part))
+		}
+		if !strings.HasPrefix(part, " ") && part != strings.Split(folded, "\r\n")[0] {
+			t.Errorf("Continuation line must start with a space: %q", part)
+		}
+	}
+	if strings.ReplaceAll(folded, "\r\n ", "") != line {
+		t.Error("Unfolding did not restore the original line")
+	}
+}
+
+func TestAccessLogRedactsCalendarToken(t *testing.T) {
+	t.Parallel()
+	var log bytes.Buffer
+	r := gin.New()
+	r.Use(middleware.Logger(&log, "token"))
+	r.GET("/api/calendar.ics", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, query := range []string{"token=" + testCalendarToken + "&completed=false", "completed=false&tok%65n=" + testCalendarToken} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/calendar.ics?"+query, nil))
+	}
+
+	if strings.Contains(log.String(), testCalendarToken) {
+		t.Errorf("Expected token to be redacted, got %q", log.String())
+	}
+	if !strings.Contains(log.String(), "token=REDACTED&completed=false") {
+		t.Errorf("Expected other query parameters to be kept, got %q", log.String())
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo-backend/internal/middleware"
+	"todo-backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testCORSOrigin = "https://app.todo.example"
+
+func corsRequest(t *testing.T, server *testutil.Server, method, origin string, headers map[string]string) *http.Response {
+	t.Helper()
+
+	if origin != "" {
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		headers["Origin"] = origin
+	}
+	resp, err := makeRawRequest(method, server.URL+"/api/todos", nil, headers)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestCORSPreflight(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp := corsRequest(t, server, "OPTIONS", testCORSOrigin, map[string]string{
+		"Access-Control-Request-Method":  "PATCH",
+		"Access-Control-Request-Headers": "if-match",
+	})
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", resp.StatusCode)
+	}
+
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":      testCORSOrigin,
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	}
+	for name, value := range expected {
+		if got := resp.Header.Get(name); got != value {
+			t.Errorf("Expected %s %q, got %q", name, value, got)
+		}
+	}
+	if !strings.Contains(resp.Header.Get("Access-Control-Allow-Methods"), "PATCH") {
+		t.Errorf("Expected PATCH in allowed methods, got %q", resp.Header.Get("Access-Control-Allow-Methods"))
+	}
+	if !strings.Contains(resp.Header.Get("Access-Control-Allow-Headers"), "If-Match") {
+		t.Errorf("Expected If-Match in allowed headers, got %q", resp.Header.Get("Access-Control-Allow-Headers"))
+	}
+	if resp.Header.Get("Vary") != "Origin" {
+		t.Errorf("Expected Vary: Origin, got %q", resp.Header.Get("Vary"))
+	}
+
+	resp = corsRequest(t, server, "OPTIONS", "https://evil.example", map[string]string{"Access-Control-Request-Method": "DELETE"})
+	if resp.StatusCode != http.StatusForbidden || resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected a disallowed preflight to be rejected, got %d %v", resp.StatusCode, resp.Header)
+	}
+}
+
+func TestCORSSimpleRequests(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	cases := map[string]bool{
+		testCORSOrigin:                   true,
+		"https://APP.todo.example":       true,
+		"https://web.todo.test":          true,
+		"https://a.b.todo.test":          true,
+		"https://todo.test":              false,
+		"http://web.todo.test":           false,
+		"https://web.todo.test.evil.com": false,
+		"https://evil.com/.todo.test":    false,
+		"https://app.todo.example:8443":  false,
+	}
+	for origin, allowed := range cases {
+		resp := corsRequest(t, server, "GET", origin, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected the request to be served, got %d", origin, resp.StatusCode)
+		}
+		got := resp.Header.Get("Access-Control-Allow-Origin")
+		if allowed && (got != origin || resp.Header.Get("Access-Control-Expose-Headers") != "ETag") {
+			t.Errorf("%s: expected the origin to be allowed, got %q", origin, got)
+		}
+		if !allowed && got != "" {
+			t.Errorf("%s: expected no CORS headers, got %q", origin, got)
+		}
+	}
+
+	// 没有 Origin 的请求不是跨域请求
+	resp := corsRequest(t, server, "GET", "", nil)
+	if resp.Header.Get("Access-Control-Allow-Origin") != "" || resp.Header.Get("Vary") != "Origin" {
+		t.Errorf("Unexpected headers without Origin: %v", resp.Header)
+	}
+}
+
+func TestCORSWildcardNeverAllowsCredentials(t *testing.T) {
+	t.Parallel()
+	r := gin.New()
+	r.Use(middleware.CORS(middleware.CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true}))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest("OPTIONS", "/", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected a wildcard preflight response, got %d %v", w.Code, w.Header())
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "" || w.Header().Get("Access-Control-Max-Age") != "" {
+		t.Errorf("Expected no credentials or max age, got %v", w.Header())
+	}
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"todo-backend/internal/model"
+	"todo-backend/internal/testutil"
+)
+
+func getTodoETag(t *testing.T, server *testutil.Server, id uint) string {
+	t.Helper()
+
+	resp, err := makeRequest("GET", fmt.Sprintf("%s/api/todos/%d", server.URL, id), nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag header")
+	}
+	return etag
+}
+
+func putWithIfMatch(t *testing.T, server *testutil.Server, id uint, etag string, body model.UpdateTodoRequest) *http.Response {
+	t.Helper()
+
+	data, _ := json.Marshal(body)
+	resp, err := makeRawRequest("PUT", fmt.Sprintf("%s/api/todos/%d", server.URL, id), data, map[string]string{"If-Match": etag})
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	return resp
+}
+
+func TestUpdateTodoIfMatch(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	id := createTestTodo(t, server, "ETag Test")
+	etag := getTodoETag(t, server, id)
+
+	resp := putWithIfMatch(t, server, id, etag, model.UpdateTodoRequest{Title: "First Tab"})
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	newETag := resp.Header.Get("ETag")
+	if newETag == "" || newETag == etag {
+		t.Errorf("Expected a new ETag after update, got %q", newETag)
+	}
+
+	// 第二个标签页仍持有旧的 ETag
+	resp = putWithIfMatch(t, server, id, etag, model.UpdateTodoRequest{Title: "Second Tab"})
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412, got %d", resp.StatusCode)
+	}
+
+	if resp.Header.Get("ETag") != newETag {
+		t.Errorf("Expected current ETag %q, got %q", newETag, resp.Header.Get("ETag"))
+	}
+
+	if todo, _ := getTestTodo(t, server, id); todo["title"] != "First Tab" {
+		t.Errorf("Expected stale write to be rejected, got %v", todo["title"])
+	}
+}
+
+func TestUpdateTodoMarksIncomplete(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	id := createTestTodo(t, server, "Toggle")
+
+	resp, err := makeRequest("PUT", fmt.Sprintf("%s/api/todos/%d", server.URL, id), model.UpdateTodoRequest{Completed: boolPtr(true)})
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = makeRequest("PUT", fmt.Sprintf("%s/api/todos/%d", server.URL, id), model.UpdateTodoRequest{Completed: boolPtr(false)})
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	todo, _ := getTestTodo(t, server, id)
+	if todo["completed"] != false {
+		t.Errorf("Expected completed false, got %v", todo["completed"])
+	}
+	if todo["version"] != float64(3) {
+		t.Errorf("Expected version 3, got %v", todo["version"])
+	}
+}
+
+func TestGetTodoIfNoneMatch(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	id := createTestTodo(t, server, "Conditional GET")
+	etag := getTodoETag(t, server, id)
+
+	url := fmt.Sprintf("%s/api/todos/%d", server.URL, id)
+	resp, err := makeRawRequest("GET", url, nil, map[string]string{"If-None-Match": etag})
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected status 304, got %d", resp.StatusCode)
+	}
+
+	resp, err = makeRawRequest("GET", url, nil, map[string]string{"If-None-Match": `"0-0"`})
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestGetAllTodosIfNoneMatch(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp, err := makeRequest("GET", server.URL+"/api/todos", nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	etag := resp.Header.Get("ETag")
+	resp, err = makeRawRequest("GET", server.URL+"/api/todos", nil, map[string]string{"If-None-Match": etag})
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected status 304, got %d", resp.StatusCode)
+	}
+
+	createTestTodo(t, server, "Changes List")
+
+	resp, err = makeRawRequest("GET", server.URL+"/api/todos", nil, map[string]string{"If-None-Match": etag})
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 after change, got %d", resp.StatusCode)
+	}
+}
+
+func TestDeleteTodoIfMatch(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	id := createTestTodo(t, server, "Conditional Delete")
+	url := fmt.Sprintf("%s/api/todos/%d", server.URL, id)
+
+	resp, err := makeRawRequest("DELETE", url, nil, map[string]string{"If-Match": `"0-0"`})
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412, got %d", resp.StatusCode)
+	}
+
+	resp, err = makeRawRequest("DELETE", url, nil, map[string]string{"If-Match": getTodoETag(t, server, id)})
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-backend/internal/events"
+	"todo-backend/internal/model"
+	"todo-backend/internal/testutil"
+)
+
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+func openEventStream(t *testing.T, server *testutil.Server, lastEventID string) <-chan sseEvent {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/todos/events", nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Expected text/event-stream, got %s", ct)
+	}
+
+	stream := make(chan sseEvent, 16)
+	go func() {
+		defer resp.Body.Close()
+		defer close(stream)
+
+		var current sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if current.Event != "" {
+					stream <- current
+				}
+				current = sseEvent{}
+			case strings.HasPrefix(line, "id: "):
+				current.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				current.Event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				current.Data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+
+	return stream
+}
+
+func nextEvent(t *testing.T, stream <-chan sseEvent) sseEvent {
+	t.Helper()
+
+	select {
+	case event, ok := <-stream:
+		if !ok {
+			t.Fatal("Event stream closed unexpectedly")
+		}
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+	return sseEvent{}
+}
+
+func TestTodoEventStream(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	stream := openEventStream(t, server, "")
+
+	id := createTestTodo(t, server, "Streamed Todo")
+
+	event := nextEvent(t, stream)
+	if event.Event != events.TodoCreated {
+		t.Fatalf("Expected %s, got %s", events.TodoCreated, event.Event)
+	}
+
+	var todo model.Todo
+	if err := json.Unmarshal([]byte(event.Data), &todo); err != nil {
+		t.Fatalf("Failed to decode event data: %v", err)
+	}
+	if todo.ID != id || todo.Title != "Streamed Todo" {
+		t.Errorf("Unexpected event payload: %+v", todo)
+	}
+
+	resp, _ := makeRequest("PUT", fmt.Sprintf("%s/api/todos/%d", server.URL, id), model.UpdateTodoRequest{Completed: boolPtr(true)})
+	resp.Body.Close()
+
+	if event := nextEvent(t, stream); event.Event != events.TodoUpdated {
+		t.Errorf("Expected %s, got %s", events.TodoUpdated, event.Event)
+	}
+
+	resp, _ = makeRequest("DELETE", fmt.Sprintf("%s/api/todos/%d", server.URL, id), nil)
+	resp.Body.Close()
+
+	event = nextEvent(t, stream)
+	if event.Event != events.TodoDeleted {
+		t.Errorf("Expected %s, got %s", events.TodoDeleted, event.Event)
+	}
+	if event.Data != fmt.Sprintf(`{"id":%d}`, id) {
+		t.Errorf("Unexpected delete payload: %s", event.Data)
+	}
+}
+
+func TestTodoEventStreamResume(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	lastID := server.Hub.LastID()
+
+	createTestTodo(t, server, "Missed One")
+	createTestTodo(t, server, "Missed Two")
+
+	stream := openEventStream(t, server, fmt.Sprint(lastID))
+
+	first := nextEvent(t, stream)
+	second := nextEvent(t, stream)
+
+	if first.ID != fmt.Sprint(lastID+1) || second.ID != fmt.Sprint(lastID+2) {
+		t.Errorf("Expected events %d and %d, got %s and %s", lastID+1, lastID+2, first.ID, second.ID)
+	}
+
+	if !strings.Contains(second.Data, "Missed Two") {
+		t.Errorf("Expected replayed event for second todo, got %s", second.Data)
+	}
+}
+
+func TestTodoEventStreamReset(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	stream := openEventStream(t, server, fmt.Sprint(server.Hub.LastID()+100))
+
+	if event := nextEvent(t, stream); event.Event != "reset" {
+		t.Errorf("Expected reset event, got %s", event.Event)
+	}
+}
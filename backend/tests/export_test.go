@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-backend/internal/export"
+	"todo-backend/internal/model"
+	"todo-backend/internal/testutil"
+)
+
+func exportTodos(t *testing.T, server *testutil.Server, query string) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := makeRequest("GET", server.URL+"/api/export?"+query, nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+// createExportTodos 创建一组标题带 marker 的 Todo, 第二个标记为已完成
+func createExportTodos(t *testing.T, server *testutil.Server, marker string) {
+	t.Helper()
+
+	resp, _ := makeRequest("POST", server.URL+"/api/todos", map[string]string{
+		"title":   marker + " first",
+		"content": "line one, \"quoted\"\nline two",
+	})
+	resp.Body.Close()
+
+	id := createTestTodo(t, server, marker+" second")
+	resp, _ = makeRequest("PUT", fmt.Sprintf("%s/api/todos/%d", server.URL, id), model.UpdateTodoRequest{Completed: boolPtr(true)})
+	resp.Body.Close()
+}
+
+func TestExportCSV(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	createExportTodos(t, server, "csv-export")
+
+	resp, body := exportTodos(t, server, "format=csv&q=csv-export")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+		t.Errorf("Unexpected content type %s", resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(resp.Header.Get("Content-Disposition"), ".csv") {
+		t.Errorf("Expected csv attachment, got %s", resp.Header.Get("Content-Disposition"))
+	}
+
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse csv: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %d", len(records))
+	}
+	if strings.Join(records[0], ",") != strings.Join(export.CSVColumns, ",") {
+		t.Errorf("Unexpected header %v", records[0])
+	}
+	if records[1][2] != "line one, \"quoted\"\nline two" {
+		t.Errorf("Content was not round-tripped: %q", records[1][2])
+	}
+	if records[2][3] != "true" || records[2][6] == "" {
+		t.Errorf("Expected completed row with completed_at, got %v", records[2])
+	}
+}
+
+func TestExportMarkdownAndTodoTxt(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	createExportTodos(t, server, "text-export")
+	today := time.Now().Format("2006-01-02")
+
+	_, markdown := exportTodos(t, server, "format=markdown&q=text-export")
+	for _, want := range []string{"- [ ] text-export first\n  line one", "- [x] text-export second\n"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, markdown)
+		}
+	}
+
+	_, todoTxt := exportTodos(t, server, "format=todotxt&q=text-export")
+	lines := strings.Split(strings.TrimSpace(todoTxt), "\n")
+	want := []string{
+		today + " text-export first",
+		"x " + today + " " + today + " text-export second",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected todo.txt output:\n%s", todoTxt)
+	}
+}
+
+func TestExportJSONFiltered(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	createExportTodos(t, server, "json-export")
+
+	_, body := exportTodos(t, server, "format=json&q=json-export&completed=true")
+
+	var todos []model.Todo
+	if err := json.Unmarshal([]byte(body), &todos); err != nil {
+		t.Fatalf("Failed to decode json export: %v", err)
+	}
+	if len(todos) != 1 || todos[0].Title != "json-export second" || todos[0].CompletedAt == nil {
+		t.Errorf("Unexpected filtered export: %+v", todos)
+	}
+
+	resp, _ := exportTodos(t, server, "format=xml")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown format, got %d", resp.StatusCode)
+	}
+}
+
+func TestExportAbortsOnError(t *testing.T) {
+	t.Parallel()
+	// 查询在 CSV 表头写出之后失败, 响应已经开始发送
+	server := newTestServer(t, expiredQueryTimeout)
+	server.CreateTodo(t, "Never exported")
+
+	resp, err := http.Get(server.URL + "/api/export?format=csv")
+	if err == nil {
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Error("Expected the failed export to abort the connection instead of ending normally")
+	}
+}
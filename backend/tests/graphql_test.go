@@ -0,0 +1,362 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-backend/internal/testutil"
+
+	"github.com/gorilla/websocket"
+)
+
+type graphqlResult struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+type graphqlTodo struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Completed bool   `json:"completed"`
+	Version   int    `json:"version"`
+}
+
+func graphqlPost(t *testing.T, server *testutil.Server, query string, variables map[string]interface{}) (int, graphqlResult) {
+	t.Helper()
+
+	resp, err := makeRequest("POST", server.URL+"/graphql", map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result graphqlResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.StatusCode, result
+}
+
+func graphqlField(t *testing.T, result graphqlResult, field string, v interface{}) {
+	t.Helper()
+
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %+v", result.Errors)
+	}
+	if err := json.Unmarshal(result.Data[field], v); err != nil {
+		t.Fatalf("Failed to decode %s: %v", field, err)
+	}
+}
+
+func TestGraphQLTodosPagination(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	prefix := fmt.Sprintf("GraphQL Page %d", time.Now().UnixNano())
+	for i := 0; i < 3; i++ {
+		createTestTodo(t, server, fmt.Sprintf("%s-%d", prefix, i))
+	}
+
+	query := `query($q: String, $after: String) {
+		todos(query: $q, first: 2, after: $after) {
+			nodes { id title }
+			pageInfo { hasNextPage endCursor }
+			totalCount
+		}
+	}`
+	var page struct {
+		Nodes    []graphqlTodo `json:"nodes"`
+		PageInfo struct {
+			HasNextPage bool   `json:"hasNextPage"`
+			EndCursor   string `json:"endCursor"`
+		} `json:"pageInfo"`
+		TotalCount int `json:"totalCount"`
+	}
+
+	_, result := graphqlPost(t, server, query, map[string]interface{}{"q": prefix})
+	graphqlField(t, result, "todos", &page)
+	if len(page.Nodes) != 2 || !page.PageInfo.HasNextPage || page.TotalCount != 3 {
+		t.Fatalf("Unexpected first page: %+v", page)
+	}
+	if page.Nodes[0].Title != prefix+"-0" {
+		t.Errorf("Expected todos in creation order, got %s", page.Nodes[0].Title)
+	}
+
+	_, result = graphqlPost(t, server, query, map[string]interface{}{"q": prefix, "after": page.PageInfo.EndCursor})
+	graphqlField(t, result, "todos", &page)
+	if len(page.Nodes) != 1 || page.PageInfo.HasNextPage || page.Nodes[0].Title != prefix+"-2" {
+		t.Errorf("Unexpected second page: %+v", page)
+	}
+
+	// GET 请求同样可以执行查询
+	resp, err := http.Get(server.URL + "/graphql?query=" + url.QueryEscape(`{ todos(query: "GraphQL Page") { totalCount } }`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 for GET query, got %d", resp.StatusCode)
+	}
+}
+
+func TestGraphQLTodoByID(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	id := createTestTodo(t, server, "GraphQL Single")
+
+	var todo *graphqlTodo
+	_, result := graphqlPost(t, server, `query($id: ID!) { todo(id: $id) { id title completed } }`, map[string]interface{}{"id": id})
+	graphqlField(t, result, "todo", &todo)
+	if todo == nil || todo.Title != "GraphQL Single" {
+		t.Fatalf("Unexpected todo: %+v", todo)
+	}
+
+	todo = nil
+	_, result = graphqlPost(t, server, `{ todo(id: "999999") { id } }`, nil)
+	graphqlField(t, result, "todo", &todo)
+	if todo != nil {
+		t.Errorf("Expected null for a missing todo, got %+v", todo)
+	}
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	var created graphqlTodo
+	_, result := graphqlPost(t, server, `mutation { createTodo(input: {title: "GraphQL Created"}) { id title version } }`, nil)
+	graphqlField(t, result, "createTodo", &created)
+	if created.Title != "GraphQL Created" || created.Version != 1 {
+		t.Fatalf("Unexpected created todo: %+v", created)
+	}
+
+	var updated graphqlTodo
+	_, result = graphqlPost(t, server, `mutation($id: ID!) { updateTodo(id: $id, input: {title: "GraphQL Renamed"}, version: 1) { title completed version } }`,
+		map[string]interface{}{"id": created.ID})
+	graphqlField(t, result, "updateTodo", &updated)
+	if updated.Title != "GraphQL Renamed" || updated.Completed || updated.Version != 2 {
+		t.Errorf("Unexpected updated todo: %+v", updated)
+	}
+
+	var toggled graphqlTodo
+	_, result = graphqlPost(t, server, `mutation($id: ID!) { toggleTodo(id: $id) { title completed } }`, map[string]interface{}{"id": created.ID})
+	graphqlField(t, result, "toggleTodo", &toggled)
+	if !toggled.Completed || toggled.Title != "GraphQL Renamed" {
+		t.Errorf("Expected toggle to keep the title and complete the todo, got %+v", toggled)
+	}
+
+	// 过期的版本号返回 VERSION_CONFLICT
+	_, result = graphqlPost(t, server, `mutation($id: ID!) { deleteTodo(id: $id, version: 1) }`, map[string]interface{}{"id": created.ID})
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "VERSION_CONFLICT" {
+		t.Fatalf("Expected a version conflict, got %+v", result.Errors)
+	}
+
+	var deleted string
+	_, result = graphqlPost(t, server, `mutation($id: ID!) { deleteTodo(id: $id) }`, map[string]interface{}{"id": created.ID})
+	graphqlField(t, result, "deleteTodo", &deleted)
+	if deleted != created.ID {
+		t.Errorf("Expected deleted id %s, got %s", created.ID, deleted)
+	}
+
+	resp, _ := http.Get(fmt.Sprintf("%s/api/todos/%s", server.URL, created.ID))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected deleted todo to be gone, got status %d", resp.StatusCode)
+	}
+}
+
+func TestGraphQLValidationErrors(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	_, result := graphqlPost(t, server, `mutation { createTodo(input: {title: ""}) { id } }`, nil)
+	if len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "BAD_USER_INPUT" {
+		t.Errorf("Expected BAD_USER_INPUT for an empty title, got %+v", result.Errors)
+	}
+
+	status, _ := graphqlPost(t, server, `{ todos { nodes { missing } } }`, nil)
+	if status != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown field, got %d", status)
+	}
+
+	resp, err := http.Get(server.URL + "/graphql?query=" + url.QueryEscape(`mutation { deleteTodo(id: "1") }`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for a mutation over GET, got %d", resp.StatusCode)
+	}
+}
+
+func TestGraphQLDepthLimit(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	// 测试服务器的最大深度为 3
+	status, result := graphqlPost(t, server, `fragment F on Todo { id } { todos { nodes { ...F } } }`, nil)
+	if status != http.StatusOK || len(result.Errors) != 0 {
+		t.Fatalf("Expected a shallow query to pass, got %d %+v", status, result.Errors)
+	}
+
+	// 片段和内联片段中的字段同样计入深度
+	for _, query := range []string{
+		`{ todos { edges { node { id } } } }`,
+		`fragment F on TodoEdge { node { id } } { todos { edges { ...F } } }`,
+		`{ todos { edges { ... on TodoEdge { node { id } } } } }`,
+	} {
+		status, result = graphqlPost(t, server, query, nil)
+		if status != http.StatusBadRequest || len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "QUERY_TOO_COMPLEX" {
+			t.Errorf("Expected the depth limit to reject %s, got %d %+v", query, status, result.Errors)
+		}
+	}
+}
+
+func TestGraphQLComplexityLimit(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	// 测试服务器的最大复杂度为 1000, 每个字段计 1 点, 分页字段的子字段乘以 first
+	status, result := graphqlPost(t, server, `{ todos(first: 100) { nodes { id title content completed version } } }`, nil)
+	if status != http.StatusOK || len(result.Errors) != 0 {
+		t.Fatalf("Expected a query within the limit to pass, got %d %+v", status, result.Errors)
+	}
+
+	status, result = graphqlPost(t, server, `{
+		a: todos(first: 100) { nodes { id title content completed version } }
+		b: todos(first: 100) { nodes { id title content completed version } }
+	}`, nil)
+	if status != http.StatusBadRequest || len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != "QUERY_TOO_COMPLEX" {
+		t.Errorf("Expected the complexity limit to reject the query, got %d %+v", status, result.Errors)
+	}
+
+	// 通过变量传入的 first 同样计入复杂度
+	status, _ = graphqlPost(t, server, `query($n: Int) { a: todos(first: $n) { nodes { id title content } } b: todos(first: $n) { nodes { id title content } } c: todos(first: $n) { nodes { id title content } } }`,
+		map[string]interface{}{"n": 100})
+	if status != http.StatusBadRequest {
+		t.Errorf("Expected variables to count towards complexity, got %d", status)
+	}
+}
+
+func dialGraphQL(t *testing.T, server *testutil.Server) *websocket.Conn {
+	t.Helper()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}, HandshakeTimeout: 2 * time.Second}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("Failed to dial websocket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if err := conn.WriteJSON(map[string]interface{}{"type": "connection_init"}); err != nil {
+		t.Fatalf("Failed to send connection_init: %v", err)
+	}
+	if msg := readGraphQLMessage(t, conn); msg.Type != "connection_ack" {
+		t.Fatalf("Expected connection_ack, got %s", msg.Type)
+	}
+	return conn
+}
+
+type graphqlWSMessage struct {
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func readGraphQLMessage(t *testing.T, conn *websocket.Conn) graphqlWSMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg graphqlWSMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	return msg
+}
+
+func TestGraphQLSubscription(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	conn := dialGraphQL(t, server)
+
+	conn.WriteJSON(map[string]interface{}{
+		"id":      "1",
+		"type":    "subscribe",
+		"payload": map[string]interface{}{"query": `subscription { todoChanged { type todo { title } } }`},
+	})
+	// 等待订阅生效
+	time.Sleep(100 * time.Millisecond)
+
+	createTestTodo(t, server, "GraphQL Subscribed")
+
+	for i := 0; i < 20; i++ {
+		msg := readGraphQLMessage(t, conn)
+		if msg.ID != "1" || msg.Type != "next" {
+			t.Fatalf("Expected next for subscription 1, got %s %s", msg.Type, msg.ID)
+		}
+
+		var result struct {
+			Data struct {
+				TodoChanged struct {
+					Type string `json:"type"`
+					Todo struct {
+						Title string `json:"title"`
+					} `json:"todo"`
+				} `json:"todoChanged"`
+			} `json:"data"`
+		}
+		json.Unmarshal(msg.Payload, &result)
+		if result.Data.TodoChanged.Todo.Title == "GraphQL Subscribed" {
+			if result.Data.TodoChanged.Type != "todo.created" {
+				t.Errorf("Expected todo.created, got %s", result.Data.TodoChanged.Type)
+			}
+			break
+		}
+	}
+
+	// 客户端结束订阅后服务端不再回复 complete, 查询操作执行一次后由服务端发送 complete
+	conn.WriteJSON(map[string]interface{}{"id": "1", "type": "complete"})
+	conn.WriteJSON(map[string]interface{}{
+		"id":      "2",
+		"type":    "subscribe",
+		"payload": map[string]interface{}{"query": `{ todos(first: 1) { totalCount } }`},
+	})
+	for _, expected := range []string{"next", "complete"} {
+		msg := readGraphQLMessage(t, conn)
+		for msg.ID == "1" && msg.Type == "next" {
+			msg = readGraphQLMessage(t, conn)
+		}
+		if msg.ID != "2" || msg.Type != expected {
+			t.Fatalf("Expected %s for operation 2, got %s %s", expected, msg.Type, msg.ID)
+		}
+	}
+}
+
+func TestGraphQLSubscriptionRequiresInit(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("Failed to dial websocket: %v", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(map[string]interface{}{"id": "1", "type": "subscribe", "payload": map[string]interface{}{"query": `subscription { todoChanged { type } }`}})
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, 4401) {
+		t.Errorf("Expected close code 4401, got %v", err)
+	}
+}
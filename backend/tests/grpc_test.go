@@ -0,0 +1,330 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-backend/internal/model"
+	"todo-backend/internal/server"
+	"todo-backend/internal/testutil"
+	todov1 "todo-backend/proto/todo/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// todoClient 在内存中启动 gRPC 服务, 与测试的 REST 服务共用同一个数据库和事件中心
+func todoClient(t *testing.T, srv *testutil.Server) todov1.TodoServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := server.NewGRPCServer(srv.Deps)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial gRPC server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return todov1.NewTodoServiceClient(conn)
+}
+
+func grpcContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// restTodo 通过 REST 接口读取 Todo 的原始 JSON
+func restTodo(t *testing.T, srv *testutil.Server, id uint32) map[string]interface{} {
+	t.Helper()
+
+	resp, err := http.Get(fmt.Sprintf("%s/api/todos/%d", srv.URL, id))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	return body.Data
+}
+
+// assertParity 按 grpc-gateway 的 JSON 映射比较 gRPC 消息和 REST 响应, 时间统一换算为 UTC 比较
+func assertParity(t *testing.T, rest map[string]interface{}, msg proto.Message) {
+	t.Helper()
+
+	data, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to marshal message: %v", err)
+	}
+	var got map[string]interface{}
+	json.Unmarshal(data, &got)
+
+	if len(got) != len(rest) {
+		t.Errorf("Field sets differ: gRPC %v, REST %v", got, rest)
+	}
+	for field, want := range rest {
+		if normalizeJSONValue(got[field]) != normalizeJSONValue(want) {
+			t.Errorf("Field %s differs: gRPC %v, REST %v", field, got[field], want)
+		}
+	}
+}
+
+func normalizeJSONValue(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return ts.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return v
+}
+
+func grpcCode(err error) codes.Code {
+	return status.Code(err)
+}
+
+func TestGRPCGetMatchesREST(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	client := todoClient(t, srv)
+	id := createTestTodo(t, srv, "gRPC Parity Get")
+
+	got, err := client.GetTodo(grpcContext(t), &todov1.GetTodoRequest{Id: uint32(id)})
+	if err != nil {
+		t.Fatalf("GetTodo failed: %v", err)
+	}
+	assertParity(t, restTodo(t, srv, uint32(id)), got.GetTodo())
+
+	// 完成后 completed_at 在两边都有值
+	resp, _ := makeRequest("PUT", fmt.Sprintf("%s/api/todos/%d", srv.URL, id), model.UpdateTodoRequest{Completed: boolPtr(true)})
+	resp.Body.Close()
+	got, err = client.GetTodo(grpcContext(t), &todov1.GetTodoRequest{Id: uint32(id)})
+	if err != nil {
+		t.Fatalf("GetTodo failed: %v", err)
+	}
+	if got.GetTodo().GetCompletedAt() == nil {
+		t.Error("Expected completed_at to be set")
+	}
+	assertParity(t, restTodo(t, srv, uint32(id)), got.GetTodo())
+
+	_, err = client.GetTodo(grpcContext(t), &todov1.GetTodoRequest{Id: 999999})
+	if grpcCode(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestGRPCMutationsMatchREST(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	client := todoClient(t, srv)
+
+	created, err := client.CreateTodo(grpcContext(t), &todov1.CreateTodoRequest{Title: "  gRPC Parity Create  ", Content: "line\r\nbreak"})
+	if err != nil {
+		t.Fatalf("CreateTodo failed: %v", err)
+	}
+	todo := created.GetTodo()
+	if todo.GetTitle() != "gRPC Parity Create" || todo.GetContent() != "line\nbreak" {
+		t.Errorf("Expected the same normalization as REST, got %q %q", todo.GetTitle(), todo.GetContent())
+	}
+	assertParity(t, restTodo(t, srv, todo.GetId()), todo)
+
+	updated, err := client.UpdateTodo(grpcContext(t), &todov1.UpdateTodoRequest{Id: todo.GetId(), Completed: proto.Bool(true), Version: todo.GetVersion()})
+	if err != nil {
+		t.Fatalf("UpdateTodo failed: %v", err)
+	}
+	if updated.GetTodo().GetTitle() != todo.GetTitle() || !updated.GetTodo().GetCompleted() || updated.GetTodo().GetVersion() != todo.GetVersion()+1 {
+		t.Errorf("Unexpected updated todo: %v", updated.GetTodo())
+	}
+	assertParity(t, restTodo(t, srv, todo.GetId()), updated.GetTodo())
+
+	// 过期的版本号: gRPC 返回 FailedPrecondition, REST 返回 412
+	_, err = client.UpdateTodo(grpcContext(t), &todov1.UpdateTodoRequest{Id: todo.GetId(), Title: "stale", Version: todo.GetVersion()})
+	if grpcCode(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition, got %v", err)
+	}
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("%s/api/todos/%d", srv.URL, todo.GetId()), strings.NewReader(`{"title":"stale"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", fmt.Sprintf(`"%d-%d"`, todo.GetId(), todo.GetVersion()))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412, got %d", resp.StatusCode)
+	}
+
+	// 没有设置 completed 时保持完成状态
+	renamed, err := client.UpdateTodo(grpcContext(t), &todov1.UpdateTodoRequest{Id: todo.GetId(), Title: "gRPC Parity Renamed"})
+	if err != nil {
+		t.Fatalf("UpdateTodo failed: %v", err)
+	}
+	if renamed.GetTodo().GetTitle() != "gRPC Parity Renamed" || !renamed.GetTodo().GetCompleted() {
+		t.Errorf("Expected only the title to change, got %v", renamed.GetTodo())
+	}
+
+	if _, err := client.DeleteTodo(grpcContext(t), &todov1.DeleteTodoRequest{Id: todo.GetId()}); err != nil {
+		t.Fatalf("DeleteTodo failed: %v", err)
+	}
+	resp, _ = http.Get(fmt.Sprintf("%s/api/todos/%d", srv.URL, todo.GetId()))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected REST to return 404 after gRPC delete, got %d", resp.StatusCode)
+	}
+	if _, err := client.DeleteTodo(grpcContext(t), &todov1.DeleteTodoRequest{Id: todo.GetId()}); grpcCode(err) != codes.NotFound {
+		t.Errorf("Expected NotFound when deleting twice, got %v", err)
+	}
+}
+
+func TestGRPCValidationMatchesREST(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	client := todoClient(t, srv)
+
+	for _, locale := range []string{"en", "zh-CN"} {
+		ctx := metadata.AppendToOutgoingContext(grpcContext(t), "accept-language", locale)
+		_, grpcErr := client.CreateTodo(ctx, &todov1.CreateTodoRequest{Title: "bad\ntitle"})
+		if grpcCode(grpcErr) != codes.InvalidArgument {
+			t.Fatalf("Expected InvalidArgument, got %v", grpcErr)
+		}
+
+		req, _ := http.NewRequest("POST", srv.URL+"/api/todos", strings.NewReader(`{"title":"bad\ntitle"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", locale)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		body, _ := parseResponse(resp)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || body.Message != status.Convert(grpcErr).Message() {
+			t.Errorf("Expected matching %s messages, REST %d %q, gRPC %q", locale, resp.StatusCode, body.Message, status.Convert(grpcErr).Message())
+		}
+	}
+
+	_, err := client.ListTodos(grpcContext(t), &todov1.ListTodosRequest{PageToken: "not-a-token"})
+	if grpcCode(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a bad page token, got %v", err)
+	}
+}
+
+func TestGRPCListTodos(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	client := todoClient(t, srv)
+
+	prefix := fmt.Sprintf("gRPC List %d", time.Now().UnixNano())
+	var ids []uint32
+	for i := 0; i < 5; i++ {
+		ids = append(ids, uint32(createTestTodo(t, srv, fmt.Sprintf("%s-%d", prefix, i))))
+	}
+
+	var listed []uint32
+	req := &todov1.ListTodosRequest{Query: prefix, PageSize: 2}
+	for pages := 0; ; pages++ {
+		resp, err := client.ListTodos(grpcContext(t), req)
+		if err != nil {
+			t.Fatalf("ListTodos failed: %v", err)
+		}
+		if resp.GetTotalSize() != 5 {
+			t.Errorf("Expected total_size 5, got %d", resp.GetTotalSize())
+		}
+		for _, todo := range resp.GetTodos() {
+			listed = append(listed, todo.GetId())
+		}
+		if resp.GetNextPageToken() == "" {
+			if pages != 2 {
+				t.Errorf("Expected 3 pages, got %d", pages+1)
+			}
+			break
+		}
+		req.PageToken = resp.GetNextPageToken()
+	}
+	if fmt.Sprint(listed) != fmt.Sprint(ids) {
+		t.Errorf("Expected %v in ID order, got %v", ids, listed)
+	}
+
+	// 每一项都与 REST 返回的内容一致
+	completed := false
+	resp, err := client.ListTodos(grpcContext(t), &todov1.ListTodosRequest{Query: prefix, Completed: &completed, PageSize: 100})
+	if err != nil {
+		t.Fatalf("ListTodos failed: %v", err)
+	}
+	for _, todo := range resp.GetTodos() {
+		assertParity(t, restTodo(t, srv, todo.GetId()), todo)
+	}
+
+	if _, err := client.ListTodos(grpcContext(t), &todov1.ListTodosRequest{PageSize: 1000}); grpcCode(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a large page size, got %v", err)
+	}
+}
+
+func TestGRPCWatchTodos(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+	client := todoClient(t, srv)
+	target := createTestTodo(t, srv, "gRPC Watch Target")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchTodos(ctx, &todov1.WatchTodosRequest{Id: uint32(target)})
+	if err != nil {
+		t.Fatalf("WatchTodos failed: %v", err)
+	}
+	// 等待订阅生效
+	time.Sleep(100 * time.Millisecond)
+
+	createTestTodo(t, srv, "gRPC Watch Other")
+	resp, _ := makeRequest("PUT", fmt.Sprintf("%s/api/todos/%d", srv.URL, target), model.UpdateTodoRequest{Title: "gRPC Watch Renamed"})
+	resp.Body.Close()
+
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	event := msg.GetEvent()
+	if event.GetType() != "todo.updated" || event.GetTodoId() != uint32(target) || event.GetTodo().GetTitle() != "gRPC Watch Renamed" {
+		t.Fatalf("Unexpected event: %v", event)
+	}
+
+	resp, _ = makeRequest("DELETE", fmt.Sprintf("%s/api/todos/%d", srv.URL, target), nil)
+	resp.Body.Close()
+	msg, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if msg.GetEvent().GetType() != "todo.deleted" || msg.GetEvent().GetTodo() != nil {
+		t.Errorf("Expected a delete event without a todo, got %v", msg.GetEvent())
+	}
+
+	// 凭事件 ID 续传时先补发之后的事件
+	lastEventID := event.GetId() - 1
+	resumed, err := client.WatchTodos(ctx, &todov1.WatchTodosRequest{Id: uint32(target), LastEventId: &lastEventID})
+	if err != nil {
+		t.Fatalf("WatchTodos failed: %v", err)
+	}
+	if msg, err := resumed.Recv(); err != nil || msg.GetEvent().GetId() != event.GetId() {
+		t.Errorf("Expected to resume from event %d, got %v %v", event.GetId(), msg, err)
+	}
+}
@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"todo-backend/internal/config"
+	"todo-backend/internal/middleware"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func postWithIdempotencyKey(t *testing.T, server *testutil.Server, key string, body interface{}) (*testutil.Response, model.Response) {
+	t.Helper()
+
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequest("POST", server.URL+"/api/todos", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+
+	resp := server.Do(t, req)
+	return resp, resp.Envelope(t, nil)
+}
+
+func todoID(t *testing.T, response model.Response) float64 {
+	t.Helper()
+
+	todo, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected todo in response, got %v", response.Data)
+	}
+	return todo["id"].(float64)
+}
+
+func TestIdempotentCreateReplay(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+	body := model.CreateTodoRequest{Title: "Idempotent Create"}
+
+	first, firstResponse := postWithIdempotencyKey(t, server, "retry-key", body)
+	if first.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", first.StatusCode)
+	}
+
+	second, secondResponse := postWithIdempotencyKey(t, server, "retry-key", body)
+	if second.StatusCode != http.StatusCreated {
+		t.Errorf("Expected replayed status 201, got %d", second.StatusCode)
+	}
+
+	if second.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("Expected Idempotent-Replayed header on retry")
+	}
+
+	if todoID(t, firstResponse) != todoID(t, secondResponse) {
+		t.Errorf("Expected the same todo to be returned, got %v and %v", todoID(t, firstResponse), todoID(t, secondResponse))
+	}
+
+	var count int64
+	server.DB.Model(&model.Todo{}).Where("title = ?", "Idempotent Create").Count(&count)
+	if count != 1 {
+		t.Errorf("Expected exactly one todo to be created, got %d", count)
+	}
+}
+
+func TestIdempotencyKeyPayloadMismatch(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	postWithIdempotencyKey(t, server, "retry-key", model.CreateTodoRequest{Title: "Original Payload"})
+
+	resp, _ := postWithIdempotencyKey(t, server, "retry-key", model.CreateTodoRequest{Title: "Different Payload"})
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", resp.StatusCode)
+	}
+}
+
+func TestIdempotencyKeyExpires(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+	body := model.CreateTodoRequest{Title: "Expiring Key"}
+
+	_, firstResponse := postWithIdempotencyKey(t, server, "retry-key", body)
+
+	server.DB.Model(&model.IdempotencyKey{}).
+		Where("key = ?", "retry-key").
+		Update("expires_at", time.Now().Add(-time.Minute))
+
+	resp, secondResponse := postWithIdempotencyKey(t, server, "retry-key", body)
+	if resp.Header.Get("Idempotent-Replayed") != "" {
+		t.Error("Expected expired key not to be replayed")
+	}
+
+	if todoID(t, firstResponse) == todoID(t, secondResponse) {
+		t.Error("Expected a new todo after the key expired")
+	}
+}
+
+func TestIdempotencyIgnoredWithoutKey(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+	body := model.CreateTodoRequest{Title: "No Key"}
+
+	_, first := postWithIdempotencyKey(t, server, "", body)
+	_, second := postWithIdempotencyKey(t, server, "", body)
+
+	if todoID(t, first) == todoID(t, second) {
+		t.Error("Expected requests without a key to create separate todos")
+	}
+}
+
+func TestIdempotencyKeyReleasedAfterPanic(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	var calls int32
+	r := gin.New()
+	r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, _ interface{}) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	r.Use(middleware.Idempotency(repository.NewIdempotencyRepository(server.DB), middleware.IdempotencyOptions{
+		TTL:          time.Hour,
+		MaxBodyBytes: 1 << 10,
+	}))
+	r.POST("/panic", func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			panic("handler failed")
+		}
+		c.JSON(http.StatusCreated, model.Response{Code: 0, Message: "success"})
+	})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	post := func() int {
+		req, _ := http.NewRequest("POST", ts.URL+"/panic", strings.NewReader(`{}`))
+		req.Header.Set("Idempotency-Key", "panic-key")
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post(); status != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d", status)
+	}
+	// 键已释放, 重试会再次执行处理器而不是返回 409
+	if status := post(); status != http.StatusCreated {
+		t.Errorf("Expected the retry to succeed, got %d", status)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the handler to run twice, got %d", calls)
+	}
+}
+
+func TestIdempotentImportUsesRouteBodyLimit(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t, func(cfg *config.Config) {
+		cfg.MaxBodyBytes = 1 << 10
+		cfg.ImportMaxBytes = 1 << 20
+	})
+
+	// 超过全局 MaxBodyBytes, 但没有超过导入接口的上限
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "todos.txt")
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(part, "Large idempotent import %d %s\n", i, strings.Repeat("x", 40))
+	}
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", server.URL+"/api/import", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Idempotency-Key", "import-key")
+	resp := server.Do(t, req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", resp.StatusCode, resp.Body)
+	}
+}
+
+func TestIdempotencyCleanupDeletesExpiredKeys(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t, func(cfg *config.Config) {
+		cfg.IdempotencyTTL = time.Millisecond
+		cfg.IdempotencyCleanupInterval = 10 * time.Millisecond
+	})
+
+	req, _ := http.NewRequest("POST", server.URL+"/api/todos", strings.NewReader(`{"title":"Cleaned up"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", "cleanup-key")
+	testutil.AssertSuccess(t, server.Do(t, req), http.StatusCreated, nil)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var count int64
+		server.DB.Model(&model.IdempotencyKey{}).Count(&count)
+		if count == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected expired key to be deleted, %d left", count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-backend/internal/importer"
+	"todo-backend/internal/model"
+	"todo-backend/internal/testutil"
+)
+
+func uploadImport(t *testing.T, server *testutil.Server, filename, content string, fields map[string]string) (int, model.ImportReport) {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write([]byte(content))
+	writer.Close()
+
+	resp, err := http.Post(server.URL+"/api/import", writer.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Data model.ImportReport `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&envelope)
+	return resp.StatusCode, envelope.Data
+}
+
+func TestImportCSVWithDryRun(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	csv := "title,description,done,created,due,tags\n" +
+		"CSV Import One,\"multi\nline\",yes,2023-05-01,2023-06-01,\"work, home\"\n" +
+		"CSV Import Two,,no,,,\n" +
+		"CSV Import One,again,no,2023-05-01,,\n" +
+		",missing title,no,,,\n" +
+		"CSV Import Bad Date,,no,yesterday,,\n"
+
+	status, report := uploadImport(t, server, "todos.csv", csv, map[string]string{"dry_run": "true"})
+	if status != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", status)
+	}
+	if !report.DryRun || report.Format != importer.FormatCSV {
+		t.Errorf("Unexpected report header: %+v", report)
+	}
+	if report.Created != 2 || report.Duplicates != 1 || report.Failed != 2 {
+		t.Errorf("Unexpected dry run counts: %+v", report)
+	}
+	if report.Rows[0].Status != model.ImportWouldCreate || report.Rows[0].Row != 2 {
+		t.Errorf("Unexpected first row: %+v", report.Rows[0])
+	}
+	if report.Rows[3].Status != model.ImportError || report.Rows[3].Row != 6 {
+		t.Errorf("Expected error on line 6, got %+v", report.Rows[3])
+	}
+
+	var count int64
+	server.DB.Model(&model.Todo{}).Where("title LIKE ?", "CSV Import%").Count(&count)
+	if count != 0 {
+		t.Fatalf("Expected dry run not to create todos, got %d", count)
+	}
+
+	_, report = uploadImport(t, server, "todos.csv", csv, nil)
+	if report.Created != 2 {
+		t.Fatalf("Expected 2 todos to be created, got %+v", report)
+	}
+
+	todo, _ := getTestTodo(t, server, report.Rows[0].ID)
+	if todo["content"] != "multi\nline\n\nLabels: work, home" || todo["completed"] != true {
+		t.Errorf("Unexpected imported todo: %v", todo)
+	}
+	if !strings.HasPrefix(todo["created_at"].(string), "2023-05-01") || !strings.HasPrefix(todo["due_at"].(string), "2023-06-01") {
+		t.Errorf("Expected imported dates, got %v", todo)
+	}
+
+	// 再次导入时全部识别为重复
+	_, report = uploadImport(t, server, "todos.csv", csv, nil)
+	if report.Created != 0 || report.Duplicates != 3 {
+		t.Errorf("Expected re-import to be de-duplicated, got %+v", report)
+	}
+}
+
+func TestImportTodoTxt(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	txt := "x 2023-02-03 2023-02-01 Todo.txt done +chores @home\n" +
+		"(A) 2023-02-02 Todo.txt open due:2023-03-01\n" +
+		"\n" +
+		"2023-02-02 Todo.txt bad due:soon\n"
+
+	_, report := uploadImport(t, server, "todo.txt", txt, nil)
+	if report.Format != importer.FormatTodoTxt || report.Created != 2 || report.Failed != 1 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if report.Rows[2].Row != 4 {
+		t.Errorf("Expected error on line 4, got %+v", report.Rows[2])
+	}
+
+	done, _ := getTestTodo(t, server, report.Rows[0].ID)
+	if done["title"] != "Todo.txt done +chores @home" || done["completed"] != true || !strings.HasPrefix(done["completed_at"].(string), "2023-02-03") {
+		t.Errorf("Unexpected completed todo: %v", done)
+	}
+
+	open, _ := getTestTodo(t, server, report.Rows[1].ID)
+	if open["title"] != "Todo.txt open" || open["content"] != "Labels: priority:A" {
+		t.Errorf("Unexpected open todo: %v", open)
+	}
+}
+
+func TestImportTodoist(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	todoist := "TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE\n" +
+		"section,Errands,,,,,,,,\n" +
+		"task,Todoist parent @shopping,Buy things,4,1,,,2023-04-01,en,\n" +
+		"task,Milk,,1,2,,,,en,\n" +
+		"note,Remember the coupon,,,,,,,,\n" +
+		"task,Todoist recurring,,1,1,,,every monday,en,\n"
+
+	_, report := uploadImport(t, server, "export.csv", todoist, nil)
+	if report.Format != importer.FormatTodoist || report.Created != 2 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	parent, _ := getTestTodo(t, server, report.Rows[0].ID)
+	want := "Buy things\n\nRemember the coupon\n\n- [ ] Milk\n\nLabels: Errands, shopping, p1"
+	if parent["title"] != "Todoist parent" || parent["content"] != want {
+		t.Errorf("Unexpected todoist todo: %q", parent["content"])
+	}
+
+	recurring, _ := getTestTodo(t, server, report.Rows[1].ID)
+	if !strings.Contains(recurring["content"].(string), "Due: every monday") || recurring["due_at"] != nil {
+		t.Errorf("Expected recurring date to be kept in content, got %v", recurring)
+	}
+}
+
+func TestImportTrello(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	created := time.Date(2023, 1, 15, 12, 0, 0, 0, time.UTC)
+	board := map[string]interface{}{
+		"lists":  []map[string]interface{}{{"id": "l1", "name": "Doing"}, {"id": "l2", "name": "Old", "closed": true}},
+		"labels": []map[string]interface{}{{"id": "b1", "name": "", "color": "red"}},
+		"checklists": []map[string]interface{}{{"id": "c1", "checkItems": []map[string]interface{}{
+			{"name": "Step one", "state": "complete"}, {"name": "Step two", "state": "incomplete"},
+		}}},
+		"cards": []map[string]interface{}{
+			// Trello ID 的前 8 位是十六进制时间戳
+			{"id": fmt.Sprintf("%08x", created.Unix()) + "0000000000000000", "name": "Trello card", "desc": "From board",
+				"idList": "l1", "idLabels": []string{"b1"}, "idChecklists": []string{"c1"}, "due": "2023-02-01T09:00:00.000Z", "dueComplete": true},
+			{"id": "0000000000000000000000", "name": "Archived card", "idList": "l2"},
+		},
+	}
+	data, _ := json.Marshal(board)
+	_, report := uploadImport(t, server, "board.json", string(data), nil)
+	if report.Format != importer.FormatTrello || report.Created != 1 || report.Skipped != 1 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	todo, _ := getTestTodo(t, server, report.Rows[0].ID)
+	want := "From board\n\n- [x] Step one\n- [ ] Step two\n\nLabels: Doing, red"
+	if todo["content"] != want || todo["completed"] != true {
+		t.Errorf("Unexpected trello todo: %v", todo)
+	}
+	if ts, _ := time.Parse(time.RFC3339, todo["created_at"].(string)); !ts.Equal(created) {
+		t.Errorf("Expected created_at %v, got %v", created, todo["created_at"])
+	}
+}
+
+func TestImportRejectsBadUploads(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	status, _ := uploadImport(t, server, "notes.md", "# hello", nil)
+	if status != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown format, got %d", status)
+	}
+
+	rows := strings.Repeat("Too many rows\n", 101)
+	status, _ = uploadImport(t, server, "todo.txt", rows, nil)
+	if status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413 for too many rows, got %d", status)
+	}
+
+	status, _ = uploadImport(t, server, "big.txt", strings.Repeat("x", 2<<20), nil)
+	if status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413 for large file, got %d", status)
+	}
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"todo-backend/internal/config"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/testutil"
+)
+
+// TestConcurrentWriteLoad 在 WAL 文件数据库上并发创建和更新 Todo, 同时不断读取列表,
+// 所有请求都应该成功, 不出现 database is locked
+func TestConcurrentWriteLoad(t *testing.T) {
+	if testing.Short() {
+		t.Skip("load test")
+	}
+	t.Parallel()
+	server := testutil.NewServer(t, func(cfg *config.Config) {
+		cfg.DatabasePath = filepath.Join(t.TempDir(), "load.db")
+	})
+
+	const (
+		writers   = 8
+		perWriter = 25
+		updates   = 2
+	)
+
+	var (
+		wg       sync.WaitGroup
+		requests int64
+		mu       sync.Mutex
+		errs     []string
+	)
+	// 工作 goroutine 中不能调用 t.Fatal, 错误先收集起来, 等所有 goroutine 结束后再报告
+	record := func(format string, args ...interface{}) {
+		mu.Lock()
+		errs = append(errs, fmt.Sprintf(format, args...))
+		mu.Unlock()
+	}
+	send := func(method, path string, body interface{}, op string) (*testutil.Response, bool) {
+		atomic.AddInt64(&requests, 1)
+		resp, err := server.TryRequest(method, path, body)
+		if err != nil {
+			record("%s: %v", op, err)
+			return nil, false
+		}
+		if resp.StatusCode >= 300 {
+			record("%s: %d %s", op, resp.StatusCode, resp.Body)
+			return nil, false
+		}
+		return resp, true
+	}
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				send("GET", "/api/todos", nil, "list")
+			}
+		}()
+	}
+
+	started := time.Now()
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				title := fmt.Sprintf("Load %d-%d", w, i)
+				resp, ok := send("POST", "/api/todos", model.CreateTodoRequest{Title: title}, "create "+title)
+				if !ok {
+					continue
+				}
+				var todo model.Todo
+				if _, err := resp.DecodeEnvelope(&todo); err != nil {
+					record("create %s: %v", title, err)
+					continue
+				}
+
+				path := fmt.Sprintf("/api/todos/%d", todo.ID)
+				// 先标记完成, 之后只改标题; 没有提供的字段不能被覆盖
+				send("PUT", path, map[string]interface{}{"completed": true}, "complete "+title)
+				for u := 2; u <= updates; u++ {
+					send("PUT", path, map[string]interface{}{"title": fmt.Sprintf("%s v%d", title, u)}, "rename "+title)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(started)
+	close(done)
+	readers.Wait()
+
+	for i, err := range errs {
+		if i == 5 {
+			break
+		}
+		t.Error(err)
+	}
+	if len(errs) > 0 {
+		t.Fatalf("%d requests failed", len(errs))
+	}
+	t.Logf("%d requests in %v (%.0f req/s)", requests, elapsed, float64(requests)/elapsed.Seconds())
+
+	todos, err := repository.NewTodoRepository(server.DB).GetAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != writers*perWriter {
+		t.Fatalf("Expected %d todos, got %d", writers*perWriter, len(todos))
+	}
+	for _, todo := range todos {
+		if todo.Version != updates+1 {
+			t.Errorf("Expected todo %d to have version %d, got %d", todo.ID, updates+1, todo.Version)
+			break
+		}
+		if !todo.Completed || !strings.HasSuffix(todo.Title, fmt.Sprintf(" v%d", updates)) {
+			t.Errorf("Expected todo %d to be completed and renamed, got %+v", todo.ID, todo)
+			break
+		}
+	}
+
+	var mode string
+	if err := server.DB.Raw("PRAGMA journal_mode").Scan(&mode).Error; err != nil || mode != "wal" {
+		t.Errorf("Expected journal_mode wal, got %q (%v)", mode, err)
+	}
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"todo-backend/internal/openapi"
+	"todo-backend/internal/server"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+
+	doc := openapi.Build(server.ValidationRules(srv.Deps.Config))
+
+	registered := make(map[string]bool)
+	for _, route := range srv.Router.Routes() {
+		path := openapi.PathFromGin(route.Path)
+		registered[route.Method+" "+path] = true
+
+		item, ok := doc.Paths[path]
+		if !ok || !item.HasMethod(route.Method) {
+			t.Errorf("Route %s %s is missing from the OpenAPI document", route.Method, route.Path)
+		}
+	}
+
+	// 反过来, 文档中的每个操作都必须对应一个已注册的路由
+	for path, item := range doc.Paths {
+		for _, method := range []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH"} {
+			if item.Operation(method) != nil && !registered[method+" "+path] {
+				t.Errorf("Documented operation %s %s is not registered", method, path)
+			}
+		}
+		for _, method := range item.WebDAVMethods {
+			if !registered[method+" "+path] {
+				t.Errorf("Documented WebDAV method %s %s is not registered", method, path)
+			}
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/api/openapi.json")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		t.Fatalf("Unexpected response %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string `json:"required"`
+				Properties map[string]struct {
+					MaxLength int `json:"maxLength"`
+				} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("Expected openapi 3.1.0, got %q", doc.OpenAPI)
+	}
+
+	// 所有 $ref 都指向存在的 schema
+	for _, match := range regexp.MustCompile(`"#/components/schemas/([A-Za-z]+)"`).FindAllStringSubmatch(string(body), -1) {
+		if _, ok := doc.Components.Schemas[match[1]]; !ok {
+			t.Errorf("Unresolved reference %s", match[0])
+		}
+	}
+
+	// 字段与 model 的 JSON 标签一致, 内部字段不出现在文档中
+	todo := doc.Components.Schemas["Todo"]
+	for _, field := range []string{"id", "title", "content", "completed", "version", "completed_at", "due_at", "created_at", "updated_at"} {
+		if _, ok := todo.Properties[field]; !ok {
+			t.Errorf("Expected Todo.%s in schema", field)
+		}
+	}
+	for _, field := range []string{"DeletedAt", "change_seq", "UID"} {
+		if _, ok := todo.Properties[field]; ok {
+			t.Errorf("Did not expect Todo.%s in schema", field)
+		}
+	}
+
+	create := doc.Components.Schemas["CreateTodoRequest"]
+	if len(create.Required) != 1 || create.Required[0] != "title" || create.Properties["title"].MaxLength == 0 {
+		t.Errorf("Expected title to be required with a max length, got %+v", create)
+	}
+}
+
+func TestDocsPage(t *testing.T) {
+	t.Parallel()
+	srv := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/api/docs")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("Unexpected response %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), "openapi.json") {
+		t.Errorf("Expected the docs page to load openapi.json")
+	}
+}
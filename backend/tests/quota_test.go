@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"todo-backend/internal/config"
+	"todo-backend/internal/testutil"
+)
+
+// withQuota 启动使用指定配额的独立服务, 0 表示不限制
+func withQuota(t *testing.T, maxTodos, maxBytes int64) *testutil.Server {
+	t.Helper()
+
+	return testutil.NewServer(t, func(cfg *config.Config) {
+		cfg.QuotaMaxTodos = maxTodos
+		cfg.QuotaMaxStorageBytes = maxBytes
+	})
+}
+
+func TestTodoCountQuota(t *testing.T) {
+	t.Parallel()
+	server := withQuota(t, 1, 0)
+
+	testutil.AssertSuccess(t, server.Request(t, "POST", "/api/todos", map[string]string{"title": "Within quota"}), http.StatusCreated, nil)
+
+	message := testutil.AssertError(t, server.Request(t, "POST", "/api/todos", map[string]string{"title": "Over quota"}), http.StatusForbidden)
+	if !strings.Contains(message, "quota exceeded") {
+		t.Errorf("Expected quota exceeded message, got %q", message)
+	}
+
+	// 批量创建整体回滚
+	resp := server.Request(t, "POST", "/api/todos/batch", map[string]interface{}{
+		"operations": []map[string]interface{}{{"op": "create", "data": map[string]string{"title": "Batch over quota"}}},
+	})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected batch create to fail with 403, got %d", resp.StatusCode)
+	}
+
+	resp = server.Request(t, "POST", "/graphql", map[string]string{"query": `mutation { createTodo(input: {title: "GraphQL over quota"}) { id } }`})
+	var gql graphqlResult
+	if err := json.Unmarshal(resp.Body, &gql); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(gql.Errors) != 1 || gql.Errors[0].Extensions["code"] != "QUOTA_EXCEEDED" {
+		t.Errorf("Expected a QUOTA_EXCEEDED error, got %+v", gql.Errors)
+	}
+}
+
+func TestStorageQuota(t *testing.T) {
+	t.Parallel()
+	// 标题 "Storage quota" 占 13 字节, 内容最多 10 字节
+	server := withQuota(t, 0, 23)
+	todo := server.CreateTodo(t, "Storage quota")
+	path := "/api/todos/" + strconv.Itoa(int(todo.ID))
+
+	// 内容变短不增加用量, 总是允许
+	update := func(content string) int {
+		return server.Request(t, "PUT", path, map[string]interface{}{"title": "Storage quota", "content": content}).StatusCode
+	}
+	if status := update("0123456789"); status != http.StatusOK {
+		t.Errorf("Expected an update within quota to succeed, got %d", status)
+	}
+	if status := update("0123456789x"); status != http.StatusForbidden {
+		t.Errorf("Expected an update over quota to fail, got %d", status)
+	}
+	if status := update("short"); status != http.StatusOK {
+		t.Errorf("Expected a shrinking update to succeed, got %d", status)
+	}
+}
+
+func TestQuotaIsPerServer(t *testing.T) {
+	t.Parallel()
+	limited := withQuota(t, 1, 0)
+	unlimited := withQuota(t, 0, 0)
+
+	testutil.AssertSuccess(t, limited.Request(t, "POST", "/api/todos", map[string]string{"title": "Limited"}), http.StatusCreated, nil)
+	for i := 0; i < 3; i++ {
+		testutil.AssertSuccess(t, unlimited.Request(t, "POST", "/api/todos", map[string]string{"title": "Unlimited"}), http.StatusCreated, nil)
+	}
+	testutil.AssertError(t, limited.Request(t, "POST", "/api/todos", map[string]string{"title": "Over quota"}), http.StatusForbidden)
+}
+
+func TestQuotaIsPerClient(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t, func(cfg *config.Config) {
+		cfg.QuotaMaxTodos = 1
+		cfg.TrustedProxies = []string{"127.0.0.1"}
+	})
+	create := func(ip, title string) int {
+		req, _ := http.NewRequest("POST", server.URL+"/api/todos", strings.NewReader(`{"title":"`+title+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", ip)
+		return server.Do(t, req).StatusCode
+	}
+
+	if status := create("192.0.2.1", "First client"); status != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", status)
+	}
+	if status := create("192.0.2.1", "First client again"); status != http.StatusForbidden {
+		t.Errorf("Expected the first client to be over quota, got %d", status)
+	}
+	if status := create("192.0.2.2", "Second client"); status != http.StatusCreated {
+		t.Errorf("Expected another client to have its own quota, got %d", status)
+	}
+
+	// 不经过 HTTP 的写入 (命令行工具、测试夹具) 不占用客户端的配额
+	server.CreateTodo(t, "Without owner")
+	if status := create("192.0.2.3", "Third client"); status != http.StatusCreated {
+		t.Errorf("Expected todos without owner not to count, got %d", status)
+	}
+}
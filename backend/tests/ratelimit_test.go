@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"todo-backend/internal/config"
+	"todo-backend/internal/middleware"
+	"todo-backend/internal/ratelimit"
+	"todo-backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRateLimitedRouter(t *testing.T, opts middleware.RateLimitOptions) *gin.Engine {
+	t.Helper()
+
+	r := gin.New()
+	r.Use(middleware.RateLimit(opts))
+	r.GET("/api/todos", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/api/todos", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	return r
+}
+
+func limitedRequest(r *gin.Engine, method, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/todos", nil)
+	req.RemoteAddr = remoteAddr
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestParseRateLimit(t *testing.T) {
+	t.Parallel()
+	valid := map[string]ratelimit.Limit{
+		"20/s":   {Requests: 20, Per: time.Second},
+		"600/m":  {Requests: 600, Per: time.Minute},
+		"1000/h": {Requests: 1000, Per: time.Hour},
+		"5/10s":  {Requests: 5, Per: 10 * time.Second},
+	}
+	for input, expected := range valid {
+		limit, err := ratelimit.ParseLimit(input)
+		if err != nil || limit != expected {
+			t.Errorf("ParseLimit(%q) = %v, %v", input, limit, err)
+		}
+		if limit.String() != input {
+			t.Errorf("Expected %q to round-trip, got %q", input, limit.String())
+		}
+	}
+	for _, input := range []string{"", "20", "0/s", "-1/s", "x/s", "5/week", "5/-1s"} {
+		if _, err := ratelimit.ParseLimit(input); err == nil {
+			t.Errorf("Expected ParseLimit(%q) to fail", input)
+		}
+	}
+
+	routes, err := middleware.ParseRouteLimits([]string{"post /api/todos=30/m"})
+	if err != nil || routes["POST /api/todos"] != (ratelimit.Limit{Requests: 30, Per: time.Minute}) {
+		t.Errorf("Unexpected route limits %v, %v", routes, err)
+	}
+	if _, err := middleware.ParseRouteLimits([]string{"/api/todos=30/m"}); err == nil {
+		t.Error("Expected a route limit without a method to fail")
+	}
+}
+
+func TestRateLimitHeadersAndRetryAfter(t *testing.T) {
+	t.Parallel()
+	r := newRateLimitedRouter(t, middleware.RateLimitOptions{Default: ratelimit.Limit{Requests: 3, Per: time.Minute}})
+
+	for i := 0; i < 3; i++ {
+		w := limitedRequest(r, "GET", "192.0.2.1:1234", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i, w.Code)
+		}
+		if w.Header().Get("RateLimit-Limit") != "3" || w.Header().Get("RateLimit-Remaining") != strconv.Itoa(2-i) {
+			t.Errorf("Request %d: unexpected headers %v", i, w.Header())
+		}
+		if w.Header().Get("RateLimit-Policy") != "3;w=60" {
+			t.Errorf("Unexpected policy %q", w.Header().Get("RateLimit-Policy"))
+		}
+	}
+
+	w := limitedRequest(r, "GET", "192.0.2.1:1234", nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", w.Code)
+	}
+	// 每 20 秒补充一个令牌
+	if w.Header().Get("Retry-After") != "20" || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Reset") != "60" {
+		t.Errorf("Unexpected headers on 429: %v", w.Header())
+	}
+
+	// 其他 IP 使用各自的令牌桶; 未经校验的 Bearer token 不能换一个新的令牌桶
+	if w := limitedRequest(r, "GET", "192.0.2.2:1234", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected another IP to be allowed, got %d", w.Code)
+	}
+	auth := map[string]string{"Authorization": "Bearer random-token"}
+	if w := limitedRequest(r, "GET", "192.0.2.1:1234", auth); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected an unverified token to share the IP bucket, got %d", w.Code)
+	}
+}
+
+func TestRateLimitPerRoute(t *testing.T) {
+	t.Parallel()
+	r := newRateLimitedRouter(t, middleware.RateLimitOptions{
+		Routes: map[string]ratelimit.Limit{"POST /api/todos": {Requests: 2, Per: 100 * time.Millisecond}},
+	})
+
+	for i := 0; i < 2; i++ {
+		if w := limitedRequest(r, "POST", "192.0.2.1:1234", nil); w.Code != http.StatusCreated {
+			t.Fatalf("Request %d: expected 201, got %d", i, w.Code)
+		}
+	}
+	if w := limitedRequest(r, "POST", "192.0.2.1:1234", nil); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected 429 with Retry-After rounded up to 1, got %d %v", w.Code, w.Header())
+	}
+
+	// 没有默认限制时其他路由不受影响
+	for i := 0; i < 5; i++ {
+		if w := limitedRequest(r, "GET", "192.0.2.1:1234", nil); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("Expected GET to be unlimited, got %d %v", w.Code, w.Header())
+		}
+	}
+
+	// 令牌按速率补充
+	time.Sleep(60 * time.Millisecond)
+	if w := limitedRequest(r, "POST", "192.0.2.1:1234", nil); w.Code != http.StatusCreated {
+		t.Errorf("Expected a refilled token, got %d", w.Code)
+	}
+}
+
+func TestRateLimitKeysAuthenticatedCallers(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t, func(cfg *config.Config) {
+		cfg.RateLimit = "2/m"
+		cfg.CalDAVUsername = testCalDAVUser
+		cfg.CalDAVPassword = testCalDAVPassword
+		cfg.AdminToken = "admin-secret"
+	})
+
+	// 用完客户端 IP 的令牌桶
+	for i := 0; i < 2; i++ {
+		testutil.AssertSuccess(t, server.Request(t, "GET", "/api/todos", nil), http.StatusOK, nil)
+	}
+	testutil.AssertError(t, server.Request(t, "GET", "/api/todos", nil), http.StatusTooManyRequests)
+
+	// 认证之后按用户和 token 计数, 不受同一 IP 上匿名请求的影响
+	req, _ := http.NewRequest("PROPFIND", server.URL+"/caldav/", nil)
+	req.SetBasicAuth(testCalDAVUser, testCalDAVPassword)
+	req.Header.Set("Depth", "0")
+	if resp := server.Do(t, req); resp.StatusCode == http.StatusTooManyRequests || resp.Header.Get("RateLimit-Remaining") != "1" {
+		t.Errorf("Expected the CalDAV user to have its own bucket, got %d %v", resp.StatusCode, resp.Header)
+	}
+
+	req, _ = http.NewRequest("GET", server.URL+"/api/admin/backups", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	if resp := server.Do(t, req); resp.StatusCode != http.StatusOK || resp.Header.Get("RateLimit-Remaining") != "1" {
+		t.Errorf("Expected the admin token to have its own bucket, got %d %v", resp.StatusCode, resp.Header)
+	}
+}
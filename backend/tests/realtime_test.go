@@ -0,0 +1,281 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-backend/internal/config"
+	"todo-backend/internal/events"
+	"todo-backend/internal/model"
+	"todo-backend/internal/realtime"
+	"todo-backend/internal/testutil"
+
+	"github.com/gorilla/websocket"
+)
+
+type wsMessage struct {
+	Type        string                 `json:"type"`
+	RequestID   string                 `json:"request_id"`
+	ClientID    string                 `json:"client_id"`
+	Status      int                    `json:"status"`
+	Message     string                 `json:"message"`
+	Data        interface{}            `json:"data"`
+	Event       map[string]interface{} `json:"event"`
+	Topics      []string               `json:"topics"`
+	TodoID      uint                   `json:"todo_id"`
+	LastEventID uint64                 `json:"last_event_id"`
+}
+
+func dialRealtime(t *testing.T, server *testutil.Server, user string) *websocket.Conn {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws?user=" + user
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to dial websocket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if msg := readMessage(t, conn); msg.Type != "welcome" {
+		t.Fatalf("Expected welcome message, got %s", msg.Type)
+	}
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	return msg
+}
+
+// readUntil 跳过不关心的消息, 直到读到指定类型
+func readUntil(t *testing.T, conn *websocket.Conn, msgType string) wsMessage {
+	t.Helper()
+
+	for i := 0; i < 20; i++ {
+		if msg := readMessage(t, conn); msg.Type == msgType {
+			return msg
+		}
+	}
+	t.Fatalf("Did not receive %s message", msgType)
+	return wsMessage{}
+}
+
+func sendMessage(t *testing.T, conn *websocket.Conn, msg map[string]interface{}) {
+	t.Helper()
+
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+}
+
+func subscribe(t *testing.T, conn *websocket.Conn, topics ...string) {
+	t.Helper()
+
+	sendMessage(t, conn, map[string]interface{}{"type": "subscribe", "topics": topics})
+	if msg := readUntil(t, conn, "subscribed"); len(msg.Topics) == 0 {
+		t.Fatal("Expected subscribed topics")
+	}
+}
+
+func TestRealtimeReceivesEvents(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	all := dialRealtime(t, server, "alice")
+	subscribe(t, all, realtime.TopicAllTodos)
+
+	target := createTestTodo(t, server, "Realtime Target")
+	if msg := readUntil(t, all, "event"); msg.Event["type"] != events.TodoCreated {
+		t.Errorf("Expected %s, got %v", events.TodoCreated, msg.Event["type"])
+	}
+
+	single := dialRealtime(t, server, "bob")
+	subscribe(t, single, realtime.TodoTopic(target))
+
+	createTestTodo(t, server, "Realtime Other")
+	resp, _ := makeRequest("PUT", fmt.Sprintf("%s/api/todos/%d", server.URL, target), model.UpdateTodoRequest{Completed: boolPtr(true)})
+	resp.Body.Close()
+
+	msg := readUntil(t, single, "event")
+	if msg.Event["type"] != events.TodoUpdated {
+		t.Errorf("Expected %s, got %v", events.TodoUpdated, msg.Event["type"])
+	}
+	if data := msg.Event["data"].(map[string]interface{}); data["id"] != float64(target) {
+		t.Errorf("Expected event for todo %d, got %v", target, data["id"])
+	}
+}
+
+func TestRealtimeMutations(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	conn := dialRealtime(t, server, "carol")
+
+	sendMessage(t, conn, map[string]interface{}{
+		"type": "mutate", "request_id": "bad", "op": "create",
+		"data": map[string]interface{}{"title": "   "},
+	})
+	if msg := readUntil(t, conn, "error"); msg.RequestID != "bad" || msg.Status != http.StatusBadRequest {
+		t.Errorf("Expected validation error for request bad, got %+v", msg)
+	}
+
+	sendMessage(t, conn, map[string]interface{}{
+		"type": "mutate", "request_id": "good", "op": "create",
+		"data": map[string]interface{}{"title": "Created Over WS"},
+	})
+	msg := readUntil(t, conn, "result")
+	if msg.RequestID != "good" || msg.Status != http.StatusCreated {
+		t.Fatalf("Expected created result, got %+v", msg)
+	}
+
+	id := uint(msg.Data.(map[string]interface{})["id"].(float64))
+	if todo, status := getTestTodo(t, server, id); status != http.StatusOK || todo["title"] != "Created Over WS" {
+		t.Errorf("Expected todo to be persisted, got %d %v", status, todo)
+	}
+
+	sendMessage(t, conn, map[string]interface{}{
+		"type": "mutate", "request_id": "stale", "op": "update", "id": id,
+		"if_match": `"0-0"`, "data": map[string]interface{}{"title": "Stale"},
+	})
+	if msg := readUntil(t, conn, "error"); msg.Status != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412, got %d", msg.Status)
+	}
+
+	sendMessage(t, conn, map[string]interface{}{"type": "mutate", "request_id": "rm", "op": "delete", "id": id})
+	if msg := readUntil(t, conn, "result"); msg.RequestID != "rm" {
+		t.Errorf("Expected delete result, got %+v", msg)
+	}
+}
+
+func TestRealtimePresence(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	id := createTestTodo(t, server, "Presence Todo")
+	topic := realtime.TodoTopic(id)
+
+	editor := dialRealtime(t, server, "dave")
+	watcher := dialRealtime(t, server, "erin")
+	subscribe(t, editor, topic)
+	subscribe(t, watcher, topic)
+	readUntil(t, watcher, "presence")
+
+	sendMessage(t, editor, map[string]interface{}{"type": "presence", "todo_id": id, "state": realtime.StateEditing})
+
+	msg := readUntil(t, watcher, "presence")
+	members, _ := msg.Data.([]interface{})
+	if len(members) != 1 {
+		t.Fatalf("Expected one member, got %v", msg.Data)
+	}
+	if member := members[0].(map[string]interface{}); member["user"] != "dave" || member["state"] != realtime.StateEditing {
+		t.Errorf("Unexpected presence member: %v", member)
+	}
+
+	editor.Close()
+
+	msg = readUntil(t, watcher, "presence")
+	if members, _ := msg.Data.([]interface{}); len(members) != 0 {
+		t.Errorf("Expected presence to be cleared after disconnect, got %v", msg.Data)
+	}
+}
+
+func TestRealtimeResumeAndPing(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	lastID := server.Hub.LastID()
+	createTestTodo(t, server, "Missed While Offline")
+
+	conn := dialRealtime(t, server, "frank")
+	sendMessage(t, conn, map[string]interface{}{"type": "subscribe", "topics": []string{realtime.TopicAllTodos}, "last_event_id": lastID})
+
+	msg := readUntil(t, conn, "event")
+	if msg.Event["id"] != float64(lastID+1) {
+		t.Errorf("Expected replayed event %d, got %v", lastID+1, msg.Event["id"])
+	}
+
+	sendMessage(t, conn, map[string]interface{}{"type": "ping", "request_id": "hb"})
+	if msg := readUntil(t, conn, "pong"); msg.RequestID != "hb" {
+		t.Errorf("Expected pong for hb, got %+v", msg)
+	}
+}
+
+func TestRealtimeRejectsUnknownTopic(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	conn := dialRealtime(t, server, "grace")
+
+	sendMessage(t, conn, map[string]interface{}{"type": "subscribe", "topics": []string{"project:1"}})
+	if msg := readUntil(t, conn, "error"); msg.Status != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", msg.Status)
+	}
+}
+
+func TestRealtimeRejectsCrossSiteOrigin(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws"
+	_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example.com"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected cross-site handshake to be rejected with 403, got %v", err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {server.URL}})
+	if err != nil {
+		t.Fatalf("Expected same-origin handshake to succeed, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestWebSocketOriginCheck(t *testing.T) {
+	t.Parallel()
+	wildcard := testutil.NewServer(t)
+	listed := testutil.NewServer(t, func(cfg *config.Config) {
+		cfg.CORSOrigins = []string{"https://app.example.com"}
+		cfg.CORSAllowCredentials = true
+	})
+
+	tests := []struct {
+		name   string
+		server *testutil.Server
+		origin string
+		want   int
+	}{
+		{"non-browser client", wildcard, "", http.StatusSwitchingProtocols},
+		{"same origin", wildcard, wildcard.URL, http.StatusSwitchingProtocols},
+		{"wildcard does not apply", wildcard, "https://evil.example.com", http.StatusForbidden},
+		{"listed origin", listed, "https://app.example.com", http.StatusSwitchingProtocols},
+		{"unlisted origin", listed, "https://evil.example.com", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		for _, path := range []string{"/api/ws", "/graphql"} {
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}, HandshakeTimeout: 2 * time.Second}
+			conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(tt.server.URL, "http")+path, header)
+			if conn != nil {
+				conn.Close()
+			}
+			if resp == nil {
+				t.Errorf("%s %s: handshake failed: %v", tt.name, path, err)
+				continue
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("%s %s: expected status %d, got %d", tt.name, path, tt.want, resp.StatusCode)
+			}
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"todo-backend/internal/model"
+	"todo-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+type syncPayload struct {
+	Token   string             `json:"token"`
+	Reset   bool               `json:"reset"`
+	Changes []model.SyncChange `json:"changes"`
+	Results []model.SyncResult `json:"results"`
+}
+
+func decodeSync(t *testing.T, resp *http.Response) syncPayload {
+	t.Helper()
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var envelope struct {
+		Data syncPayload `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		t.Fatalf("Failed to decode sync response: %v", err)
+	}
+	return envelope.Data
+}
+
+func pullChanges(t *testing.T, server *testutil.Server, token string) syncPayload {
+	t.Helper()
+
+	resp, err := makeRequest("GET", server.URL+"/api/sync?since="+token, nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	return decodeSync(t, resp)
+}
+
+func pushChanges(t *testing.T, server *testutil.Server, since string, changes ...map[string]interface{}) syncPayload {
+	t.Helper()
+
+	resp, err := makeRequest("POST", server.URL+"/api/sync", map[string]interface{}{"since": since, "changes": changes})
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	return decodeSync(t, resp)
+}
+
+func findChange(changes []model.SyncChange, id uint) *model.SyncChange {
+	for i := range changes {
+		if changes[i].ID == id {
+			return &changes[i]
+		}
+	}
+	return nil
+}
+
+func TestSyncPullDelta(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	initial := pullChanges(t, server, "")
+	if !initial.Reset {
+		t.Error("Expected full sync without token to set reset")
+	}
+
+	updated := createTestTodo(t, server, "Sync Updated")
+	deleted := createTestTodo(t, server, "Sync Deleted")
+	pulled := pullChanges(t, server, initial.Token)
+
+	resp, _ := makeRequest("PUT", fmt.Sprintf("%s/api/todos/%d", server.URL, updated), model.UpdateTodoRequest{Completed: boolPtr(true)})
+	resp.Body.Close()
+	resp, _ = makeRequest("DELETE", fmt.Sprintf("%s/api/todos/%d", server.URL, deleted), nil)
+	resp.Body.Close()
+	created := createTestTodo(t, server, "Sync Created")
+	shortLived := createTestTodo(t, server, "Sync Short Lived")
+	resp, _ = makeRequest("DELETE", fmt.Sprintf("%s/api/todos/%d", server.URL, shortLived), nil)
+	resp.Body.Close()
+
+	delta := pullChanges(t, server, pulled.Token)
+	if delta.Reset {
+		t.Error("Expected delta sync not to reset")
+	}
+
+	if change := findChange(delta.Changes, updated); change == nil || change.Type != model.SyncUpdated || !change.Todo.Completed {
+		t.Errorf("Expected update for todo %d, got %+v", updated, change)
+	}
+	if change := findChange(delta.Changes, deleted); change == nil || change.Type != model.SyncDeleted || change.DeletedAt == nil {
+		t.Errorf("Expected tombstone for todo %d, got %+v", deleted, change)
+	}
+	if change := findChange(delta.Changes, created); change == nil || change.Type != model.SyncCreated {
+		t.Errorf("Expected creation of todo %d, got %+v", created, change)
+	}
+	if change := findChange(delta.Changes, shortLived); change != nil {
+		t.Errorf("Expected no change for todo created and deleted after the token, got %+v", change)
+	}
+
+	if again := pullChanges(t, server, delta.Token); len(again.Changes) != 0 {
+		t.Errorf("Expected no changes after latest token, got %d", len(again.Changes))
+	}
+}
+
+func TestSyncInvalidToken(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	resp, _ := makeRequest("GET", server.URL+"/api/sync?since=abc", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+
+	if future := pullChanges(t, server, "999999999"); !future.Reset {
+		t.Error("Expected reset for token newer than the server")
+	}
+}
+
+func TestSyncPushCreateAndDelete(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	since := pullChanges(t, server, "").Token
+	existing := createTestTodo(t, server, "Sync Push Delete")
+
+	result := pushChanges(t, server, since,
+		map[string]interface{}{"client_ref": "local-1", "title": "  Offline Todo ", "completed": true},
+		map[string]interface{}{"id": existing, "deleted": true, "modified_at": time.Now()},
+		map[string]interface{}{"client_ref": "local-2", "content": "no title"},
+	)
+
+	if len(result.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(result.Results))
+	}
+
+	created := result.Results[0]
+	if created.Status != model.SyncCreated || created.ClientRef != "local-1" || created.Todo.Title != "Offline Todo" || !created.Todo.Completed {
+		t.Errorf("Unexpected create result: %+v", created)
+	}
+	if result.Results[1].Status != model.SyncDeleted {
+		t.Errorf("Expected delete to be applied, got %+v", result.Results[1])
+	}
+	if result.Results[2].Status != model.SyncError {
+		t.Errorf("Expected error for create without title, got %+v", result.Results[2])
+	}
+
+	if change := findChange(result.Changes, created.ID); change == nil || change.Type != model.SyncCreated {
+		t.Errorf("Expected pushed todo in returned changes, got %+v", change)
+	}
+	if _, status := getTestTodo(t, server, existing); status != http.StatusNotFound {
+		t.Errorf("Expected deleted todo to return 404, got %d", status)
+	}
+}
+
+func TestSyncPushFieldMerge(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	id := createTestTodo(t, server, "Merge Base")
+	todo, _ := getTestTodo(t, server, id)
+	baseVersion := todo["version"]
+	before := time.Now().Add(-time.Hour)
+
+	// 服务端修改了 completed, 客户端在更早的时间修改了 title 和 completed
+	resp, _ := makeRequest("PUT", fmt.Sprintf("%s/api/todos/%d", server.URL, id), model.UpdateTodoRequest{Content: "server content", Completed: boolPtr(true)})
+	resp.Body.Close()
+
+	result := pushChanges(t, server, "", map[string]interface{}{
+		"id": id, "base_version": baseVersion, "modified_at": before,
+		"title": "Merge Base", "completed": false, "content": "client content",
+	})
+
+	merged := result.Results[0]
+	if merged.Status != model.SyncConflict {
+		t.Fatalf("Expected conflict for stale client change, got %+v", merged)
+	}
+	if len(merged.Conflicts) != 2 {
+		t.Errorf("Expected conflicts on content and completed, got %+v", merged.Conflicts)
+	}
+	if merged.Todo == nil || merged.Todo.Content != "server content" {
+		t.Errorf("Expected server value to be kept, got %+v", merged.Todo)
+	}
+
+	// 客户端修改时间更晚时以客户端为准
+	result = pushChanges(t, server, "", map[string]interface{}{
+		"id": id, "base_version": baseVersion, "modified_at": time.Now().Add(time.Minute),
+		"completed": false,
+	})
+	if applied := result.Results[0]; applied.Status != model.SyncApplied || applied.Todo.Completed || applied.Todo.Content != "server content" {
+		t.Errorf("Expected newer client change to win, got %+v", applied)
+	}
+
+	// 在已删除的记录上修改会报告冲突
+	resp, _ = makeRequest("DELETE", fmt.Sprintf("%s/api/todos/%d", server.URL, id), nil)
+	resp.Body.Close()
+	result = pushChanges(t, server, "", map[string]interface{}{"id": id, "modified_at": time.Now().Add(time.Hour), "title": "Revived"})
+	if conflict := result.Results[0]; conflict.Status != model.SyncConflict || conflict.Conflicts[0].Field != "deleted" {
+		t.Errorf("Expected conflict on deleted todo, got %+v", conflict)
+	}
+}
+
+func TestSyncPushMergesEachFieldAgainstBase(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+	todo := server.CreateTodo(t, "Field Base", testutil.WithContent("base content"))
+	stale := time.Now().Add(-time.Hour)
+
+	// 服务端只修改了 content
+	testutil.AssertSuccess(t, server.Request(t, "PUT", fmt.Sprintf("/api/todos/%d", todo.ID), map[string]string{"content": "server content"}), http.StatusOK, nil)
+
+	// 客户端的修改时间更早, 但它修改的 title 和 completed 服务端没有改过, 原样带回的 content 也不算修改
+	var result syncPayload
+	testutil.AssertSuccess(t, server.Request(t, "POST", "/api/sync", map[string]interface{}{"changes": []map[string]interface{}{{
+		"id": todo.ID, "base_version": todo.Version, "modified_at": stale,
+		"base":  map[string]interface{}{"title": "Field Base", "content": "base content", "completed": false},
+		"title": "Client Title", "content": "base content", "completed": true,
+	}}}), http.StatusOK, &result)
+
+	applied := result.Results[0]
+	if applied.Status != model.SyncApplied || len(applied.Conflicts) != 0 {
+		t.Fatalf("Expected the client-only fields to be applied, got %+v", applied)
+	}
+	if applied.Todo.Title != "Client Title" || !applied.Todo.Completed || applied.Todo.Content != "server content" {
+		t.Errorf("Expected each side's changes to be kept, got %+v", applied.Todo)
+	}
+
+	// 双方都修改了 content 时, 较早的客户端修改报告冲突, 其他字段照常合并
+	testutil.AssertSuccess(t, server.Request(t, "POST", "/api/sync", map[string]interface{}{"changes": []map[string]interface{}{{
+		"id": todo.ID, "base_version": todo.Version, "modified_at": stale,
+		"base":    map[string]interface{}{"content": "base content", "completed": true},
+		"content": "client content", "completed": false,
+	}}}), http.StatusOK, &result)
+
+	merged := result.Results[0]
+	if merged.Status != model.SyncMerged || len(merged.Conflicts) != 1 || merged.Conflicts[0].Field != "content" {
+		t.Fatalf("Expected a conflict on content only, got %+v", merged)
+	}
+	if merged.Todo.Content != "server content" || merged.Todo.Completed {
+		t.Errorf("Expected server content and client completed, got %+v", merged.Todo)
+	}
+}
+
+func TestSyncPushSavepointFailure(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	// 每个新建有两层 SAVEPOINT (同步项和 Create), 第三个是第二条修改的同步项
+	var savepoints int32
+	err := server.DB.Callback().Raw().Before("gorm:raw").Register("test:fail_savepoint", func(tx *gorm.DB) {
+		if strings.HasPrefix(tx.Statement.SQL.String(), "SAVEPOINT ") && atomic.AddInt32(&savepoints, 1) == 3 {
+			tx.AddError(errors.New("savepoint failed"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result syncPayload
+	testutil.AssertSuccess(t, server.Request(t, "POST", "/api/sync", map[string]interface{}{"changes": []map[string]interface{}{
+		{"client_ref": "first", "title": "First"},
+		{"client_ref": "second", "title": "Second"},
+		{"client_ref": "third", "title": "Third"},
+	}}), http.StatusOK, &result)
+
+	failed := result.Results[1]
+	if failed.Status != model.SyncError || failed.ClientRef != "second" || failed.Todo != nil {
+		t.Errorf("Expected the second change to fail, got %+v", failed)
+	}
+	if result.Results[0].Status != model.SyncCreated || result.Results[2].Status != model.SyncCreated {
+		t.Errorf("Expected the other changes to be created, got %+v", result.Results)
+	}
+
+	var todos []model.Todo
+	testutil.AssertSuccess(t, server.Request(t, "GET", "/api/todos", nil), http.StatusOK, &todos)
+	if len(todos) != 2 {
+		t.Errorf("Expected 2 todos to be created, got %d", len(todos))
+	}
+}
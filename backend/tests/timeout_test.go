@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-backend/internal/config"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// expiredQueryTimeout 让每个请求的查询截止时间在第一条查询之前就已经过去
+func expiredQueryTimeout(cfg *config.Config) {
+	cfg.QueryTimeout = time.Nanosecond
+}
+
+func TestQueryTimeoutReturns504(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t, expiredQueryTimeout)
+
+	for _, path := range []string{"/api/todos", "/api/todos/1", "/api/sync", "/api/webhooks"} {
+		resp := server.Request(t, "GET", path, nil)
+		if resp.StatusCode != http.StatusGatewayTimeout {
+			t.Errorf("GET %s: expected status 504, got %d: %s", path, resp.StatusCode, resp.Body)
+		}
+	}
+
+	resp := server.Request(t, "POST", "/api/todos", map[string]string{"title": "Too slow"})
+	testutil.AssertError(t, resp, http.StatusGatewayTimeout)
+}
+
+func TestQueryTimeoutGraphQLCode(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t, expiredQueryTimeout)
+
+	resp := server.Request(t, "POST", "/graphql", map[string]string{"query": `{ todos { totalCount } }`})
+	var result graphqlResult
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != "TIMEOUT" {
+		t.Errorf("Expected TIMEOUT error, got %+v", result.Errors)
+	}
+}
+
+func TestQueryTimeoutDisabled(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t, func(cfg *config.Config) { cfg.QueryTimeout = 0 })
+
+	testutil.AssertSuccess(t, server.Request(t, "GET", "/api/todos", nil), http.StatusOK, nil)
+}
+
+func TestCanceledRequestReturns503(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest("GET", "/api/todos", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	server.Router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRepositoryContextErrors(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	repo := repository.NewTodoRepository(server.DB)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.GetAll(ctx); !errors.Is(err, repository.ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got %v", err)
+	}
+
+	ctx = repository.WithQueryTimeout(context.Background(), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, err := repo.Count(ctx, repository.TodoFilter{}); !errors.Is(err, repository.ErrQueryTimeout) {
+		t.Errorf("Expected ErrQueryTimeout, got %v", err)
+	}
+
+	// 长连接中的每条消息重新计算截止时间
+	ctx = repository.WithQueryTimeout(context.Background(), time.Minute)
+	if _, err := repo.Count(repository.RenewQueryTimeout(ctx), repository.TodoFilter{}); err != nil {
+		t.Errorf("Expected renewed query to succeed, got %v", err)
+	}
+	if _, err := repo.GetAll(context.Background()); err != nil {
+		t.Errorf("Expected query without timeout to succeed, got %v", err)
+	}
+}
+
+func TestEachRenewsQueryTimeoutPerBatch(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	todos := make([]model.Todo, 450)
+	for i := range todos {
+		todos[i].Title = fmt.Sprintf("Each %d", i)
+	}
+	if err := server.DB.Create(&todos).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 三批查询各自都很快, 但加上处理每批的时间后总时间超过查询超时
+	ctx := repository.WithQueryTimeout(context.Background(), 50*time.Millisecond)
+	count := 0
+	err := repository.NewTodoRepository(server.DB).Each(ctx, repository.TodoFilter{}, func(todo *model.Todo) error {
+		if count%200 == 0 {
+			time.Sleep(30 * time.Millisecond)
+		}
+		count++
+		return nil
+	})
+	if err != nil || count != len(todos) {
+		t.Errorf("Expected all %d todos without a timeout, got %d: %v", len(todos), count, err)
+	}
+}
+
+func TestImportRenewsQueryTimeoutPerRow(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t, func(cfg *config.Config) { cfg.QueryTimeout = 50 * time.Millisecond })
+
+	// 每行都在超时之内, 但整个导入的时间超过超时
+	err := server.DB.Callback().Create().After("gorm:create").Register("test:slow_create", func(tx *gorm.DB) {
+		if tx.Statement.Table == "todos" {
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for i := 0; i < 30; i++ {
+		lines = append(lines, fmt.Sprintf("Slow import %d", i))
+	}
+	status, report := uploadImport(t, server, "todo.txt", strings.Join(lines, "\n"), nil)
+	if status != http.StatusOK || report.Created != len(lines) {
+		t.Errorf("Expected %d todos to be imported, got %d %+v", len(lines), status, report)
+	}
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-backend/internal/database"
+	"todo-backend/internal/model"
+	"todo-backend/internal/repository"
+	"todo-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+var errUnitOfWorkTest = errors.New("unit of work test")
+
+func TestUnitOfWorkRollback(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+	ctx := context.Background()
+
+	attempts := 0
+	err := repository.NewUnitOfWork(server.DB).Do(ctx, func(tx *repository.UnitOfWork) error {
+		attempts++
+		if err := tx.Todos().Create(ctx, &model.Todo{Title: "Rolled back"}); err != nil {
+			return err
+		}
+		if err := tx.Webhooks().Create(ctx, &model.Webhook{URL: "http://example.com/hook", Secret: "s"}); err != nil {
+			return err
+		}
+		return errUnitOfWorkTest
+	})
+	if !errors.Is(err, errUnitOfWorkTest) {
+		t.Fatalf("Expected fn error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Expected no retry for ordinary errors, got %d attempts", attempts)
+	}
+
+	todos, _ := repository.NewTodoRepository(server.DB).GetAll(ctx)
+	webhooks, _ := repository.NewWebhookRepository(server.DB).GetAll(ctx)
+	if len(todos) != 0 || len(webhooks) != 0 {
+		t.Errorf("Expected all writes to be rolled back, got %d todos and %d webhooks", len(todos), len(webhooks))
+	}
+}
+
+func TestUnitOfWorkSavepoint(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+	ctx := context.Background()
+
+	err := repository.NewUnitOfWork(server.DB).Do(ctx, func(tx *repository.UnitOfWork) error {
+		if err := tx.Todos().Create(ctx, &model.Todo{Title: "Kept"}); err != nil {
+			return err
+		}
+		err := tx.Do(ctx, func(inner *repository.UnitOfWork) error {
+			if err := inner.Todos().Create(ctx, &model.Todo{Title: "Discarded"}); err != nil {
+				return err
+			}
+			return errUnitOfWorkTest
+		})
+		if !errors.Is(err, errUnitOfWorkTest) {
+			t.Errorf("Expected savepoint error, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	todos, err := repository.NewTodoRepository(server.DB).GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 1 || todos[0].Title != "Kept" {
+		t.Errorf("Expected only the outer write to be committed, got %+v", todos)
+	}
+}
+
+// openBusyDatabase 打开一个不等待锁 (BusyTimeout 为 0) 的文件数据库, 并用另一个连接持有写锁, 调用 release 释放
+func openBusyDatabase(t *testing.T) (db *gorm.DB, release func()) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "busy.db")
+	db, err := database.Connect(path, database.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := database.Connect(path, database.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := other.DB()
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(context.Background(), "BEGIN IMMEDIATE"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		database.CloseDB(other)
+		database.CloseDB(db)
+	})
+	return db, func() {
+		conn.ExecContext(context.Background(), "COMMIT")
+	}
+}
+
+func TestUnitOfWorkRetriesBusy(t *testing.T) {
+	t.Parallel()
+	db, release := openBusyDatabase(t)
+	ctx := context.Background()
+
+	// 不等待锁, 只有重试才能在写锁释放后成功
+	time.AfterFunc(30*time.Millisecond, release)
+
+	err := repository.NewUnitOfWork(db).Do(ctx, func(tx *repository.UnitOfWork) error {
+		return tx.Todos().Create(ctx, &model.Todo{Title: "After lock"})
+	})
+	if err != nil {
+		t.Fatalf("Expected transaction to succeed after the lock is released, got %v", err)
+	}
+}
+
+func TestUnitOfWorkBusyGivesUp(t *testing.T) {
+	t.Parallel()
+	db, _ := openBusyDatabase(t)
+	ctx := context.Background()
+
+	started := time.Now()
+	calls := 0
+	err := repository.NewUnitOfWork(db).Do(ctx, func(tx *repository.UnitOfWork) error {
+		calls++
+		return tx.Todos().Create(ctx, &model.Todo{Title: "Never written"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Fatalf("Expected database is locked error, got %v", err)
+	}
+	// 事务使用 BEGIN IMMEDIATE, 拿不到写锁时 fn 不会执行
+	if calls != 0 {
+		t.Errorf("Expected fn not to run without the write lock, got %d calls", calls)
+	}
+	if elapsed := time.Since(started); elapsed < 70*time.Millisecond {
+		t.Errorf("Expected retries with backoff, gave up after %v", elapsed)
+	}
+}
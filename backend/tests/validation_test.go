@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"todo-backend/internal/config"
+	"todo-backend/internal/model"
+	"todo-backend/internal/testutil"
+)
+
+func makeRawRequest(method, url string, body []byte, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	return http.DefaultClient.Do(req)
+}
+
+func expectCreateStatus(t *testing.T, server *testutil.Server, body []byte, headers map[string]string, status int) model.Response {
+	t.Helper()
+
+	req, err := http.NewRequest("POST", server.URL+"/api/todos", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp := server.Do(t, req)
+	if resp.StatusCode != status {
+		t.Errorf("Expected status %d, got %d", status, resp.StatusCode)
+	}
+
+	response := resp.Envelope(t, nil)
+	if response.Code != status && status >= 400 {
+		t.Errorf("Expected code %d, got %d", status, response.Code)
+	}
+
+	return response
+}
+
+func TestCreateTodoTrimsTitle(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	response := expectCreateStatus(t, server, []byte(`{"title":"  Trimmed  ","content":"line1\r\nline2 "}`), nil, http.StatusCreated)
+
+	todo, ok := response.Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected data to be a map")
+	}
+
+	if todo["title"] != "Trimmed" {
+		t.Errorf("Expected title 'Trimmed', got %q", todo["title"])
+	}
+
+	if todo["content"] != "line1\nline2" {
+		t.Errorf("Expected normalized content, got %q", todo["content"])
+	}
+}
+
+func TestCreateTodoWhitespaceTitle(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	expectCreateStatus(t, server, []byte(`{"title":"   \t "}`), nil, http.StatusBadRequest)
+}
+
+func TestCreateTodoTitleTooLong(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t, func(cfg *config.Config) {
+		cfg.TitleMaxLength = 50
+	})
+	limit := server.Deps.Config.TitleMaxLength
+
+	body, _ := json.Marshal(model.CreateTodoRequest{Title: strings.Repeat("标", limit)})
+	expectCreateStatus(t, server, body, nil, http.StatusCreated)
+
+	body, _ = json.Marshal(model.CreateTodoRequest{Title: strings.Repeat("标", limit+1)})
+	response := expectCreateStatus(t, server, body, nil, http.StatusBadRequest)
+	if !strings.Contains(response.Message, strconv.Itoa(limit)) {
+		t.Errorf("Expected message to mention the limit, got %q", response.Message)
+	}
+
+	// 其他服务的规则不受影响
+	other := testutil.NewServer(t)
+	expectCreateStatus(t, other, body, nil, http.StatusCreated)
+}
+
+func TestCreateTodoControlCharacters(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	expectCreateStatus(t, server, []byte(`{"title":"multi\nline"}`), nil, http.StatusBadRequest)
+	expectCreateStatus(t, server, []byte(`{"title":"ok","content":"bell\u0007"}`), nil, http.StatusBadRequest)
+	expectCreateStatus(t, server, []byte(`{"title":"ok","content":"tab\tand\nnewline"}`), nil, http.StatusCreated)
+}
+
+func TestCreateTodoInvalidUTF8(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	expectCreateStatus(t, server, []byte("{\"title\":\"bad \xff\xfe\"}"), nil, http.StatusBadRequest)
+}
+
+func TestCreateTodoUnknownField(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	response := expectCreateStatus(t, server, []byte(`{"title":"ok","priority":1}`), nil, http.StatusBadRequest)
+	if !strings.Contains(response.Message, "priority") {
+		t.Errorf("Expected message to mention the field, got %q", response.Message)
+	}
+}
+
+func TestCreateTodoBodyTooLarge(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t, func(cfg *config.Config) {
+		cfg.MaxBodyBytes = 64
+	})
+	limit := server.Deps.Config.MaxBodyBytes
+
+	body, _ := json.Marshal(model.CreateTodoRequest{Title: "ok", Content: strings.Repeat("x", int(limit)*2)})
+	response := expectCreateStatus(t, server, body, nil, http.StatusRequestEntityTooLarge)
+	if !strings.Contains(response.Message, strconv.FormatInt(limit, 10)) {
+		t.Errorf("Expected message to mention the limit, got %q", response.Message)
+	}
+}
+
+func TestValidationMessageLocale(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t)
+
+	response := expectCreateStatus(t, server, []byte(`{"content":"no title"}`), map[string]string{"Accept-Language": "zh-CN,zh;q=0.9"}, http.StatusBadRequest)
+	if !strings.Contains(response.Message, "必填") {
+		t.Errorf("Expected Chinese message, got %q", response.Message)
+	}
+
+	response = expectCreateStatus(t, server, []byte(`{"content":"no title"}`), map[string]string{"Accept-Language": "en-US"}, http.StatusBadRequest)
+	if !strings.Contains(response.Message, "required") {
+		t.Errorf("Expected English message, got %q", response.Message)
+	}
+}
@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"todo-backend/internal/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testFrontend = fstest.MapFS{
+	"index.html":                  {Data: []byte("<!doctype html><div id=root></div>")},
+	"favicon.svg":                 {Data: []byte("<svg></svg>")},
+	"assets/index-4f9a8c1e.js":    {Data: []byte("console.log('app')")},
+	"assets/index-4f9a8c1e.js.gz": {Data: []byte("gzip bytes")},
+	"assets/index-4f9a8c1e.js.br": {Data: []byte("brotli bytes")},
+}
+
+func frontendRequest(t *testing.T, r *gin.Engine, method, path string, headers map[string]string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	body, _ := io.ReadAll(w.Body)
+	return w, string(body)
+}
+
+func newFrontendRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	h, err := web.NewHandler(testFrontend)
+	if err != nil {
+		t.Fatalf("Failed to load frontend: %v", err)
+	}
+	r := gin.New()
+	r.GET("/api/todos", func(c *gin.Context) { c.String(http.StatusOK, "api") })
+	r.NoRoute(h.Serve)
+	return r
+}
+
+func TestFrontendSPAFallback(t *testing.T) {
+	t.Parallel()
+	r := newFrontendRouter(t)
+
+	for _, path := range []string{"/", "/index.html", "/todos/42"} {
+		w, body := frontendRequest(t, r, "GET", path, nil)
+		if w.Code != http.StatusOK || body != "<!doctype html><div id=root></div>" {
+			t.Errorf("Expected index.html for %s, got %d %q", path, w.Code, body)
+		}
+		if w.Header().Get("Cache-Control") != "no-cache" || w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+			t.Errorf("Unexpected headers for %s: %v", path, w.Header())
+		}
+	}
+
+	// 缺失的静态文件和接口路径不回退到 index.html
+	if w, _ := frontendRequest(t, r, "GET", "/assets/missing.js", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing asset, got %d", w.Code)
+	}
+	for _, path := range []string{"/api/unknown", "/graphql/x", "/caldav"} {
+		w, body := frontendRequest(t, r, "GET", path, nil)
+		if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+			t.Errorf("Expected a JSON 404 for %s, got %d %q", path, w.Code, body)
+		}
+	}
+	if w, _ := frontendRequest(t, r, "POST", "/todos", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for POST, got %d", w.Code)
+	}
+	if _, body := frontendRequest(t, r, "GET", "/api/todos", nil); body != "api" {
+		t.Errorf("Expected API routes to take precedence, got %q", body)
+	}
+}
+
+func TestFrontendCachingAndCompression(t *testing.T) {
+	t.Parallel()
+	r := newFrontendRouter(t)
+	asset := "/assets/index-4f9a8c1e.js"
+
+	w, body := frontendRequest(t, r, "GET", asset, nil)
+	if body != "console.log('app')" || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected the uncompressed asset, got %q", body)
+	}
+	if w.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+		t.Errorf("Expected immutable caching for hashed assets, got %q", w.Header().Get("Cache-Control"))
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Expected Vary: Accept-Encoding, got %q", w.Header().Get("Vary"))
+	}
+	if w.Header().Get("Content-Type") != "text/javascript; charset=utf-8" {
+		t.Errorf("Unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	plainETag := w.Header().Get("ETag")
+
+	cases := map[string]string{
+		"gzip, deflate, br": "br",
+		"gzip":              "gzip",
+		"br;q=0, gzip":      "gzip",
+		"identity":          "",
+	}
+	for accept, encoding := range cases {
+		w, _ := frontendRequest(t, r, "GET", asset, map[string]string{"Accept-Encoding": accept})
+		if w.Header().Get("Content-Encoding") != encoding {
+			t.Errorf("Accept-Encoding %q: expected %q, got %q", accept, encoding, w.Header().Get("Content-Encoding"))
+		}
+	}
+
+	w, body = frontendRequest(t, r, "GET", asset, map[string]string{"Accept-Encoding": "br"})
+	if body != "brotli bytes" || w.Header().Get("ETag") == plainETag {
+		t.Errorf("Expected the brotli variant with its own ETag, got %q %s", body, w.Header().Get("ETag"))
+	}
+
+	// 文件太小没有预压缩时不设置 Vary
+	if w, _ := frontendRequest(t, r, "GET", "/favicon.svg", map[string]string{"Accept-Encoding": "br"}); w.Header().Get("Vary") != "" || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("Unexpected headers for favicon: %v", w.Header())
+	}
+
+	w, _ = frontendRequest(t, r, "GET", "/", map[string]string{"If-None-Match": mustHeader(t, r, "/", "ETag")})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %d", w.Code)
+	}
+}
+
+func mustHeader(t *testing.T, r *gin.Engine, path, name string) string {
+	t.Helper()
+
+	w, _ := frontendRequest(t, r, "GET", path, nil)
+	value := w.Header().Get(name)
+	if value == "" {
+		t.Fatalf("Expected a %s header for %s", name, path)
+	}
+	return value
+}
+
+func TestFrontendDevProxy(t *testing.T) {
+	t.Parallel()
+	vite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "vite:"+r.URL.Path)
+	}))
+	defer vite.Close()
+
+	proxy, err := web.DevProxy(vite.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.NoRoute(proxy)
+	// ReverseProxy 需要 CloseNotifier, 使用真实的服务器而不是 ResponseRecorder
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/src/main.jsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "vite:/src/main.jsx" {
+		t.Errorf("Expected the request to be proxied, got %q", body)
+	}
+
+	resp, err = http.Get(server.URL + "/api/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected API paths not to be proxied, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,334 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"todo-backend/internal/config"
+	"todo-backend/internal/database"
+	"todo-backend/internal/model"
+	"todo-backend/internal/testutil"
+	"todo-backend/internal/webhook"
+)
+
+type receivedHook struct {
+	Event     string
+	Signature string
+	Body      []byte
+}
+
+type hookReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	received []receivedHook
+	failures int
+}
+
+// newHookReceiver 启动本地接收端, 前 failures 次请求返回 500
+func newHookReceiver(t *testing.T, failures int) *hookReceiver {
+	t.Helper()
+
+	r := &hookReceiver{failures: failures}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.failures > 0 {
+			r.failures--
+			http.Error(w, "try again", http.StatusInternalServerError)
+			return
+		}
+		r.received = append(r.received, receivedHook{
+			Event:     req.Header.Get(webhook.EventHeader),
+			Signature: req.Header.Get(webhook.SignatureHeader),
+			Body:      body,
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *hookReceiver) waitFor(t *testing.T, event string) receivedHook {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		for _, hook := range r.received {
+			if hook.Event == event {
+				r.mu.Unlock()
+				return hook
+			}
+		}
+		r.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Did not receive %s webhook", event)
+	return receivedHook{}
+}
+
+func createTestWebhook(t *testing.T, server *testutil.Server, body map[string]interface{}) map[string]interface{} {
+	t.Helper()
+
+	resp, err := makeRequest("POST", server.URL+"/api/webhooks", body)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	response, _ := parseResponse(resp)
+
+	hook := response.Data.(map[string]interface{})
+	t.Cleanup(func() {
+		resp, err := makeRequest("DELETE", fmt.Sprintf("%s/api/webhooks/%v", server.URL, hook["id"]), nil)
+		if err == nil {
+			resp.Body.Close()
+		}
+	})
+	return hook
+}
+
+func getDeliveries(t *testing.T, server *testutil.Server, hookID interface{}) []interface{} {
+	t.Helper()
+
+	resp, err := makeRequest("GET", fmt.Sprintf("%s/api/webhooks/%v/deliveries", server.URL, hookID), nil)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	response, _ := parseResponse(resp)
+	deliveries, _ := response.Data.([]interface{})
+	return deliveries
+}
+
+func TestWebhookSignedDelivery(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	receiver := newHookReceiver(t, 0)
+	secret := "0123456789abcdef-secret"
+	createTestWebhook(t, server, map[string]interface{}{
+		"url":    receiver.URL,
+		"secret": secret,
+		"events": []string{"todo.created"},
+	})
+
+	id := createTestTodo(t, server, "Webhook Todo")
+
+	hook := receiver.waitFor(t, "todo.created")
+	if hook.Signature != webhook.Sign(secret, hook.Body) {
+		t.Errorf("Invalid signature %s", hook.Signature)
+	}
+
+	var payload struct {
+		Type string     `json:"type"`
+		Data model.Todo `json:"data"`
+	}
+	if err := json.Unmarshal(hook.Body, &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if payload.Type != "todo.created" || payload.Data.ID != id {
+		t.Errorf("Unexpected payload: %s", hook.Body)
+	}
+
+	// 未订阅的事件不会投递
+	resp, _ := makeRequest("DELETE", fmt.Sprintf("%s/api/todos/%d", server.URL, id), nil)
+	resp.Body.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	for _, received := range receiver.received {
+		if received.Event == "todo.deleted" {
+			t.Error("Expected todo.deleted to be filtered out")
+		}
+	}
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	receiver := newHookReceiver(t, 2)
+	hook := createTestWebhook(t, server, map[string]interface{}{"url": receiver.URL})
+
+	resp, _ := makeRequest("POST", fmt.Sprintf("%s/api/webhooks/%v/test", server.URL, hook["id"]), nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+	}
+
+	receiver.waitFor(t, webhook.EventTest)
+	time.Sleep(50 * time.Millisecond)
+
+	deliveries := getDeliveries(t, server, hook["id"])
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected one delivery, got %d", len(deliveries))
+	}
+	delivery := deliveries[0].(map[string]interface{})
+	if delivery["status"] != model.DeliverySucceeded || delivery["attempts"] != float64(3) {
+		t.Errorf("Expected success after 3 attempts, got %v", delivery)
+	}
+	if delivery["response_code"] != float64(http.StatusNoContent) {
+		t.Errorf("Expected response code 204, got %v", delivery["response_code"])
+	}
+}
+
+func TestWebhookGivesUpAfterMaxAttempts(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	receiver := newHookReceiver(t, 100)
+	hook := createTestWebhook(t, server, map[string]interface{}{"url": receiver.URL})
+
+	resp, _ := makeRequest("POST", fmt.Sprintf("%s/api/webhooks/%v/test", server.URL, hook["id"]), nil)
+	resp.Body.Close()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries := getDeliveries(t, server, hook["id"])
+		if len(deliveries) == 1 {
+			delivery := deliveries[0].(map[string]interface{})
+			if delivery["status"] == model.DeliveryFailed {
+				if delivery["response_code"] != float64(http.StatusInternalServerError) {
+					t.Errorf("Expected response code 500, got %v", delivery["response_code"])
+				}
+				return
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Error("Expected delivery to be marked as failed")
+}
+
+func TestWebhookSecretAndValidation(t *testing.T) {
+	t.Parallel()
+	server := newTestServer(t)
+
+	hook := createTestWebhook(t, server, map[string]interface{}{"url": "https://example.com/hook"})
+	if secret, _ := hook["secret"].(string); len(secret) < 16 {
+		t.Errorf("Expected generated secret on create, got %q", secret)
+	}
+
+	resp, _ := makeRequest("GET", fmt.Sprintf("%s/api/webhooks/%v", server.URL, hook["id"]), nil)
+	response, _ := parseResponse(resp)
+	resp.Body.Close()
+	if _, ok := response.Data.(map[string]interface{})["secret"]; ok {
+		t.Error("Expected secret to be hidden")
+	}
+
+	for _, body := range []map[string]interface{}{
+		{"url": "ftp://example.com/hook"},
+		{"url": "https://example.com/hook", "events": []string{"todo.archived"}},
+		{"url": "https://example.com/hook", "secret": "short"},
+	} {
+		resp, _ := makeRequest("POST", server.URL+"/api/webhooks", body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %v, got %d", body, resp.StatusCode)
+		}
+	}
+}
+
+// sendTestDelivery 在 server 上创建指向 url 的 Webhook 并发送测试事件, 等待投递结束后返回投递记录
+func sendTestDelivery(t *testing.T, server *testutil.Server, url string) map[string]interface{} {
+	t.Helper()
+
+	var hook model.Webhook
+	testutil.AssertSuccess(t, server.Request(t, "POST", "/api/webhooks", map[string]string{"url": url}), http.StatusCreated, &hook)
+	testutil.AssertSuccess(t, server.Request(t, "POST", fmt.Sprintf("/api/webhooks/%d/test", hook.ID), nil), http.StatusAccepted, nil)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		var deliveries []map[string]interface{}
+		testutil.AssertSuccess(t, server.Request(t, "GET", fmt.Sprintf("/api/webhooks/%d/deliveries", hook.ID), nil), http.StatusOK, &deliveries)
+		if len(deliveries) == 1 && deliveries[0]["status"] != model.DeliveryPending {
+			return deliveries[0]
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("Expected the delivery to finish")
+	return nil
+}
+
+func TestWebhookRefusesPrivateAddresses(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t, func(cfg *config.Config) {
+		cfg.WebhookAllowPrivate = false
+		cfg.WebhookMaxAttempts = 1
+	})
+	receiver := newHookReceiver(t, 0)
+
+	delivery := sendTestDelivery(t, server, receiver.URL)
+	if delivery["status"] != model.DeliveryFailed {
+		t.Errorf("Expected delivery to fail, got %v", delivery["status"])
+	}
+	if msg, _ := delivery["error"].(string); !strings.Contains(msg, "not allowed") {
+		t.Errorf("Expected the address to be refused, got %q", msg)
+	}
+	if _, ok := delivery["response_body"]; ok {
+		t.Error("Expected response body not to be returned")
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.received) != 0 {
+		t.Errorf("Expected loopback receiver not to be called, got %d requests", len(receiver.received))
+	}
+}
+
+func TestWebhookDoesNotFollowRedirects(t *testing.T) {
+	t.Parallel()
+	server := testutil.NewServer(t, func(cfg *config.Config) {
+		cfg.WebhookMaxAttempts = 1
+	})
+	target := newHookReceiver(t, 0)
+	redirector := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	t.Cleanup(redirector.Close)
+
+	delivery := sendTestDelivery(t, server, redirector.URL)
+	if delivery["status"] != model.DeliveryFailed || delivery["response_code"] != float64(http.StatusTemporaryRedirect) {
+		t.Errorf("Expected the redirect to fail the delivery, got %v %v", delivery["status"], delivery["response_code"])
+	}
+
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	if len(target.received) != 0 {
+		t.Errorf("Expected the redirect not to be followed, got %d requests", len(target.received))
+	}
+}
+
+func TestMigrateDropsStoredResponseBodies(t *testing.T) {
+	t.Parallel()
+
+	db, err := testutil.OpenDB(testutil.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.CloseDB(db) })
+
+	// 模拟早期版本的表结构
+	if err := db.Exec("ALTER TABLE webhook_deliveries ADD COLUMN response_body text").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if db.Migrator().HasColumn(&model.WebhookDelivery{}, "response_body") {
+		t.Error("Expected response_body column to be dropped")
+	}
+}
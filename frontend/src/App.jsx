@@ -27,10 +27,22 @@ export default function App() {
     fetchTodos()
   }, [])
 
+  // 通过 SSE 同步其他客户端的修改
+  useEffect(() => {
+    return api.subscribeTodoEvents({
+      'todo.created': (todo) =>
+        setTodos((prev) => (prev.some((t) => t.id === todo.id) ? prev : [todo, ...prev])),
+      'todo.updated': (todo) =>
+        setTodos((prev) => prev.map((t) => (t.id === todo.id ? todo : t))),
+      'todo.deleted': ({ id }) => setTodos((prev) => prev.filter((t) => t.id !== id)),
+      reset: () => fetchTodos(),
+    })
+  }, [])
+
   // 创建 Todo
   const handleCreate = async (title) => {
     try {
-      await api.createTodo({ title, completed: false })
+      await api.createTodo({ title })
       await fetchTodos()
     } catch (err) {
       setError(err.message)
@@ -41,9 +53,10 @@ export default function App() {
   // 更新 Todo
   const handleUpdate = async (todo) => {
     try {
-      await api.updateTodo(todo.id, todo)
-      setTodos(todos.map((t) => (t.id === todo.id ? todo : t)))
+      const updated = await api.updateTodo(todo.id, todo)
+      setTodos(todos.map((t) => (t.id === todo.id ? updated : t)))
     } catch (err) {
+      await fetchTodos()
       setError(err.message)
       console.error('更新 Todo 失败:', err)
     }
@@ -35,16 +35,32 @@ export const api = {
 
   // 更新 Todo
   updateTodo: async (id, todo) => {
+    // 后端会拒绝未知字段, 只提交可修改的字段
+    const { title, content, completed, version } = todo
+    const headers = {
+      'Content-Type': 'application/json',
+    }
+    // 带上版本号, 其他标签页已修改时后端返回 412
+    if (version) {
+      headers['If-Match'] = `"${id}-${version}"`
+    }
     const response = await fetch(`${BASE_URL}/todos/${id}`, {
       method: 'PUT',
-      headers: {
-        'Content-Type': 'application/json',
-      },
-      body: JSON.stringify(todo),
+      headers,
+      body: JSON.stringify({ title, content, completed }),
     })
     return handleResponse(response)
   },
 
+  // 订阅 Todo 变更事件, 返回取消订阅的函数
+  subscribeTodoEvents: (handlers) => {
+    const source = new EventSource(`${BASE_URL}/todos/events`)
+    Object.entries(handlers).forEach(([type, handler]) => {
+      source.addEventListener(type, (event) => handler(JSON.parse(event.data)))
+    })
+    return () => source.close()
+  },
+
   // 删除 Todo
   deleteTodo: async (id) => {
     const response = await fetch(`${BASE_URL}/todos/${id}`, {
@@ -1,8 +1,47 @@
 import { defineConfig } from 'vite'
 import react from '@vitejs/plugin-react'
+import { readdirSync, readFileSync, statSync, writeFileSync } from 'node:fs'
+import { extname, join, resolve } from 'node:path'
+import { brotliCompressSync, constants, gzipSync } from 'node:zlib'
+
+const compressible = new Set(['.html', '.js', '.css', '.svg', '.json', '.txt', '.map'])
+
+// 为构建产物生成 .gz 和 .br 预压缩文件, 后端按 Accept-Encoding 直接返回, 不需要在请求时压缩
+function precompress() {
+  let outDir
+
+  const walk = (dir) => {
+    for (const name of readdirSync(dir)) {
+      const path = join(dir, name)
+      if (statSync(path).isDirectory()) {
+        walk(path)
+        continue
+      }
+      if (!compressible.has(extname(name))) continue
+
+      const data = readFileSync(path)
+      if (data.length < 1024) continue
+      writeFileSync(path + '.gz', gzipSync(data, { level: 9 }))
+      writeFileSync(path + '.br', brotliCompressSync(data, {
+        params: { [constants.BROTLI_PARAM_QUALITY]: constants.BROTLI_MAX_QUALITY }
+      }))
+    }
+  }
+
+  return {
+    name: 'precompress',
+    apply: 'build',
+    configResolved(config) {
+      outDir = resolve(config.root, config.build.outDir)
+    },
+    closeBundle() {
+      walk(outDir)
+    }
+  }
+}
 
 export default defineConfig({
-  plugins: [react()],
+  plugins: [react(), precompress()],
   server: {
     port: 5173,
     proxy: {